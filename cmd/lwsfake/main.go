@@ -0,0 +1,129 @@
+// Command lwsfake serves an in-memory fake of the LWS DNS API, so that
+// Terraform can run against it locally by pointing base_url at it:
+//
+//	go run ./cmd/lwsfake -zone example.com -fault "method=POST,status=502,times=1"
+//
+// The fake accepts any credentials unless -login and -api-key are given, and
+// logs every request it serves.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/M4XGO/terraform-provider-lws/internal/lwsfake"
+)
+
+// listFlag collects the values of a repeated flag.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func main() {
+	var (
+		addr     string
+		login    string
+		apiKey   string
+		seed     string
+		firstID  int
+		lag      int
+		zones    listFlag
+		faultSet listFlag
+	)
+
+	flag.StringVar(&addr, "addr", "127.0.0.1:8080", "address to listen on")
+	flag.StringVar(&login, "login", "", "login to require in X-Auth-Login, any when empty")
+	flag.StringVar(&apiKey, "api-key", "", "API key to require in X-Auth-Pass, any when empty")
+	flag.StringVar(&seed, "seed", "", "JSON file of the initial records, an object mapping zone names to lists of records")
+	flag.IntVar(&firstID, "first-id", 1, "ID of the first record created")
+	flag.IntVar(&lag, "lag", 0, "number of listings still serving the previous records of a zone after a change")
+	flag.Var(&zones, "zone", "zone to serve, empty at start (repeatable)")
+	flag.Var(&faultSet, "fault", `fault to inject, e.g. "method=POST,status=502,times=2" (repeatable)`)
+	flag.Parse()
+
+	opts := []lwsfake.Option{
+		lwsfake.WithCredentials(login, apiKey),
+		lwsfake.WithFirstID(firstID),
+		lwsfake.WithConsistencyLag(lag),
+	}
+	for _, zone := range zones {
+		opts = append(opts, lwsfake.WithZone(zone))
+	}
+	if seed != "" {
+		seeded, err := loadSeed(seed)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		for zone, records := range seeded {
+			opts = append(opts, lwsfake.WithZone(zone, records...))
+		}
+	}
+	for _, spec := range faultSet {
+		fault, err := lwsfake.ParseFault(spec)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		opts = append(opts, lwsfake.WithFaults(fault))
+	}
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           logRequests(lwsfake.New(opts...)),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("Fake LWS API listening on http://%s, set base_url or LWS_BASE_URL to it", addr)
+	log.Fatal(server.ListenAndServe())
+}
+
+// loadSeed reads the initial records of the zones.
+func loadSeed(path string) (map[string][]lwsfake.Record, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- the path is chosen by the user
+	if err != nil {
+		return nil, fmt.Errorf("error reading seed file: %w", err)
+	}
+	var seeded map[string][]lwsfake.Record
+	if err := json.Unmarshal(data, &seeded); err != nil {
+		return nil, fmt.Errorf("error decoding seed file %s: %w", path, err)
+	}
+	return seeded, nil
+}
+
+// statusRecorder remembers the status written to a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// logRequests logs each request with its status and duration.
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+		defer func() {
+			status := fmt.Sprint(recorder.status)
+			if recorder.status == 0 {
+				status = "dropped"
+			}
+			log.Printf("%s %s %s (%s)", r.Method, r.URL.Path, status, time.Since(start).Round(time.Millisecond))
+		}()
+		next.ServeHTTP(recorder, r)
+	})
+}
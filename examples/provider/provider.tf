@@ -14,6 +14,9 @@ provider "lws" {
 
   # Optional: Custom API endpoint
   # base_url = "https://api.lws.net/v1"
+  # Optional: API endpoints to fail over between, instead of base_url
+  # base_urls         = ["https://api.lws.net/v1", "https://relay.example.internal/v1"]
+  # failover_cooldown = 60
   # Optional: API request timeout in seconds
   # timeout = 30
   # Optional: Number of retries for an API request
@@ -22,4 +25,19 @@ provider "lws" {
   # delay = 15
   # Optional: Backoff multiplier for delay between retries
   # backoff = 2
+  # Optional: Average API requests per second (lowered automatically on throttling)
+  # requests_per_second = 5
+  # Optional: Maximum number of API requests in flight
+  # max_concurrent_requests = 4
+  # Optional: Seconds a zone listing is shared between resources
+  # zone_cache_ttl = 30
+  # Optional: Consecutive challenge/5xx responses before API calls are suspended
+  # circuit_breaker_threshold = 5
+  # Optional: Seconds to wait for the zone listing to reflect a change
+  # consistency_max_wait = 30
+  # Optional: Extra patterns masked in the API logs
+  # log_redact_patterns = ["acme-[a-z0-9]+"]
+  # Optional: Egress proxy and the CA of a TLS intercepting proxy
+  # proxy_url    = "http://proxy.internal:3128"
+  # ca_cert_file = "/etc/ssl/certs/corporate-ca.pem"
 }
@@ -0,0 +1,6 @@
+# DNS records can be imported by zone, name, type and value hash, the ID the
+# provider stores in state
+terraform import lws_dns_record.www example.com/www/A/c5eb5a4cc76a5cdb
+
+# or by zone and LWS numeric ID
+terraform import lws_dns_record.www example.com:12345
@@ -0,0 +1,47 @@
+package client
+
+import "context"
+
+// DNSAPI is the set of DNS operations the provider relies on. LWSClient is
+// the production implementation; tests, decorators and alternative backends
+// only need to satisfy this interface.
+type DNSAPI interface {
+	// GetDNSZone returns every record of a zone.
+	GetDNSZone(ctx context.Context, zoneName string) (*DNSZone, error)
+	// GetDNSRecord returns the record with the given ID. The error satisfies
+	// IsNotFound when the zone holds no such record.
+	GetDNSRecord(ctx context.Context, zoneName, recordID string) (*DNSRecord, error)
+	// CreateDNSRecord adds a record to record.Zone and returns it with its ID.
+	CreateDNSRecord(ctx context.Context, record *DNSRecord) (*DNSRecord, error)
+	// UpdateDNSRecord replaces the record identified by record.ID.
+	UpdateDNSRecord(ctx context.Context, record *DNSRecord) (*DNSRecord, error)
+	// DeleteDNSRecord removes the record with the given ID from a zone.
+	DeleteDNSRecord(ctx context.Context, recordID int, zoneName string) error
+	// Describe identifies the backend in diagnostics and logs.
+	Describe() Description
+}
+
+// Description identifies a DNSAPI implementation for diagnostics.
+type Description struct {
+	// Backend is a short name for the implementation, e.g. "lws".
+	Backend string
+	// BaseURL is the API address, empty for backends without one.
+	BaseURL string
+	// Login is the account the backend authenticates as, if any.
+	Login string
+	// TestMode is set when the backend does not apply changes for real.
+	TestMode bool
+}
+
+// Ensure LWSClient satisfies the DNSAPI interface.
+var _ DNSAPI = &LWSClient{}
+
+// Describe implements DNSAPI
+func (c *LWSClient) Describe() Description {
+	return Description{
+		Backend:  "lws",
+		BaseURL:  c.BaseURL,
+		Login:    c.Login,
+		TestMode: c.TestMode,
+	}
+}
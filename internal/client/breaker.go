@@ -0,0 +1,226 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Defaults used when the provider configuration does not override them.
+const (
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets every request through.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails every request without calling the API.
+	BreakerOpen
+	// BreakerHalfOpen lets a single probe through after the cooldown.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitOpenError is returned for every call made while the breaker is
+// open. It satisfies IsCircuitOpen.
+type CircuitOpenError struct {
+	// Failures is the number of consecutive failures that opened the breaker.
+	Failures int
+	// Cause is the last failure seen before opening.
+	Cause error
+	// RetryAt is when the breaker lets a probe request through.
+	RetryAt time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("LWS API calls suspended after %d consecutive Cloudflare challenges or server errors, "+
+		"the API will be probed again at %s. Last error: %v",
+		e.Failures, e.RetryAt.Format(time.RFC3339), e.Cause)
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+func (e *CircuitOpenError) Unwrap() error {
+	return e.Cause
+}
+
+// CircuitBreaker stops calling the API once it keeps answering with
+// Cloudflare challenges or 5xx errors, so parallel resources fail within
+// seconds instead of each burning its retry budget.
+//
+// After threshold consecutive failures the breaker opens and every call
+// fails fast. Once the cooldown has elapsed a single probe request is let
+// through: a success closes the breaker, another failure re-opens it.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	clock     Clock
+
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	lastErr  *APIError
+	probing  bool
+	opened   chan struct{}
+}
+
+// NewCircuitBreaker creates a breaker opening after threshold consecutive
+// failures and probing the API again after cooldown. A threshold of zero
+// or less disables it.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		clock:     systemClock{},
+		opened:    make(chan struct{}),
+	}
+}
+
+// Allow returns a CircuitOpenError when the call must not reach the API. In
+// the half-open state the first caller is let through as the probe and must
+// report its outcome with Record.
+func (b *CircuitBreaker) Allow() (BreakerState, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.threshold <= 0 {
+		return BreakerClosed, nil
+	}
+
+	switch b.state {
+	case BreakerOpen:
+		if b.clock.Now().Before(b.openedAt.Add(b.cooldown)) {
+			return b.state, b.openError()
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		b.opened = make(chan struct{})
+		return b.state, nil
+	case BreakerHalfOpen:
+		if b.probing {
+			return b.state, b.openError()
+		}
+		b.probing = true
+		return b.state, nil
+	default:
+		return b.state, nil
+	}
+}
+
+// Record reports the outcome of a call let through by Allow and returns the
+// breaker state before and after it.
+func (b *CircuitBreaker) Record(err error) (BreakerState, BreakerState) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	from := b.state
+	if b.threshold <= 0 {
+		return from, from
+	}
+
+	probe := b.state == BreakerHalfOpen && b.probing
+	if probe {
+		b.probing = false
+	}
+
+	var apiErr *APIError
+	switch {
+	case err != nil && !errors.As(err, &apiErr):
+		// Transport error or cancellation, the API state is unknown.
+	case isBreakerFailure(err):
+		b.failures++
+		// Keep a copy, the caller owns err
+		lastErr := *apiErr
+		b.lastErr = &lastErr
+		if probe || (b.state == BreakerClosed && b.failures >= b.threshold) {
+			b.state = BreakerOpen
+			b.openedAt = b.clock.Now()
+			close(b.opened)
+		}
+	case b.state == BreakerOpen:
+		// A call let through before the breaker opened answered late. Only
+		// the half-open probe closes the breaker again, Opened is closed
+		// until then.
+	default:
+		// Any answer that is not a challenge or a server error, including
+		// a regular API error, proves the API is reachable again.
+		b.failures = 0
+		b.lastErr = nil
+		b.state = BreakerClosed
+	}
+
+	return from, b.state
+}
+
+// Opened returns a channel closed when the breaker opens, so callers waiting
+// to retry can give up immediately.
+func (b *CircuitBreaker) Opened() <-chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.opened
+}
+
+// State returns the current state of the breaker.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// OpenError returns the error failing calls while the breaker is open, or
+// nil when calls are let through.
+func (b *CircuitBreaker) OpenError() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != BreakerOpen {
+		return nil
+	}
+	return b.openError()
+}
+
+// openError returns a new error for each call, with its own copy of the
+// last failure, so that callers may not see each other's changes.
+func (b *CircuitBreaker) openError() error {
+	var cause error
+	if b.lastErr != nil {
+		lastErr := *b.lastErr
+		cause = &lastErr
+	}
+	return &CircuitOpenError{
+		Failures: b.failures,
+		Cause:    cause,
+		RetryAt:  b.openedAt.Add(b.cooldown),
+	}
+}
+
+// isBreakerFailure reports whether err counts towards opening the breaker:
+// Cloudflare challenges and 5xx answers.
+func isBreakerFailure(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.Challenge || apiErr.StatusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,219 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const challengePage = `<!DOCTYPE html><html><head><title>Just a moment...</title></head></html>`
+
+func TestCircuitBreaker_States(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	breaker := NewCircuitBreaker(2, time.Minute)
+	breaker.clock = clock
+
+	challenge := &APIError{StatusCode: http.StatusForbidden, Challenge: true}
+	serverError := &APIError{StatusCode: http.StatusBadGateway}
+	notFound := &APIError{StatusCode: http.StatusNotFound}
+
+	record := func(err error) {
+		if _, allowErr := breaker.Allow(); allowErr != nil {
+			t.Fatalf("Expected the call to be allowed, got %v", allowErr)
+		}
+		breaker.Record(err)
+	}
+
+	record(challenge)
+	record(notFound)
+	if breaker.State() != BreakerClosed {
+		t.Fatalf("Expected a regular API error to reset the failure count, got %s", breaker.State())
+	}
+
+	record(challenge)
+	record(serverError)
+	if breaker.State() != BreakerOpen {
+		t.Fatalf("Expected the breaker to open after 2 failures, got %s", breaker.State())
+	}
+
+	_, err := breaker.Allow()
+	if !IsCircuitOpen(err) {
+		t.Fatalf("Expected IsCircuitOpen, got %v", err)
+	}
+	var cause *APIError
+	if !errors.As(err, &cause) || *cause != *serverError {
+		t.Errorf("Expected the open error to wrap the last failure, got %v", err)
+	}
+	if cause == serverError {
+		t.Errorf("Expected the open error to wrap a copy of the last failure")
+	}
+
+	clock.Advance(time.Minute)
+
+	state, err := breaker.Allow()
+	if err != nil || state != BreakerHalfOpen {
+		t.Fatalf("Expected a half-open probe after the cooldown, got %s, %v", state, err)
+	}
+	if _, err := breaker.Allow(); !IsCircuitOpen(err) {
+		t.Errorf("Expected a single probe in the half-open state, got %v", err)
+	}
+
+	breaker.Record(challenge)
+	if breaker.State() != BreakerOpen {
+		t.Fatalf("Expected a failed probe to re-open the breaker, got %s", breaker.State())
+	}
+
+	clock.Advance(time.Minute)
+	if _, err := breaker.Allow(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	breaker.Record(nil)
+	if breaker.State() != BreakerClosed {
+		t.Errorf("Expected a successful probe to close the breaker, got %s", breaker.State())
+	}
+}
+
+func TestCircuitBreaker_LateSuccessWhileOpen(t *testing.T) {
+	breaker := NewCircuitBreaker(2, time.Minute)
+	serverError := &APIError{StatusCode: http.StatusBadGateway}
+
+	// Three calls are in flight when the first two failures open the breaker
+	for range 3 {
+		if _, err := breaker.Allow(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	breaker.Record(serverError)
+	breaker.Record(serverError)
+	breaker.Record(nil)
+	if breaker.State() != BreakerOpen {
+		t.Fatalf("Expected a late success to leave the breaker open, got %s", breaker.State())
+	}
+	select {
+	case <-breaker.Opened():
+	default:
+		t.Errorf("Expected Opened to stay closed while the breaker is open")
+	}
+
+	// Used to close the opened channel twice and panic
+	breaker.Record(serverError)
+	breaker.Record(serverError)
+	if breaker.State() != BreakerOpen {
+		t.Errorf("Expected the breaker to stay open, got %s", breaker.State())
+	}
+}
+
+func TestCircuitBreaker_Disabled(t *testing.T) {
+	breaker := NewCircuitBreaker(0, time.Minute)
+	for i := 0; i < 10; i++ {
+		if _, err := breaker.Allow(); err != nil {
+			t.Fatalf("Expected a disabled breaker to allow every call, got %v", err)
+		}
+		breaker.Record(&APIError{StatusCode: http.StatusServiceUnavailable})
+	}
+	if breaker.State() != BreakerClosed {
+		t.Errorf("Expected a disabled breaker to stay closed, got %s", breaker.State())
+	}
+}
+
+func TestLWSClient_CircuitBreakerRequestIDs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(challengePage))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server.URL,
+		WithRateLimit(0, 0, 4),
+		WithCircuitBreaker(1, time.Hour),
+	)
+	if _, err := client.GetDNSZone(context.Background(), testDomainName); err == nil {
+		t.Fatalf("Expected the challenge to fail the call")
+	}
+
+	// Every operation fails with the same last failure and must only see
+	// its own request ID; run with -race to catch shared writes
+	var wg sync.WaitGroup
+	errs := make([]error, 20)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx := WithRequestID(context.Background(), fmt.Sprintf("operation-%02d", i))
+			_, errs[i] = client.GetDNSZone(ctx, testDomainName)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		want := fmt.Sprintf("operation-%02d", i)
+		if !IsCircuitOpen(err) {
+			t.Fatalf("Expected the breaker error, got %v", err)
+		}
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.RequestID != want {
+			t.Errorf("Expected an APIError carrying request ID %s, got %v", want, apiErr)
+		}
+		if strings.Count(err.Error(), "request ID: ") != 1 || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected only request ID %s in %q", want, err.Error())
+		}
+	}
+}
+
+func TestLWSClient_CircuitBreakerFailsFast(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(challengePage))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server.URL,
+		WithRetryPolicy(NewDefaultRetryPolicy(5, time.Hour, 1)),
+		WithRateLimit(0, 0, 4),
+		WithCircuitBreaker(3, time.Hour),
+	)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	errs := make([]error, 50)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = client.GetDNSRecord(context.Background(), "zone"+string(rune('a'+i%26))+".com", "1")
+		}(i)
+	}
+	wg.Wait()
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the operations to fail fast, took %s", elapsed)
+	}
+	if n := atomic.LoadInt32(&requests); n > 6 {
+		t.Errorf("Expected the breaker to stop calling the API, got %d requests", n)
+	}
+
+	open := 0
+	for _, err := range errs {
+		if err == nil {
+			t.Fatalf("Expected every operation to fail")
+		}
+		if IsCircuitOpen(err) {
+			open++
+			if !strings.Contains(err.Error(), "consecutive Cloudflare challenges or server errors") {
+				t.Errorf("Expected a clear diagnostic, got %q", err.Error())
+			}
+		}
+	}
+	if open < len(errs)-6 {
+		t.Errorf("Expected most operations to fail with the breaker error, got %d of %d", open, len(errs))
+	}
+}
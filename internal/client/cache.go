@@ -0,0 +1,185 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultZoneCacheTTL is how long a zone listing is reused before it is
+// fetched again from the API.
+const DefaultZoneCacheTTL = 30 * time.Second
+
+// zoneCache keeps a short-lived snapshot of every zone the provider reads,
+// so refreshing many records of the same zone costs a single GET.
+//
+// Concurrent misses for the same zone share one in-flight fetch. Every
+// mutation made through the client bumps the zone generation, which both
+// patches the snapshot and prevents a fetch that started before the
+// mutation from storing a stale listing, or from being joined by callers
+// arriving after it.
+type zoneCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*zoneCacheEntry
+	calls   map[string]*zoneFetch
+	gens    map[string]uint64
+	clock   Clock
+}
+
+type zoneCacheEntry struct {
+	records []DNSRecord
+	expires time.Time
+}
+
+type zoneFetch struct {
+	done    chan struct{}
+	gen     uint64
+	records []DNSRecord
+	err     error
+}
+
+func newZoneCache(ttl time.Duration, clock Clock) *zoneCache {
+	return &zoneCache{
+		ttl:     ttl,
+		clock:   clock,
+		entries: make(map[string]*zoneCacheEntry),
+		calls:   make(map[string]*zoneFetch),
+		gens:    make(map[string]uint64),
+	}
+}
+
+// zoneCacheKey normalises a zone name so "Example.com" and "example.com"
+// share an entry.
+func zoneCacheKey(zoneName string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(zoneName), "."))
+}
+
+// get returns the records of the zone, calling fetch at most once for all
+// concurrent callers when the snapshot is missing or expired.
+func (zc *zoneCache) get(ctx context.Context, zoneName string, fetch func(context.Context) ([]DNSRecord, error)) ([]DNSRecord, error) {
+	key := zoneCacheKey(zoneName)
+
+	for {
+		zc.mu.Lock()
+		if entry, ok := zc.entries[key]; ok && zc.clock.Now().Before(entry.expires) {
+			records := copyRecords(entry.records)
+			zc.mu.Unlock()
+			return records, nil
+		}
+
+		// A fetch started before the last mutation may miss it
+		if call, ok := zc.calls[key]; ok && call.gen == zc.gens[key] {
+			zc.mu.Unlock()
+
+			select {
+			case <-call.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+
+			// The leader was cancelled by its own caller; try again on our behalf.
+			if isContextError(call.err) && ctx.Err() == nil {
+				continue
+			}
+			if call.err != nil {
+				return nil, call.err
+			}
+			return copyRecords(call.records), nil
+		}
+
+		call := &zoneFetch{done: make(chan struct{}), gen: zc.gens[key]}
+		zc.calls[key] = call
+		zc.mu.Unlock()
+
+		call.records, call.err = fetch(ctx)
+
+		zc.mu.Lock()
+		if zc.calls[key] == call {
+			delete(zc.calls, key)
+		}
+		if call.err == nil && zc.gens[key] == call.gen {
+			zc.entries[key] = &zoneCacheEntry{
+				records: copyRecords(call.records),
+				expires: zc.clock.Now().Add(zc.ttl),
+			}
+		}
+		zc.mu.Unlock()
+		close(call.done)
+
+		if call.err != nil {
+			return nil, call.err
+		}
+		return copyRecords(call.records), nil
+	}
+}
+
+// invalidate drops the snapshot of a zone.
+func (zc *zoneCache) invalidate(zoneName string) {
+	key := zoneCacheKey(zoneName)
+
+	zc.mu.Lock()
+	defer zc.mu.Unlock()
+
+	zc.gens[key]++
+	delete(zc.entries, key)
+}
+
+// upsert replaces the record previously known as oldID with record, or adds
+// it when oldID is not in the snapshot.
+func (zc *zoneCache) upsert(zoneName string, oldID int, record DNSRecord) {
+	key := zoneCacheKey(zoneName)
+
+	zc.mu.Lock()
+	defer zc.mu.Unlock()
+
+	zc.gens[key]++
+	entry, ok := zc.entries[key]
+	if !ok {
+		return
+	}
+
+	record.Zone = ""
+	for i := range entry.records {
+		if entry.records[i].ID == oldID {
+			entry.records[i] = record
+			return
+		}
+	}
+	entry.records = append(entry.records, record)
+}
+
+// remove deletes a record from the snapshot.
+func (zc *zoneCache) remove(zoneName string, id int) {
+	key := zoneCacheKey(zoneName)
+
+	zc.mu.Lock()
+	defer zc.mu.Unlock()
+
+	zc.gens[key]++
+	entry, ok := zc.entries[key]
+	if !ok {
+		return
+	}
+
+	records := entry.records[:0]
+	for _, record := range entry.records {
+		if record.ID != id {
+			records = append(records, record)
+		}
+	}
+	entry.records = records
+}
+
+func copyRecords(records []DNSRecord) []DNSRecord {
+	if records == nil {
+		return nil
+	}
+	return append([]DNSRecord(nil), records...)
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,257 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const cacheTestZoneResponse = `{
+	"code": 200,
+	"info": "Fetched DNS Zone",
+	"data": [
+		{"id": 1, "name": "www", "type": "A", "value": "192.168.1.1", "ttl": 3600},
+		{"id": 2, "name": "mail", "type": "CNAME", "value": "www.example.com", "ttl": 3600}
+	]
+}`
+
+func TestLWSClient_ZoneCacheSingleFlight(t *testing.T) {
+	var getCount int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&getCount, 1)
+		time.Sleep(50 * time.Millisecond)
+		_, _ = w.Write([]byte(cacheTestZoneResponse))
+	}))
+	defer server.Close()
+
+	client := NewLWSClient("testlogin", "testkey", server.URL, true, 30, 0, 0, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			record, err := client.GetDNSRecord(context.Background(), "Example.com", "1")
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+			if record.Name != "www" {
+				t.Errorf("Expected record www, got %s", record.Name)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Subsequent reads are served from the snapshot
+	if _, err := client.GetDNSZone(context.Background(), testDomainName); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if getCount != 1 {
+		t.Errorf("Expected a single GET for the zone, got %d", getCount)
+	}
+}
+
+func TestLWSClient_ZoneCacheTTL(t *testing.T) {
+	var getCount int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&getCount, 1)
+		_, _ = w.Write([]byte(cacheTestZoneResponse))
+	}))
+	defer server.Close()
+
+	clock := &fakeClock{now: time.Now()}
+	client := newTestClient(t, server.URL, WithZoneCacheTTL(time.Minute), WithClock(clock))
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.GetDNSZone(context.Background(), testDomainName); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if getCount != 1 {
+		t.Errorf("Expected 1 GET before expiry, got %d", getCount)
+	}
+
+	clock.Advance(61 * time.Second)
+
+	if _, err := client.GetDNSZone(context.Background(), testDomainName); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if getCount != 2 {
+		t.Errorf("Expected a new GET after expiry, got %d", getCount)
+	}
+}
+
+func TestLWSClient_ZoneCacheIsolation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(cacheTestZoneResponse))
+	}))
+	defer server.Close()
+
+	client := NewLWSClient("testlogin", "testkey", server.URL, true, 30, 0, 0, 0)
+
+	zone, err := client.GetDNSZone(context.Background(), testDomainName)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	zone.Records[0].Value = "tampered"
+
+	zone, err = client.GetDNSZone(context.Background(), testDomainName)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if zone.Records[0].Value != "192.168.1.1" {
+		t.Errorf("Caller modifications leaked into the cache: %s", zone.Records[0].Value)
+	}
+}
+
+func TestLWSClient_ZoneCachePatchedByMutations(t *testing.T) {
+	var getCount int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			atomic.AddInt32(&getCount, 1)
+			_, _ = w.Write([]byte(cacheTestZoneResponse))
+		case http.MethodPut:
+			_, _ = w.Write([]byte(`{"code": 200, "info": "Record updated", "data": {"id": 1, "name": "www", "type": "A", "value": "10.0.0.1", "ttl": 3600}}`))
+		case http.MethodDelete:
+			_, _ = w.Write([]byte(`{"code": 200, "info": "Record deleted", "data": null}`))
+		}
+	}))
+	defer server.Close()
+
+	// Without the consistency waiter, mutations are applied to the snapshot
+	client := newTestClient(t, server.URL, WithTestMode(false))
+	ctx := context.Background()
+
+	if _, err := client.GetDNSZone(ctx, testDomainName); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	_, err := client.UpdateDNSRecord(ctx, &DNSRecord{ID: 1, Name: "www", Type: "A", Value: "10.0.0.1", Zone: testDomainName, TTL: 3600})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	record, err := client.GetDNSRecord(ctx, testDomainName, "1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if record.Value != "10.0.0.1" {
+		t.Errorf("Expected patched value 10.0.0.1, got %s", record.Value)
+	}
+
+	if err := client.DeleteDNSRecord(ctx, 2, testDomainName); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := client.GetDNSRecord(ctx, testDomainName, "2"); !IsNotFound(err) {
+		t.Errorf("Expected deleted record to be gone from the snapshot, got %v", err)
+	}
+
+	if getCount != 1 {
+		t.Errorf("Expected mutations to patch the snapshot without refetching, got %d GETs", getCount)
+	}
+}
+
+func TestLWSClient_ZoneCacheInvalidatedByCreate(t *testing.T) {
+	var created int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			atomic.StoreInt32(&created, 1)
+			_, _ = w.Write([]byte(`{"code": 200, "info": "Added a new line in the DNS Zone", "data": {"name": "api", "type": "A", "value": "10.0.0.2", "ttl": 3600}}`))
+		case http.MethodGet:
+			if atomic.LoadInt32(&created) == 1 {
+				_, _ = w.Write([]byte(`{"code": 200, "info": "Fetched DNS Zone", "data": [{"id": 3, "name": "api", "type": "A", "value": "10.0.0.2", "ttl": 3600}]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"code": 200, "info": "Fetched DNS Zone", "data": []}`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewLWSClient("testlogin", "testkey", server.URL, true, 30, 0, 0, 0)
+	ctx := context.Background()
+
+	if _, err := client.GetDNSZone(ctx, testDomainName); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	record, err := client.CreateDNSRecord(ctx, &DNSRecord{Name: "api", Type: "A", Value: "10.0.0.2", Zone: testDomainName, TTL: 3600})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if record.ID != 3 {
+		t.Errorf("Expected ID 3 from a fresh listing, got %d", record.ID)
+	}
+}
+
+func TestLWSClient_ZoneCacheInvalidatedDuringFetch(t *testing.T) {
+	var getCount int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&getCount, 1) == 1 {
+			// The first listing predates the change and is held until after it
+			close(started)
+			<-release
+			_, _ = w.Write([]byte(`{"code": 200, "info": "Fetched DNS Zone", "data": []}`))
+			return
+		}
+		_, _ = w.Write([]byte(cacheTestZoneResponse))
+	}))
+	defer server.Close()
+	releaseOnce := sync.OnceFunc(func() { close(release) })
+	defer releaseOnce()
+
+	client := newTestClient(t, server.URL, WithZoneCacheTTL(time.Minute))
+	ctx := context.Background()
+
+	stale := make(chan int, 1)
+	go func() {
+		zone, err := client.GetDNSZone(ctx, testDomainName)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			stale <- -1
+			return
+		}
+		stale <- len(zone.Records)
+	}()
+	<-started
+
+	client.zones.invalidate(testDomainName)
+
+	// Joining the held listing would block until the deadline
+	fresh, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	zone, err := client.GetDNSZone(fresh, testDomainName)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(zone.Records) != 2 {
+		t.Errorf("Expected a listing fetched after the change, got %d records", len(zone.Records))
+	}
+
+	releaseOnce()
+	if n := <-stale; n != 0 {
+		t.Errorf("Expected the first caller to get the listing it started, got %d records", n)
+	}
+
+	// The stale listing finished last but must not replace the fresh one
+	zone, err = client.GetDNSZone(ctx, testDomainName)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(zone.Records) != 2 {
+		t.Errorf("Expected the fresh listing to stay cached, got %d records", len(zone.Records))
+	}
+	if n := atomic.LoadInt32(&getCount); n != 2 {
+		t.Errorf("Expected 2 GETs, got %d", n)
+	}
+}
@@ -0,0 +1,170 @@
+// Package cassette records the HTTP traffic of the LWS client once and
+// replays it in tests and offline Terraform runs.
+//
+// A Recorder wraps the transport of a client and keeps every exchange, with
+// credentials dropped and secrets scrubbed, as an interaction of a
+// cassette saved as YAML. A Replayer serves a cassette back, matching each
+// request by method, path, query and body. Requests that match no recorded
+// interaction fail with an UnmatchedError, so changes of the requests the
+// client sends are noticed instead of silently answered.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/M4XGO/terraform-provider-lws/internal/client"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the cassette format written by Save.
+const Version = 1
+
+// Cassette is a recorded sequence of HTTP interactions.
+type Cassette struct {
+	Version      int           `yaml:"version"`
+	Interactions []Interaction `yaml:"interactions"`
+}
+
+// Interaction is a request and the response it got.
+type Interaction struct {
+	Request  Request  `yaml:"request"`
+	Response Response `yaml:"response"`
+}
+
+// Request is a recorded request. Credentials are never recorded.
+type Request struct {
+	Method  string            `yaml:"method"`
+	Path    string            `yaml:"path"`
+	Query   string            `yaml:"query,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Body    string            `yaml:"body,omitempty"`
+}
+
+// Response is a recorded response. Error is set instead when the request
+// failed without a response.
+type Response struct {
+	Status  int               `yaml:"status,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Body    string            `yaml:"body,omitempty"`
+	Error   string            `yaml:"error,omitempty"`
+}
+
+// String renders the request as "METHOD /path?query".
+func (r Request) String() string {
+	if r.Query == "" {
+		return r.Method + " " + r.Path
+	}
+	return r.Method + " " + r.Path + "?" + r.Query
+}
+
+// Load reads a cassette. Files ending with ".har" are read as HAR archives,
+// such as those written with LWS_HTTP_CAPTURE, and converted.
+func Load(path string) (*Cassette, error) {
+	if strings.EqualFold(filepath.Ext(path), ".har") {
+		har, err := client.LoadHAR(path)
+		if err != nil {
+			return nil, err
+		}
+		return FromHAR(har), nil
+	}
+
+	data, err := os.ReadFile(path) // #nosec G304 -- the path is chosen by the user
+	if err != nil {
+		return nil, fmt.Errorf("error reading cassette: %w", err)
+	}
+	var c Cassette
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("error decoding cassette %s: %w", path, err)
+	}
+	if c.Version != Version {
+		return nil, fmt.Errorf("unsupported cassette version %d in %s, expected %d", c.Version, path, Version)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette to path as YAML, replacing it atomically.
+func (c *Cassette) Save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("error encoding cassette: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("error writing cassette %s: %w", path, err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing cassette %s: %w", path, err)
+	}
+	return nil
+}
+
+// FromHAR converts the entries of a HAR archive to interactions. The
+// headers it keeps are those of the archive, where credentials are already
+// masked.
+func FromHAR(har *client.HAR) *Cassette {
+	c := &Cassette{Version: Version}
+	for _, entry := range har.Log.Entries {
+		interaction := Interaction{
+			Request:  Request{Method: entry.Request.Method},
+			Response: Response{Status: entry.Response.Status, Body: entry.Response.Content.Text, Error: entry.Error},
+		}
+		if u, err := url.Parse(entry.Request.URL); err == nil {
+			interaction.Request.Path, interaction.Request.Query = u.Path, u.RawQuery
+		}
+		if entry.Request.PostData != nil {
+			interaction.Request.Body = formatBody(entry.Request.PostData.Text)
+		}
+		interaction.Response.Body = formatBody(interaction.Response.Body)
+		for _, h := range entry.Response.Headers {
+			if !client.IsSecretHeader(h.Name) {
+				if interaction.Response.Headers == nil {
+					interaction.Response.Headers = make(map[string]string)
+				}
+				interaction.Response.Headers[h.Name] = h.Value
+			}
+		}
+		c.Interactions = append(c.Interactions, interaction)
+	}
+	return c
+}
+
+// formatBody indents JSON bodies so cassettes read and diff well. Other
+// bodies are kept as they are.
+func formatBody(body string) string {
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, []byte(body), "", "  "); err != nil {
+		return body
+	}
+	return indented.String() + "\n"
+}
+
+// sameBody reports whether two bodies are equal, comparing JSON documents
+// by value so formatting and member order do not matter.
+func sameBody(a, b string) bool {
+	if strings.TrimSpace(a) == strings.TrimSpace(b) {
+		return true
+	}
+	var decodedA, decodedB interface{}
+	if json.Unmarshal([]byte(a), &decodedA) != nil || json.Unmarshal([]byte(b), &decodedB) != nil {
+		return false
+	}
+	normalizedA, errA := json.Marshal(decodedA)
+	normalizedB, errB := json.Marshal(decodedB)
+	return errA == nil && errB == nil && bytes.Equal(normalizedA, normalizedB)
+}
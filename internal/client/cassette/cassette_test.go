@@ -0,0 +1,271 @@
+package cassette
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/M4XGO/terraform-provider-lws/internal/client"
+)
+
+const testZone = "example.com"
+
+// newClient returns a client sending its requests to baseURL through
+// transport, without retries or consistency waits.
+func newClient(t *testing.T, baseURL string, transport http.RoundTripper, opts ...client.Option) *client.LWSClient {
+	t.Helper()
+
+	opts = append([]client.Option{
+		client.WithCredentials("testlogin", "testkey"),
+		client.WithBaseURL(baseURL),
+		client.WithTransport(transport),
+		client.WithRetryPolicy(client.NewDefaultRetryPolicy(0, 0, 1)),
+		client.WithConsistencyWait(0, 0, 1),
+		client.WithZoneCacheTTL(0),
+	}, opts...)
+	c, err := client.New(opts...)
+	if err != nil {
+		t.Fatalf("Unexpected configuration error: %v", err)
+	}
+	return c
+}
+
+// zoneServer is a small stand-in for the zone endpoint of the LWS API.
+type zoneServer struct {
+	mu      sync.Mutex
+	records []client.DNSRecord
+}
+
+func (s *zoneServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Date", "Mon, 01 Jan 2024 00:00:00 GMT")
+	switch r.Method {
+	case http.MethodGet:
+		data, _ := json.Marshal(s.records)
+		_, _ = w.Write([]byte(`{"code": 200, "info": "Fetched DNS Zone", "data": ` + string(data) + `}`))
+	case http.MethodPost:
+		var record client.DNSRecord
+		_ = json.NewDecoder(r.Body).Decode(&record)
+		record.ID = 100 + len(s.records)
+		s.records = append(s.records, record)
+		_, _ = w.Write([]byte(`{"code": 200, "info": "Added a new line in the DNS Zone", "data": {}}`))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	backend := &zoneServer{records: []client.DNSRecord{
+		{ID: 7, Name: "_acme-challenge", Type: "TXT", Value: "token-3f9a1c", TTL: 60},
+	}}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	recorder, err := NewRecorder(nil, RecorderOptions{
+		Replace: map[string]string{"secret-zone.fr": testZone},
+		Redact:  []string{`token-[0-9a-f]+`},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	live := newClient(t, server.URL, recorder)
+	ctx := context.Background()
+	if _, err := live.CreateDNSRecord(ctx, &client.DNSRecord{Name: "www", Type: "A", Value: "192.0.2.10", TTL: 3600, Zone: "secret-zone.fr"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := live.GetDNSZone(ctx, "secret-zone.fr"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "cassette.yaml")
+	if err := recorder.Save(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, secret := range []string{"testkey", "testlogin", "secret-zone.fr", "token-3f9a1c", "Date", "User-Agent"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("Expected %q to be scrubbed from the cassette:\n%s", secret, data)
+		}
+	}
+
+	// The scrubbed cassette replays against any base URL
+	cassette, err := Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(cassette.Interactions) != 3 {
+		t.Fatalf("Expected the create, its lookup and the listing, got %d interactions", len(cassette.Interactions))
+	}
+	replayer := NewReplayer(cassette, ReplayOptions{})
+	offline := newClient(t, "https://lws.invalid", replayer)
+
+	created, err := offline.CreateDNSRecord(ctx, &client.DNSRecord{Name: "www", Type: "A", Value: "192.0.2.10", TTL: 3600, Zone: testZone})
+	if err != nil {
+		t.Fatalf("Unexpected replay error: %v", err)
+	}
+	if created.ID != 101 {
+		t.Errorf("Expected the recorded ID, got %+v", created)
+	}
+	zone, err := offline.GetDNSZone(ctx, testZone)
+	if err != nil {
+		t.Fatalf("Unexpected replay error: %v", err)
+	}
+	if len(zone.Records) != 2 || zone.Records[0].Value != redactedValue {
+		t.Errorf("Expected the redacted listing, got %+v", zone.Records)
+	}
+	if err := replayer.Check(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if unused := replayer.Unused(); len(unused) != 0 {
+		t.Errorf("Expected every interaction to be replayed, got %v", unused)
+	}
+}
+
+func TestReplayer(t *testing.T) {
+	cassette, err := Load(filepath.Join("testdata", "create_record.yaml"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	replayer := NewReplayer(cassette, ReplayOptions{})
+	c := newClient(t, client.DefaultBaseURL, replayer)
+	ctx := context.Background()
+
+	created, err := c.CreateDNSRecord(ctx, &client.DNSRecord{Name: "www", Type: "A", Value: "192.0.2.10", TTL: 3600, Zone: testZone})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if created.ID != 12345 {
+		t.Errorf("Expected the ID of the listing, got %+v", created)
+	}
+	if err := c.DeleteDNSRecord(ctx, created.ID, testZone); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := replayer.Check(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if unused := replayer.Unused(); len(unused) != 0 {
+		t.Errorf("Expected every interaction to be replayed, got %v", unused)
+	}
+}
+
+func TestReplayer_Unmatched(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   ReplayOptions
+		call   func(ctx context.Context, c *client.LWSClient) error
+		want   string
+		passes bool
+	}{
+		{
+			name: "other_body",
+			call: func(ctx context.Context, c *client.LWSClient) error {
+				_, err := c.CreateDNSRecord(ctx, &client.DNSRecord{Name: "www", Type: "A", Value: "192.0.2.99", TTL: 3600, Zone: testZone})
+				return err
+			},
+			want: `no recorded interaction matches POST /v1/domain/example.com/zdns with body`,
+		},
+		{
+			name: "other_path",
+			call: func(ctx context.Context, c *client.LWSClient) error {
+				_, err := c.GetDNSZone(ctx, "example.org")
+				return err
+			},
+			want: "no recorded interaction matches GET /v1/domain/example.org/zdns;",
+		},
+		{
+			name: "read_twice",
+			call: func(ctx context.Context, c *client.LWSClient) error {
+				if _, err := c.GetDNSZone(ctx, testZone); err != nil {
+					return err
+				}
+				_, err := c.GetDNSZone(ctx, testZone)
+				return err
+			},
+			want: "(1 recorded for GET /v1/domain/example.com/zdns, already replayed",
+		},
+		{
+			name: "read_twice_repeated",
+			opts: ReplayOptions{RepeatReads: true},
+			call: func(ctx context.Context, c *client.LWSClient) error {
+				if _, err := c.GetDNSZone(ctx, testZone); err != nil {
+					return err
+				}
+				_, err := c.GetDNSZone(ctx, testZone)
+				return err
+			},
+			passes: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cassette, err := Load(filepath.Join("testdata", "create_record.yaml"))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			replayer := NewReplayer(cassette, tt.opts)
+
+			// Unmatched requests are not retried
+			retries := client.WithRetryPolicy(client.NewDefaultRetryPolicy(2, 0, 1))
+			err = tt.call(context.Background(), newClient(t, client.DefaultBaseURL, replayer, retries))
+			if tt.passes {
+				if err != nil || replayer.Check() != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+
+			var unmatched *UnmatchedError
+			if !errors.As(err, &unmatched) || !client.IsNotRecorded(err) || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Expected an unmatched request error containing %q, got %v", tt.want, err)
+			}
+			if checkErr := replayer.Check(); checkErr == nil || !strings.Contains(checkErr.Error(), "1 request(s) matched no recorded interaction") {
+				t.Errorf("Expected Check to report the request, got %v", checkErr)
+			}
+		})
+	}
+}
+
+func TestLoad_HAR(t *testing.T) {
+	server := httptest.NewServer(&zoneServer{records: []client.DNSRecord{{ID: 1, Name: "www", Type: "A", Value: "192.0.2.1", TTL: 300}}})
+	defer server.Close()
+
+	capture := client.NewHARCapture(client.HARCaptureOptions{})
+	live := newClient(t, server.URL, nil, client.WithHTTPCapture(capture))
+	if _, err := live.GetDNSZone(context.Background(), testZone); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "run.har")
+	if err := capture.WriteFile(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cassette, err := Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(cassette.Interactions) != 1 || cassette.Interactions[0].Request.String() != "GET /domain/example.com/zdns" {
+		t.Fatalf("Unexpected interactions %+v", cassette.Interactions)
+	}
+
+	zone, err := newClient(t, "https://lws.invalid", NewReplayer(cassette, ReplayOptions{})).GetDNSZone(context.Background(), testZone)
+	if err != nil {
+		t.Fatalf("Unexpected replay error: %v", err)
+	}
+	if len(zone.Records) != 1 || zone.Records[0].Value != "192.0.2.1" {
+		t.Errorf("Expected the captured listing, got %+v", zone.Records)
+	}
+}
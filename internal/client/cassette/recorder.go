@@ -0,0 +1,154 @@
+package cassette
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// redactedValue replaces the text matching the redaction patterns.
+const redactedValue = "[REDACTED]"
+
+// recordedRequestHeaders and recordedResponseHeaders are the headers kept
+// in cassettes. Others, such as dates and request IDs, change on every run.
+var (
+	recordedRequestHeaders  = []string{"Content-Type", "X-Test-Mode"}
+	recordedResponseHeaders = []string{"Content-Type", "Retry-After"}
+)
+
+// RecorderOptions configures the scrubbing of a Recorder. Credentials
+// headers are never recorded, whatever the options.
+type RecorderOptions struct {
+	// Replace rewrites literal text in the recorded paths, queries and
+	// bodies, e.g. a real zone name with "example.com".
+	Replace map[string]string
+	// Redact lists regular expressions whose matches are replaced with
+	// "[REDACTED]" in the recorded paths, queries and bodies.
+	Redact []string
+}
+
+// Recorder is an http.RoundTripper that sends requests through another
+// transport and records them with their responses.
+type Recorder struct {
+	next     http.RoundTripper
+	replacer *strings.Replacer
+	redact   []*regexp.Regexp
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder returns a recorder sending requests through next, or through
+// http.DefaultTransport when next is nil.
+func NewRecorder(next http.RoundTripper, opts RecorderOptions) (*Recorder, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	r := &Recorder{next: next, cassette: Cassette{Version: Version}}
+
+	var pairs []string
+	for old, replacement := range opts.Replace {
+		pairs = append(pairs, old, replacement)
+	}
+	r.replacer = strings.NewReplacer(pairs...)
+
+	for _, pattern := range opts.Redact {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		r.redact = append(r.redact, re)
+	}
+	return r, nil
+}
+
+// scrub applies the replacements and redaction patterns to s.
+func (r *Recorder) scrub(s string) string {
+	s = r.replacer.Replace(s)
+	for _, re := range r.redact {
+		s = re.ReplaceAllString(s, redactedValue)
+	}
+	return s
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	interaction := Interaction{Request: Request{
+		Method:  req.Method,
+		Path:    r.scrub(req.URL.Path),
+		Query:   r.scrub(req.URL.RawQuery),
+		Headers: keepHeaders(req.Header, recordedRequestHeaders),
+		Body:    r.scrub(formatBody(string(reqBody))),
+	}}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		interaction.Response.Error = r.scrub(err.Error())
+		r.add(interaction)
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	if err != nil {
+		return resp, err
+	}
+
+	interaction.Response.Status = resp.StatusCode
+	interaction.Response.Headers = keepHeaders(resp.Header, recordedResponseHeaders)
+	interaction.Response.Body = r.scrub(formatBody(string(respBody)))
+	r.add(interaction)
+	return resp, nil
+}
+
+func (r *Recorder) add(interaction Interaction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+}
+
+// Cassette returns the interactions recorded so far.
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := &Cassette{Version: r.cassette.Version}
+	c.Interactions = append(c.Interactions, r.cassette.Interactions...)
+	return c
+}
+
+// Save writes the interactions recorded so far to path.
+func (r *Recorder) Save(path string) error {
+	return r.Cassette().Save(path)
+}
+
+// keepHeaders returns the headers of names present in header.
+func keepHeaders(header http.Header, names []string) map[string]string {
+	var kept map[string]string
+	for _, name := range names {
+		if value := header.Get(name); value != "" {
+			if kept == nil {
+				kept = make(map[string]string)
+			}
+			kept[name] = value
+		}
+	}
+	return kept
+}
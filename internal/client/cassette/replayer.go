@@ -0,0 +1,167 @@
+package cassette
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/M4XGO/terraform-provider-lws/internal/client"
+)
+
+// UnmatchedError is returned for a request that matches no recorded
+// interaction left to replay.
+type UnmatchedError struct {
+	Request Request
+	// Recorded counts the interactions with the same method and path,
+	// already replayed or with another query or body.
+	Recorded int
+}
+
+func (e *UnmatchedError) Error() string {
+	msg := "cassette: no recorded interaction matches " + e.Request.String()
+	if e.Request.Body != "" {
+		msg += " with body " + strconv.Quote(e.Request.Body)
+	}
+	if e.Recorded > 0 {
+		msg += fmt.Sprintf(" (%d recorded for %s %s, already replayed or with another query or body)", e.Recorded, e.Request.Method, e.Request.Path)
+	}
+	return msg + "; the requests sent by the client changed, record the cassette again"
+}
+
+// Is makes errors.Is(err, client.ErrNotRecorded) match, so that the client
+// returns the error at once instead of retrying it.
+func (e *UnmatchedError) Is(target error) bool {
+	return target == client.ErrNotRecorded
+}
+
+// ReplayOptions configures a Replayer.
+type ReplayOptions struct {
+	// RepeatReads replays the last matching GET interaction again once
+	// every match has been used. Terraform reads zones more often in some
+	// runs than in others, mutations are never repeated.
+	RepeatReads bool
+}
+
+// Replayer is an http.RoundTripper answering requests from a cassette.
+// Each request gets the response of the first interaction not yet replayed
+// with the same method, path and query and an equal body; JSON bodies are
+// compared by value. The host of the request is ignored, the path of the
+// base URL it was recorded with, such as "/v1", is not.
+type Replayer struct {
+	opts         ReplayOptions
+	interactions []Interaction
+
+	mu        sync.Mutex
+	used      []bool
+	unmatched []string
+}
+
+// NewReplayer returns a replayer serving the interactions of c.
+func NewReplayer(c *Cassette, opts ReplayOptions) *Replayer {
+	return &Replayer{
+		opts:         opts,
+		interactions: c.Interactions,
+		used:         make([]bool, len(c.Interactions)),
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	want := Request{Method: req.Method, Path: req.URL.Path, Query: req.URL.RawQuery, Body: string(body)}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	recorded, last := 0, -1
+	for i, interaction := range r.interactions {
+		got := interaction.Request
+		if got.Method != want.Method || got.Path != want.Path {
+			continue
+		}
+		recorded++
+		if got.Query != want.Query || !sameBody(got.Body, want.Body) {
+			continue
+		}
+		last = i
+		if !r.used[i] {
+			r.used[i] = true
+			return response(req, interaction.Response)
+		}
+	}
+
+	if last >= 0 && r.opts.RepeatReads && req.Method == http.MethodGet {
+		return response(req, r.interactions[last].Response)
+	}
+
+	err := &UnmatchedError{Request: want, Recorded: recorded}
+	r.unmatched = append(r.unmatched, want.String())
+	return nil, err
+}
+
+// response builds the response of a recorded interaction.
+func response(req *http.Request, recorded Response) (*http.Response, error) {
+	if recorded.Status == 0 {
+		return nil, fmt.Errorf("replayed transport error: %s", recorded.Error)
+	}
+
+	header := make(http.Header, len(recorded.Headers))
+	for name, value := range recorded.Headers {
+		header.Set(name, value)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewBufferString(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Unmatched returns the requests that matched no interaction, as
+// "METHOD /path?query".
+func (r *Replayer) Unmatched() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.unmatched...)
+}
+
+// Unused returns the interactions that were never replayed.
+func (r *Replayer) Unused() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var unused []string
+	for i, interaction := range r.interactions {
+		if !r.used[i] {
+			unused = append(unused, interaction.Request.String())
+		}
+	}
+	return unused
+}
+
+// Check returns an error listing the requests that matched no interaction,
+// for tests to assert at the end that the code under test did not ignore
+// the errors it got for them.
+func (r *Replayer) Check() error {
+	unmatched := r.Unmatched()
+	if len(unmatched) == 0 {
+		return nil
+	}
+	return fmt.Errorf("cassette: %d request(s) matched no recorded interaction: %v", len(unmatched), unmatched)
+}
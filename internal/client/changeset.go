@@ -0,0 +1,419 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/M4XGO/terraform-provider-lws/internal/client/rdata"
+)
+
+// DefaultChangeSetParallelism is the number of changes of a ChangeSet
+// applied at the same time when Parallelism is not set.
+const DefaultChangeSetParallelism = 4
+
+// ChangeKind is the operation of a Change.
+type ChangeKind string
+
+// Kinds of changes.
+const (
+	ChangeCreate ChangeKind = "create"
+	ChangeUpdate ChangeKind = "update"
+	ChangeDelete ChangeKind = "delete"
+)
+
+// Change is one operation of a ChangeSet.
+type Change struct {
+	Kind ChangeKind
+	// Record is the record to create, or the new content of the record
+	// with ID Record.ID for an update. Deletes only use Record.ID.
+	Record DNSRecord
+}
+
+// ChangeStatus is the outcome of a Change.
+type ChangeStatus string
+
+// Outcomes of a change.
+const (
+	// ChangeApplied changes were applied and, if the set failed, not reverted.
+	ChangeApplied ChangeStatus = "applied"
+	// ChangeFailed changes were attempted and failed. A failed create that
+	// may have added its record anyway has it removed by the rollback.
+	ChangeFailed ChangeStatus = "failed"
+	// ChangeSkipped changes were not attempted because another one failed.
+	ChangeSkipped ChangeStatus = "skipped"
+	// ChangeRolledBack changes were applied, then reverted.
+	ChangeRolledBack ChangeStatus = "rolled_back"
+	// ChangeRollbackFailed changes were applied, or may have been, and could
+	// not be reverted.
+	ChangeRollbackFailed ChangeStatus = "rollback_failed"
+)
+
+// ChangeSet is a group of changes to a zone applied together: either all
+// of them are applied, or those applied before one failed are reverted.
+//
+// Deletes are applied first, then updates, then creates, so that a name
+// freed by the set can be reused by it. Changes of the same kind run in
+// parallel, at most Parallelism at a time.
+type ChangeSet struct {
+	Zone    string
+	Changes []Change
+	// Parallelism bounds the changes applied at the same time. Zero means
+	// DefaultChangeSetParallelism.
+	Parallelism int
+}
+
+// NewChangeSet returns an empty change set for a zone.
+func NewChangeSet(zoneName string) *ChangeSet {
+	return &ChangeSet{Zone: zoneName}
+}
+
+// Create adds the creation of a record to the set.
+func (cs *ChangeSet) Create(record DNSRecord) *ChangeSet {
+	cs.Changes = append(cs.Changes, Change{Kind: ChangeCreate, Record: record})
+	return cs
+}
+
+// Update adds the update of the record with ID record.ID to the set.
+func (cs *ChangeSet) Update(record DNSRecord) *ChangeSet {
+	cs.Changes = append(cs.Changes, Change{Kind: ChangeUpdate, Record: record})
+	return cs
+}
+
+// Delete adds the deletion of a record to the set.
+func (cs *ChangeSet) Delete(recordID int) *ChangeSet {
+	cs.Changes = append(cs.Changes, Change{Kind: ChangeDelete, Record: DNSRecord{ID: recordID}})
+	return cs
+}
+
+// ChangeResult reports what happened to one change of a set.
+type ChangeResult struct {
+	Change Change
+	Status ChangeStatus
+	// Before is the record as it was in the snapshot, for updates and
+	// deletes.
+	Before *DNSRecord
+	// After is the record as the API returned it, for applied creates and
+	// updates.
+	After *DNSRecord
+	// Err is the error of the change, for failed changes.
+	Err error
+	// RollbackErr is the error of reverting the change, for changes whose
+	// rollback failed.
+	RollbackErr error
+}
+
+// ChangeReport is the outcome of applying a ChangeSet. Results are in the
+// order of the changes of the set.
+type ChangeReport struct {
+	Zone    string
+	Results []ChangeResult
+}
+
+// Err returns nil when every change was applied, and otherwise an error
+// listing the failed changes and the ones left applied by a failed rollback.
+func (r *ChangeReport) Err() error {
+	var errs []error
+	for i, result := range r.Results {
+		switch result.Status {
+		case ChangeFailed:
+			errs = append(errs, fmt.Errorf("change %d (%s %s): %w", i, result.Change.Kind, describeChange(result), result.Err))
+		case ChangeRollbackFailed:
+			if result.Err != nil {
+				// A failed create that may have landed anyway
+				errs = append(errs, fmt.Errorf("change %d (%s %s): %w", i, result.Change.Kind, describeChange(result), result.Err))
+			}
+			errs = append(errs, fmt.Errorf("change %d (%s %s) could not be reverted: %w", i, result.Change.Kind, describeChange(result), result.RollbackErr))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("change set for zone '%s' failed: %w", r.Zone, errors.Join(errs...))
+}
+
+// Apply validates the set against a snapshot of the zone, then applies it.
+// Invalid sets are rejected before any change is made. When a change
+// fails, the remaining ones are skipped and the applied ones are reverted
+// from the snapshot, even if ctx is cancelled by then. The returned error is
+// that of the validation or ChangeReport.Err.
+//
+// When api implements ZoneLocker, the zone lock is held for the whole set.
+func (cs *ChangeSet) Apply(ctx context.Context, api DNSAPI) (*ChangeReport, error) {
+	if locker, ok := api.(ZoneLocker); ok {
+		lockedCtx, unlock, err := locker.LockZone(ctx, cs.Zone)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+		ctx = lockedCtx
+	}
+
+	zone, err := api.GetDNSZone(ctx, cs.Zone)
+	if err != nil {
+		return nil, fmt.Errorf("error getting DNS zone '%s': %w", cs.Zone, err)
+	}
+
+	report := &ChangeReport{Zone: cs.Zone, Results: make([]ChangeResult, len(cs.Changes))}
+	if err := cs.validate(zone.Records, report); err != nil {
+		return nil, err
+	}
+
+	failed := false
+	for _, kind := range []ChangeKind{ChangeDelete, ChangeUpdate, ChangeCreate} {
+		if !failed {
+			failed = cs.applyKind(ctx, api, kind, report)
+		}
+	}
+
+	if failed {
+		// Revert what was applied even if the caller gave up on the set
+		cs.rollback(context.WithoutCancel(ctx), api, report)
+	}
+
+	return report, report.Err()
+}
+
+// validate checks the changes against the records of the zone and fills in
+// the snapshot of the records they touch.
+func (cs *ChangeSet) validate(records []DNSRecord, report *ChangeReport) error {
+	byID := make(map[int]DNSRecord, len(records))
+	for _, record := range records {
+		byID[record.ID] = record
+	}
+
+	// A record the set deletes may be created again by it
+	deleted := make(map[int]bool)
+	for _, change := range cs.Changes {
+		if change.Kind == ChangeDelete {
+			deleted[change.Record.ID] = true
+		}
+	}
+
+	var errs []error
+	touched := make(map[int]int)
+	created := make(map[RecordIdentity]int)
+	for i, change := range cs.Changes {
+		report.Results[i] = ChangeResult{Change: change, Status: ChangeSkipped}
+		record := change.Record
+		record.Zone = cs.Zone
+
+		switch change.Kind {
+		case ChangeUpdate, ChangeDelete:
+			before, ok := byID[record.ID]
+			if !ok {
+				errs = append(errs, fmt.Errorf("change %d: no record with ID %d in zone '%s'", i, record.ID, cs.Zone))
+				continue
+			}
+			if j, dup := touched[record.ID]; dup {
+				errs = append(errs, fmt.Errorf("change %d: record %d is already changed by change %d", i, record.ID, j))
+				continue
+			}
+			touched[record.ID] = i
+			before.Zone = cs.Zone
+			report.Results[i].Before = &before
+
+		case ChangeCreate:
+			identity := IdentityOf(record)
+			if existing := FindRecord(records, identity); existing != nil && !deleted[existing.ID] {
+				errs = append(errs, fmt.Errorf("change %d: zone '%s' already holds %s record '%s' with this value (ID %d)", i, cs.Zone, record.Type, record.Name, existing.ID))
+				continue
+			}
+			if j, dup := created[identity]; dup {
+				errs = append(errs, fmt.Errorf("change %d: same record as change %d", i, j))
+				continue
+			}
+			created[identity] = i
+
+		default:
+			errs = append(errs, fmt.Errorf("change %d: unknown kind %q", i, change.Kind))
+			continue
+		}
+
+		if change.Kind != ChangeDelete {
+			if err := ValidateRecord(record); err != nil {
+				errs = append(errs, fmt.Errorf("change %d: %w", i, err))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid change set for zone '%s': %w", cs.Zone, errors.Join(errs...))
+	}
+	return nil
+}
+
+// ValidateRecord checks that a record to write has a name, a type and a
+// value that parses for its type.
+func ValidateRecord(record DNSRecord) error {
+	if strings.TrimSpace(record.Name) == "" || strings.TrimSpace(record.Type) == "" {
+		return fmt.Errorf("record name and type are required")
+	}
+	if strings.TrimSpace(record.Value) == "" {
+		return fmt.Errorf("%s record '%s' has no value", record.Type, record.Name)
+	}
+	if rdata.Supported(record.Type) {
+		if _, err := rdata.Parse(record.Type, record.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyKind applies the changes of one kind, at most Parallelism at a time.
+// Once one fails, changes not started yet are skipped. It reports whether a
+// change failed.
+func (cs *ChangeSet) applyKind(ctx context.Context, api DNSAPI, kind ChangeKind, report *ChangeReport) bool {
+	parallelism := cs.Parallelism
+	if parallelism <= 0 {
+		parallelism = DefaultChangeSetParallelism
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed bool
+		slots  = make(chan struct{}, parallelism)
+	)
+
+	for i := range cs.Changes {
+		if cs.Changes[i].Kind != kind {
+			continue
+		}
+
+		slots <- struct{}{}
+		mu.Lock()
+		stop := failed
+		mu.Unlock()
+		if stop {
+			<-slots
+			break
+		}
+
+		wg.Add(1)
+		go func(result *ChangeResult) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			after, err := cs.applyChange(ctx, api, result.Change)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				result.Status, result.Err = ChangeFailed, err
+				failed = true
+				return
+			}
+			result.Status, result.After = ChangeApplied, after
+		}(&report.Results[i])
+	}
+
+	wg.Wait()
+	return failed
+}
+
+// applyChange makes one change.
+func (cs *ChangeSet) applyChange(ctx context.Context, api DNSAPI, change Change) (*DNSRecord, error) {
+	record := change.Record
+	record.Zone = cs.Zone
+
+	switch change.Kind {
+	case ChangeCreate:
+		return api.CreateDNSRecord(ctx, &record)
+	case ChangeUpdate:
+		return api.UpdateDNSRecord(ctx, &record)
+	default:
+		return nil, api.DeleteDNSRecord(ctx, record.ID, cs.Zone)
+	}
+}
+
+// rollback reverts the applied changes, newest kind first: creates are
+// deleted, updated records get their snapshot content back and deleted ones
+// are created again. Failed creates that may have landed are looked up and
+// deleted too.
+func (cs *ChangeSet) rollback(ctx context.Context, api DNSAPI, report *ChangeReport) {
+	for _, kind := range []ChangeKind{ChangeCreate, ChangeUpdate, ChangeDelete} {
+		for i := range report.Results {
+			result := &report.Results[i]
+			if kind == ChangeCreate && result.Change.Kind == kind && result.Status == ChangeFailed && createMayHaveLanded(result.Err) {
+				if err := cs.removeLanded(ctx, api, result.Change.Record); err != nil {
+					result.Status, result.RollbackErr = ChangeRollbackFailed, err
+				}
+				continue
+			}
+			if result.Change.Kind != kind || result.Status != ChangeApplied {
+				continue
+			}
+
+			var err error
+			switch kind {
+			case ChangeCreate:
+				err = api.DeleteDNSRecord(ctx, result.After.ID, cs.Zone)
+			case ChangeUpdate:
+				// LWS may have given the record a new ID
+				before := *result.Before
+				if result.After != nil && result.After.ID != 0 {
+					before.ID = result.After.ID
+				}
+				_, err = api.UpdateDNSRecord(ctx, &before)
+			case ChangeDelete:
+				before := *result.Before
+				before.ID = 0
+				_, err = api.CreateDNSRecord(ctx, &before)
+			}
+
+			if err != nil {
+				result.Status, result.RollbackErr = ChangeRollbackFailed, err
+				continue
+			}
+			result.Status = ChangeRolledBack
+		}
+	}
+}
+
+// createMayHaveLanded reports whether a failed create may still have added
+// its record: unless LWS rejected the POST itself, or it was never sent, the
+// record may be in the zone, e.g. when the response was lost or the ID of
+// the record could not be found.
+func createMayHaveLanded(err error) bool {
+	if IsCircuitOpen(err) || IsNotRecorded(err) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.Method == http.MethodPost {
+		return apiErr.StatusCode >= http.StatusInternalServerError
+	}
+	return true
+}
+
+// removeLanded deletes the record of a failed create when a fresh listing
+// of the zone holds it.
+func (cs *ChangeSet) removeLanded(ctx context.Context, api DNSAPI, record DNSRecord) error {
+	record.Zone = cs.Zone
+	zone, err := api.GetDNSZone(ctx, cs.Zone)
+	if err != nil {
+		return fmt.Errorf("unable to check whether the record was created anyway: %w", err)
+	}
+	landed := FindRecord(zone.Records, IdentityOf(record))
+	if landed == nil {
+		return nil
+	}
+	if err := api.DeleteDNSRecord(ctx, landed.ID, cs.Zone); err != nil && !IsNotFound(err) {
+		return fmt.Errorf("the record was created anyway (ID %d) and could not be deleted: %w", landed.ID, err)
+	}
+	return nil
+}
+
+// describeChange names the record a change is about.
+func describeChange(result ChangeResult) string {
+	record := result.Change.Record
+	if result.Before != nil {
+		record = *result.Before
+	}
+	if record.Name == "" {
+		return fmt.Sprintf("record %d", record.ID)
+	}
+	return fmt.Sprintf("%s record '%s'", record.Type, record.Name)
+}
@@ -0,0 +1,362 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/M4XGO/terraform-provider-lws/internal/lwsfake"
+)
+
+// changeSetServer is a laggingZoneServer without lag that fails the
+// mutations matched by fail and records how many run at the same time.
+type changeSetServer struct {
+	zone  laggingZoneServer
+	fail  func(method string, body []byte) bool
+	delay time.Duration
+
+	mu          sync.Mutex
+	mutations   int
+	inFlight    int
+	maxInFlight int
+}
+
+func (s *changeSetServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		s.zone.ServeHTTP(w, r)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	s.mu.Lock()
+	s.mutations++
+	s.inFlight++
+	s.maxInFlight = max(s.maxInFlight, s.inFlight)
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.inFlight--
+		s.mu.Unlock()
+	}()
+
+	time.Sleep(s.delay)
+	if s.fail != nil && s.fail(r.Method, body) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"code": 500, "info": "Internal error", "data": null}`))
+		return
+	}
+	s.zone.ServeHTTP(w, r)
+}
+
+// contents returns the records of the zone without their IDs, sorted.
+func (s *changeSetServer) contents() []string {
+	s.zone.mu.Lock()
+	defer s.zone.mu.Unlock()
+
+	var contents []string
+	for _, record := range s.zone.records {
+		contents = append(contents, strings.Join([]string{record.Name, record.Type, record.Value}, " "))
+	}
+	sort.Strings(contents)
+	return contents
+}
+
+// newChangeSetServer serves a zone with two MX records and a CNAME.
+func newChangeSetServer(t *testing.T, fail func(method string, body []byte) bool) (*changeSetServer, *LWSClient) {
+	t.Helper()
+
+	server := &changeSetServer{fail: fail}
+	server.zone.records = []DNSRecord{
+		{ID: 1, Name: "@", Type: "MX", Value: "10 mx1.example.com", TTL: 3600},
+		{ID: 2, Name: "@", Type: "MX", Value: "20 mx2.example.com", TTL: 3600},
+		{ID: 3, Name: "old", Type: "CNAME", Value: "www.example.com", TTL: 3600},
+	}
+	server.zone.nextID = 3
+
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	return server, newTestClient(t, ts.URL, WithRateLimit(0, 0, 8))
+}
+
+// moveSet swaps the MX priorities and renames the CNAME.
+func moveSet() *ChangeSet {
+	return NewChangeSet(testDomainName).
+		Update(DNSRecord{ID: 1, Name: "@", Type: "MX", Value: "20 mx1.example.com", TTL: 3600}).
+		Update(DNSRecord{ID: 2, Name: "@", Type: "MX", Value: "10 mx2.example.com", TTL: 3600}).
+		Delete(3).
+		Create(DNSRecord{Name: "new", Type: "CNAME", Value: "www.example.com", TTL: 3600})
+}
+
+func statuses(report *ChangeReport) []ChangeStatus {
+	var got []ChangeStatus
+	for _, result := range report.Results {
+		got = append(got, result.Status)
+	}
+	return got
+}
+
+func TestChangeSet_Apply(t *testing.T) {
+	server, client := newChangeSetServer(t, nil)
+
+	report, err := moveSet().Apply(context.Background(), client)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []ChangeStatus{ChangeApplied, ChangeApplied, ChangeApplied, ChangeApplied}
+	if got := statuses(report); !equalStatuses(got, want) {
+		t.Errorf("Expected statuses %v, got %v", want, got)
+	}
+	if report.Results[2].Before == nil || report.Results[2].Before.Name != "old" {
+		t.Errorf("Expected the deleted record in the report, got %+v", report.Results[2].Before)
+	}
+
+	wantContents := []string{"@ MX 10 mx2.example.com", "@ MX 20 mx1.example.com", "new CNAME www.example.com"}
+	if got := server.contents(); strings.Join(got, "|") != strings.Join(wantContents, "|") {
+		t.Errorf("Expected zone %v, got %v", wantContents, got)
+	}
+}
+
+func TestChangeSet_ApplyRollsBack(t *testing.T) {
+	tests := []struct {
+		name   string
+		fail   func(method string, body []byte) bool
+		set    *ChangeSet
+		want   []ChangeStatus
+		intact bool
+	}{
+		{
+			name: "create_fails",
+			fail: func(method string, body []byte) bool {
+				return method == http.MethodPost && bytes.Contains(body, []byte("broken"))
+			},
+			set: moveSet().Create(DNSRecord{Name: "@", Type: "TXT", Value: "broken", TTL: 3600}),
+			want: []ChangeStatus{
+				ChangeRolledBack, ChangeRolledBack, ChangeRolledBack, ChangeRolledBack, ChangeFailed,
+			},
+			intact: true,
+		},
+		{
+			name: "update_fails_before_creates",
+			fail: func(method string, body []byte) bool {
+				return method == http.MethodPut && bytes.Contains(body, []byte("mx2"))
+			},
+			set:    moveSet(),
+			want:   []ChangeStatus{ChangeRolledBack, ChangeFailed, ChangeRolledBack, ChangeSkipped},
+			intact: true,
+		},
+		{
+			name: "rollback_fails",
+			fail: func(method string, body []byte) bool {
+				// The new CNAME can't be deleted again
+				return (method == http.MethodPost && bytes.Contains(body, []byte("broken"))) ||
+					(method == http.MethodDelete && bytes.Contains(body, []byte(`"id":4`)))
+			},
+			set: moveSet().Create(DNSRecord{Name: "@", Type: "TXT", Value: "broken", TTL: 3600}),
+			want: []ChangeStatus{
+				ChangeRolledBack, ChangeRolledBack, ChangeRolledBack, ChangeRollbackFailed, ChangeFailed,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := newChangeSetServer(t, tt.fail)
+			before := server.contents()
+			tt.set.Parallelism = 1
+
+			report, err := tt.set.Apply(context.Background(), client)
+			if err == nil {
+				t.Fatalf("Expected an error")
+			}
+			if report == nil {
+				t.Fatalf("Expected a report with the error %v", err)
+			}
+			if got := statuses(report); !equalStatuses(got, tt.want) {
+				t.Errorf("Expected statuses %v, got %v", tt.want, got)
+			}
+			for _, result := range report.Results {
+				if result.Status == ChangeFailed && !errors.Is(err, result.Err) {
+					t.Errorf("Expected the error to wrap %v, got %v", result.Err, err)
+				}
+				if result.Status == ChangeRollbackFailed && !errors.Is(err, result.RollbackErr) {
+					t.Errorf("Expected the error to wrap %v, got %v", result.RollbackErr, err)
+				}
+			}
+
+			if after := server.contents(); tt.intact && strings.Join(after, "|") != strings.Join(before, "|") {
+				t.Errorf("Expected the zone to be restored to %v, got %v", before, after)
+			}
+		})
+	}
+}
+
+func TestChangeSet_ApplyRemovesLandedCreates(t *testing.T) {
+	tests := []struct {
+		name   string
+		faults []lwsfake.Fault
+		want   []ChangeStatus
+		intact bool
+	}{
+		{
+			name:   "removed",
+			faults: []lwsfake.Fault{{Method: http.MethodPost, Drop: true, Times: 1}},
+			want:   []ChangeStatus{ChangeRolledBack, ChangeFailed},
+			intact: true,
+		},
+		{
+			name: "removal_fails",
+			faults: []lwsfake.Fault{
+				{Method: http.MethodPost, Drop: true, Times: 1},
+				{Method: http.MethodDelete, Status: http.StatusBadGateway},
+			},
+			want: []ChangeStatus{ChangeRolledBack, ChangeRollbackFailed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seeded := []lwsfake.Record{
+				{ID: 1, Name: "@", Type: "MX", Value: "10 mx1.example.com", TTL: 3600},
+				{ID: 2, Name: "old", Type: "CNAME", Value: "www.example.com", TTL: 3600},
+			}
+			fake := lwsfake.New(lwsfake.WithCredentials("testlogin", "testkey"), lwsfake.WithZone(testDomainName, seeded...))
+			ts := httptest.NewServer(fake)
+			defer ts.Close()
+			client := newTestClient(t, ts.URL, WithTestMode(false), WithRateLimit(0, 0, 8))
+
+			// The create lands but its response is lost, and it is not retried
+			fake.Inject(tt.faults...)
+			report, err := NewChangeSet(testDomainName).
+				Update(DNSRecord{ID: 1, Name: "@", Type: "MX", Value: "20 mx1.example.com", TTL: 3600}).
+				Create(DNSRecord{Name: "new", Type: "CNAME", Value: "www.example.com", TTL: 3600}).
+				Apply(context.Background(), client)
+			if err == nil || report == nil {
+				t.Fatalf("Expected a failed set with a report, got %v", err)
+			}
+			if got := statuses(report); !equalStatuses(got, tt.want) {
+				t.Errorf("Expected statuses %v, got %v", tt.want, got)
+			}
+			if !errors.Is(err, report.Results[1].Err) {
+				t.Errorf("Expected the error to wrap the failure of the create %v, got %v", report.Results[1].Err, err)
+			}
+
+			names := make(map[string]bool)
+			for _, record := range fake.Records(testDomainName) {
+				names[record.Name] = true
+			}
+			if names["new"] == tt.intact {
+				t.Errorf("Expected the landed record to be removed=%v, got zone %+v", tt.intact, fake.Records(testDomainName))
+			}
+		})
+	}
+}
+
+func TestChangeSet_ApplyValidates(t *testing.T) {
+	tests := []struct {
+		name string
+		set  *ChangeSet
+		want string
+	}{
+		{
+			name: "unknown_record",
+			set:  NewChangeSet(testDomainName).Delete(42),
+			want: "no record with ID 42",
+		},
+		{
+			name: "changed_twice",
+			set: NewChangeSet(testDomainName).
+				Update(DNSRecord{ID: 1, Name: "@", Type: "MX", Value: "5 mx1.example.com"}).
+				Delete(1),
+			want: "already changed by change 0",
+		},
+		{
+			name: "existing_record",
+			set:  NewChangeSet(testDomainName).Create(DNSRecord{Name: "@", Type: "MX", Value: "10 MX1.example.com."}),
+			want: "already holds MX record '@'",
+		},
+		{
+			name: "created_twice",
+			set: NewChangeSet(testDomainName).
+				Create(DNSRecord{Name: "www", Type: "A", Value: "192.168.1.1"}).
+				Create(DNSRecord{Name: "www", Type: "A", Value: "192.168.1.1"}),
+			want: "same record as change 0",
+		},
+		{
+			name: "invalid_value",
+			set:  NewChangeSet(testDomainName).Update(DNSRecord{ID: 2, Name: "@", Type: "MX", Value: "mx2.example.com"}),
+			want: "invalid MX data",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := newChangeSetServer(t, nil)
+
+			report, err := tt.set.Apply(context.Background(), client)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Expected an error containing %q, got %v", tt.want, err)
+			}
+			if report != nil {
+				t.Errorf("Expected no report for an invalid set, got %+v", report)
+			}
+			if server.mutations != 0 {
+				t.Errorf("Expected no change to be sent, got %d", server.mutations)
+			}
+		})
+	}
+
+	// Deleting a record and creating it again is allowed
+	_, client := newChangeSetServer(t, nil)
+	set := NewChangeSet(testDomainName).
+		Delete(3).
+		Create(DNSRecord{Name: "old", Type: "CNAME", Value: "www.example.com", TTL: 300})
+	if _, err := set.Apply(context.Background(), client); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestChangeSet_ApplyParallelism(t *testing.T) {
+	server, client := newChangeSetServer(t, nil)
+	server.delay = 10 * time.Millisecond
+
+	set := NewChangeSet(testDomainName)
+	set.Parallelism = 2
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		set.Create(DNSRecord{Name: name, Type: "A", Value: "192.168.1.1", TTL: 300})
+	}
+
+	if _, err := set.Apply(context.Background(), client); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if server.maxInFlight > 2 {
+		t.Errorf("Expected at most 2 changes at a time, got %d", server.maxInFlight)
+	}
+	if got := len(server.contents()); got != 9 {
+		t.Errorf("Expected 9 records, got %d", got)
+	}
+}
+
+func equalStatuses(a, b []ChangeStatus) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
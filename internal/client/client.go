@@ -4,27 +4,38 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 // LWSClient represents the LWS API client
 type LWSClient struct {
-	Login    string
-	ApiKey   string
-	BaseURL  string
-	TestMode bool
-	client   *http.Client
-	retries  int
-	delay    int
-	backoff  int
-	mu       sync.Mutex
+	Login       string
+	ApiKey      string
+	BaseURL     string
+	TestMode    bool
+	endpoints   *endpointPool
+	client      *http.Client
+	retryPolicy RetryPolicy
+	limiter     *RateLimiter
+	breaker     *CircuitBreaker
+	zones       *zoneCache
+	locks       *zoneLocks
+	consistency *ConsistencyWaiter
+	logger      Logger
+	redactor    *Redactor
+	userAgent   string
+	clock       Clock
+	metrics     *Metrics
+	tracer      trace.Tracer
+	capture     *HARCapture
 }
 
 // DNSRecord represents a DNS record
@@ -47,7 +58,8 @@ type DNSZone struct {
 type LWSAPIResponse struct {
 	Code int         `json:"code"`
 	Info interface{} `json:"info"` // Can be string or object
-	Data interface{} `json:"data"`
+	// Data is decoded by the caller, which knows what it holds
+	Data json.RawMessage `json:"data"`
 }
 
 // GetInfoMessage extracts a readable message from the info field
@@ -85,28 +97,57 @@ type UpdateDNSRecordRequest struct {
 	TTL   int    `json:"ttl"`
 }
 
-// NewLWSClient creates a new LWS API client
+// NewLWSClient creates a new LWS API client with the default rate limits
+// and zone cache. The configuration is not validated; prefer New, which
+// reports invalid settings.
 func NewLWSClient(login, apiKey, baseURL string, testMode bool, timeout int, retries int, delay int, backoff int) *LWSClient {
-	return &LWSClient{
-		Login:    login,
-		ApiKey:   apiKey,
-		BaseURL:  baseURL,
-		TestMode: testMode,
-		retries:  retries,
-		delay:    delay,
-		backoff:  backoff,
-		client: &http.Client{
-			Timeout: time.Duration(timeout) * time.Second,
-		},
-	}
+	cfg := DefaultConfig()
+	cfg.Login = login
+	cfg.APIKey = apiKey
+	cfg.BaseURL = baseURL
+	cfg.TestMode = testMode
+	cfg.Timeout = time.Duration(timeout) * time.Second
+	cfg.RetryPolicy = NewDefaultRetryPolicy(retries, time.Duration(delay)*time.Second, float64(backoff))
+	cfg.Burst = int(DefaultRequestsPerSecond)
+
+	return newClient(cfg)
 }
 
-// makeRequest makes an HTTP request to the LWS API
+// makeRequest makes an HTTP request to the LWS API, retrying failed
+// attempts according to the client retry policy. Every attempt carries the
+// request ID of the operation, which is also added to the returned error
+// unless the call is made within another one, see withRequestIDError.
 func (c *LWSClient) makeRequest(ctx context.Context, method, endpoint string, body interface{}) (*LWSAPIResponse, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	ctx, outermost := withRequestIDError(withOperation(ctx))
 
-	var reqBody io.Reader
+	apiResp, err := c.makeRequestWithRetries(ctx, method, endpoint, body, nil)
+	if !outermost {
+		return apiResp, err
+	}
+	return apiResp, withRequestID(err, RequestIDFromContext(ctx))
+}
+
+// retryGuard runs before a non-idempotent call is sent again. It reports
+// whether the API applied the previous attempt despite the error, in which
+// case the call must not be resent.
+type retryGuard func(ctx context.Context) (applied bool, err error)
+
+// makeGuardedRequest is makeRequest for calls that are not idempotent, such
+// as creates. Since guard is checked before every retry, these calls are
+// retried like idempotent ones. When guard reports the call applied, a nil
+// response and a nil error are returned.
+func (c *LWSClient) makeGuardedRequest(ctx context.Context, method, endpoint string, body interface{}, guard retryGuard) (*LWSAPIResponse, error) {
+	ctx, outermost := withRequestIDError(withOperation(ctx))
+
+	apiResp, err := c.makeRequestWithRetries(ctx, method, endpoint, body, guard)
+	if !outermost {
+		return apiResp, err
+	}
+	return apiResp, withRequestID(err, RequestIDFromContext(ctx))
+}
+
+// makeRequestWithRetries runs the attempts of makeRequest
+func (c *LWSClient) makeRequestWithRetries(ctx context.Context, method, endpoint string, body interface{}, guard retryGuard) (*LWSAPIResponse, error) {
 	var reqBodyBytes []byte
 	if body != nil {
 		jsonData, err := json.Marshal(body)
@@ -114,10 +155,116 @@ func (c *LWSClient) makeRequest(ctx context.Context, method, endpoint string, bo
 			return nil, fmt.Errorf("error marshaling request body: %w", err)
 		}
 		reqBodyBytes = jsonData
-		reqBody = bytes.NewBuffer(jsonData)
 	}
 
-	url := fmt.Sprintf("%s/%s", c.BaseURL, endpoint)
+	maxAttempts := c.retryPolicy.MaxAttempts()
+	for attempt := 1; ; attempt++ {
+		c.log(ctx, LogDebug, "Sending LWS API request", map[string]interface{}{
+			"method":       method,
+			"endpoint":     endpoint,
+			"attempt":      attempt,
+			"max_attempts": maxAttempts,
+			"test_mode":    c.TestMode,
+		})
+		attemptCtx, span := c.startAttempt(ctx, method, endpoint, attempt, maxAttempts)
+		apiResp, url, err := c.doRequestFailover(attemptCtx, method, endpoint, reqBodyBytes, attempt, guard)
+		if err == nil {
+			c.limiter.Succeeded()
+			endAttempt(span, nil, false, 0)
+			return apiResp, nil
+		}
+
+		if IsCircuitOpen(err) {
+			endAttempt(span, err, false, 0)
+			return nil, err
+		}
+
+		if IsRateLimited(err) || IsChallenge(err) {
+			c.limiter.Throttled()
+			c.log(ctx, LogWarn, "LWS API is throttling requests, lowering the request rate", map[string]interface{}{
+				"method":   method,
+				"endpoint": endpoint,
+				"attempt":  attempt,
+				"rate":     c.limiter.Rate(),
+			})
+		}
+
+		retryMethod := method
+		if guard != nil {
+			retryMethod = http.MethodPut
+		}
+		if ctx.Err() != nil || attempt >= maxAttempts || !c.retryPolicy.ShouldRetry(retryMethod, err) {
+			endAttempt(span, err, false, 0)
+			return apiResp, err
+		}
+
+		wait := c.retryPolicy.Backoff(attempt, err)
+		endAttempt(span, err, true, wait)
+		c.log(ctx, LogDebug, "Retrying LWS API request", map[string]interface{}{
+			"method":   method,
+			"endpoint": endpoint,
+			"attempt":  attempt,
+			"error":    err.Error(),
+			"wait":     wait.String(),
+		})
+		if waitErr := c.waitRetry(ctx, wait); waitErr != nil {
+			if IsCircuitOpen(waitErr) {
+				return nil, waitErr
+			}
+			return nil, fmt.Errorf("request to %s cancelled while waiting to retry: %w (last error: %s)", url, waitErr, err)
+		}
+
+		if guard != nil {
+			applied, guardErr := guard(ctx)
+			if guardErr != nil {
+				return nil, fmt.Errorf("unable to check whether %s %s was applied, not sending it again: %w (last error: %s)", method, endpoint, guardErr, err)
+			}
+			if applied {
+				c.log(ctx, LogInfo, "LWS API applied the request despite the error, not sending it again", map[string]interface{}{
+					"method":   method,
+					"endpoint": endpoint,
+					"attempt":  attempt,
+					"error":    err.Error(),
+				})
+				return nil, nil
+			}
+		}
+	}
+}
+
+// waitRetry sleeps before a retry. It returns early with the breaker error
+// when the circuit breaker opens in the meantime, since the retry would be
+// refused anyway.
+func (c *LWSClient) waitRetry(ctx context.Context, wait time.Duration) error {
+	if openErr := c.breaker.OpenError(); openErr != nil {
+		return openErr
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.breaker.Opened():
+		if openErr := c.breaker.OpenError(); openErr != nil {
+			return openErr
+		}
+		return nil
+	}
+}
+
+// doRequest performs a single attempt of an API call against one API
+// address. The request is rebuilt from the marshaled body every time so
+// retries never send an empty body.
+func (c *LWSClient) doRequest(ctx context.Context, method, endpoint, baseURL, url string, reqBodyBytes []byte, attempt int) (*LWSAPIResponse, error) {
+	var reqBody io.Reader
+	if reqBodyBytes != nil {
+		reqBody = bytes.NewReader(reqBodyBytes)
+	}
+
 	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
@@ -128,58 +275,142 @@ func (c *LWSClient) makeRequest(ctx context.Context, method, endpoint string, bo
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("X-Auth-Login", c.Login)
 	req.Header.Set("X-Auth-Pass", c.ApiKey)
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		req.Header.Set(RequestIDHeader, requestID)
+	}
 
 	if c.TestMode {
 		req.Header.Set("X-Test-Mode", "true")
 	}
 
-	// Debug logging - log the request details
-	log.Printf("[DEBUG] LWS API Request: %s %s", method, url)
-	log.Printf("[DEBUG] Headers: X-Auth-Login=%s, X-Auth-Pass=[REDACTED], X-Test-Mode=%s",
-		c.Login, req.Header.Get("X-Test-Mode"))
+	requestDetails := map[string]interface{}{
+		"method":   method,
+		"endpoint": endpoint,
+		"attempt":  attempt,
+		"headers":  c.redactor.Headers(req.Header),
+	}
 	if reqBodyBytes != nil {
-		log.Printf("[DEBUG] Request Body: %s", string(reqBodyBytes))
+		requestDetails["body"] = c.redactor.Body(reqBodyBytes)
 	}
+	c.log(ctx, LogTrace, "LWS API request details", requestDetails)
 
-	var resp *http.Response
-	retry := 0
-	delay := c.delay
-	for {
-		if resp != nil {
-			_ = resp.Body.Close()
-		}
-		log.Printf("[DEBUG] Sending request: %d/%d", retry+1, c.retries+1)
-		resp, err = c.client.Do(req)
-		if err == nil && resp.StatusCode < 400 {
-			break
-		}
-		if retry < c.retries {
-			log.Printf("[DEBUG] Request error, retrying in %ds", delay)
-			time.Sleep(time.Duration(delay) * time.Second)
-			retry += 1
-			delay *= c.backoff
-			continue
-		}
-		break
+	release, err := c.limiter.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("request to %s cancelled while waiting for the rate limiter: %w", url, err)
+	}
+
+	state, err := c.breaker.Allow()
+	if err != nil {
+		release()
+		return nil, err
+	}
+	if state == BreakerHalfOpen {
+		c.log(ctx, LogInfo, "Probing LWS API after circuit breaker cooldown", map[string]interface{}{
+			"method":   method,
+			"endpoint": endpoint,
+			"breaker":  state.String(),
+		})
 	}
+
+	apiResp, err := c.send(ctx, req, method, endpoint, baseURL, url, attempt, release)
+	c.recordBreakerOutcome(ctx, method, endpoint, err)
+	return apiResp, err
+}
+
+// recordBreakerOutcome feeds the result of a call to the circuit breaker and
+// logs its state changes.
+func (c *LWSClient) recordBreakerOutcome(ctx context.Context, method, endpoint string, err error) {
+	from, to := c.breaker.Record(err)
+	if from == to {
+		return
+	}
+
+	fields := map[string]interface{}{
+		"method":   method,
+		"endpoint": endpoint,
+		"breaker":  to.String(),
+	}
+	if to == BreakerOpen {
+		fields["error"] = err.Error()
+		c.log(ctx, LogWarn, "LWS API circuit breaker opened, failing API calls fast", fields)
+		return
+	}
+	c.log(ctx, LogInfo, "LWS API circuit breaker closed", fields)
+}
+
+// send performs the HTTP exchange of an attempt and decodes the response.
+// release frees the rate limiter slot once the body has been read.
+func (c *LWSClient) send(ctx context.Context, req *http.Request, method, endpoint, baseURL, url string, attempt int, release func()) (*LWSAPIResponse, error) {
+	sample := requestSample{baseURL: baseURL, method: method, endpoint: endpoint, attempt: attempt, bytesSent: max(req.ContentLength, 0)}
+	defer func() { c.metrics.record(sample) }()
+
+	req, exchange := c.capture.startExchange(req)
+	start := time.Now()
+	resp, err := c.client.Do(req)
 	if err != nil {
+		release()
+		sample.transportErr, sample.latency = true, time.Since(start)
+		traceExchange(ctx, url, 0, err)
+		exchange.record(c, req, attempt, nil, nil, err)
+		c.log(ctx, LogDebug, "LWS API request failed", map[string]interface{}{
+			"method":     method,
+			"endpoint":   endpoint,
+			"base_url":   baseURL,
+			"attempt":    attempt,
+			"latency_ms": time.Since(start).Milliseconds(),
+			"error":      err.Error(),
+		})
+		if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+			// The client timeout expired, not the caller's context: this is
+			// a transport error the retry policy may retry
+			return nil, fmt.Errorf("error making HTTP request to %s: no response within %s: %s", url, c.client.Timeout, err)
+		}
 		return nil, fmt.Errorf("error making HTTP request to %s: %w", url, err)
 	}
-	defer func() { _ = resp.Body.Close() }()
 
 	responseBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	release()
+	latency := time.Since(start)
+	sample.status, sample.latency, sample.bytesReceived = resp.StatusCode, latency, int64(len(responseBody))
+	traceExchange(ctx, url, resp.StatusCode, err)
+	exchange.record(c, req, attempt, resp, responseBody, err)
 	if err != nil {
 		return nil, fmt.Errorf("error reading response body from %s: %w", url, err)
 	}
 
-	// Debug logging - log the response details
-	log.Printf("[DEBUG] LWS API Response: Status %d (%s)", resp.StatusCode, resp.Status)
-	log.Printf("[DEBUG] Response Headers: %v", resp.Header)
-	log.Printf("[DEBUG] Response Body: %q", string(responseBody))
+	c.log(ctx, LogDebug, "Received LWS API response", map[string]interface{}{
+		"method":     method,
+		"endpoint":   endpoint,
+		"base_url":   baseURL,
+		"attempt":    attempt,
+		"status":     resp.StatusCode,
+		"latency_ms": latency.Milliseconds(),
+	})
+	c.log(ctx, LogTrace, "LWS API response details", map[string]interface{}{
+		"method":   method,
+		"endpoint": endpoint,
+		"attempt":  attempt,
+		"headers":  c.redactor.Headers(resp.Header),
+		"body":     c.redactor.Body(responseBody),
+	})
+
+	retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), c.clock.Now())
 
 	// Check if response is empty
 	if len(responseBody) == 0 {
-		return nil, fmt.Errorf("API returned empty response (status %d) for URL: %s. This usually means the endpoint doesn't exist or authentication failed", resp.StatusCode, url)
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Method:     method,
+			Endpoint:   endpoint,
+			URL:        url,
+			RetryAfter: retryAfter,
+			Detail: fmt.Sprintf("API returned empty response (status %d) for URL: %s. This usually means the endpoint doesn't exist or authentication failed",
+				resp.StatusCode, url),
+		}
 	}
 
 	var apiResp LWSAPIResponse
@@ -192,9 +423,19 @@ func (c *LWSClient) makeRequest(ctx context.Context, method, endpoint string, bo
 			strings.Contains(responseStr, "cloudflare") ||
 			strings.Contains(responseStr, "challenge") {
 
+			apiErr := &APIError{
+				StatusCode: resp.StatusCode,
+				Method:     method,
+				Endpoint:   endpoint,
+				URL:        url,
+				RetryAfter: retryAfter,
+				Challenge:  true,
+			}
+			sample.challenge = true
+
 			// Extract the main error info from the response if it contains JSON within
 			if strings.Contains(responseStr, "Invalid response from upstream server") {
-				return nil, fmt.Errorf("LWS API is temporarily protected by Cloudflare challenge system (HTTP %d). "+
+				apiErr.Detail = fmt.Sprintf("LWS API is temporarily protected by Cloudflare challenge system (HTTP %d). "+
 					"This is usually temporary and indicates either:\n"+
 					"1. High traffic or suspicious activity detected\n"+
 					"2. LWS API server is having temporary issues\n"+
@@ -205,28 +446,56 @@ func (c *LWSClient) makeRequest(ctx context.Context, method, endpoint string, bo
 					"- Contact LWS support if the issue persists\n\n"+
 					"Technical details: The API returned an HTML challenge page instead of JSON response",
 					resp.StatusCode)
+				return nil, apiErr
 			}
 
-			return nil, fmt.Errorf("LWS API returned HTML challenge page instead of JSON (HTTP %d). "+
+			apiErr.Detail = fmt.Sprintf("LWS API returned HTML challenge page instead of JSON (HTTP %d). "+
 				"This indicates the API is protected by Cloudflare and requires browser-based verification. "+
 				"This is usually temporary - wait a few minutes and try again. "+
 				"If this persists, check LWS service status or contact support", resp.StatusCode)
+			return nil, apiErr
 		}
 
 		// For other JSON parsing errors, provide the original detailed error
-		return nil, fmt.Errorf("error unmarshaling response from %s (status %d, body: %q): %w", url, resp.StatusCode, string(responseBody), err)
+		return nil, fmt.Errorf("error unmarshaling response from %s (status %d, body: %q): %w", url, resp.StatusCode, c.redactor.Body(responseBody), err)
 	}
 
 	// LWS API uses code 200 for success, other codes for errors
 	if resp.StatusCode >= 400 || apiResp.Code != 200 {
-		return &apiResp, fmt.Errorf("API error for %s (HTTP %d): Code=%d, Info=%s", url, resp.StatusCode, apiResp.Code, apiResp.GetInfoMessage())
+		return &apiResp, &APIError{
+			StatusCode: resp.StatusCode,
+			Code:       apiResp.Code,
+			Info:       apiResp.Info,
+			Method:     method,
+			Endpoint:   endpoint,
+			URL:        url,
+			RetryAfter: retryAfter,
+		}
 	}
 
 	return &apiResp, nil
 }
 
-// GetDNSZone retrieves DNS zone information
+// GetDNSZone retrieves DNS zone information. Listings are served from the
+// zone cache when a recent snapshot exists.
 func (c *LWSClient) GetDNSZone(ctx context.Context, zoneName string) (*DNSZone, error) {
+	records, err := c.zones.get(ctx, zoneName, func(ctx context.Context) ([]DNSRecord, error) {
+		return c.fetchDNSZone(ctx, zoneName)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	zone := &DNSZone{
+		Name:    zoneName,
+		Records: records,
+	}
+
+	return zone, nil
+}
+
+// fetchDNSZone downloads the records of a zone from the API
+func (c *LWSClient) fetchDNSZone(ctx context.Context, zoneName string) ([]DNSRecord, error) {
 	endpoint := fmt.Sprintf("domain/%s/zdns", zoneName)
 	resp, err := c.makeRequest(ctx, "GET", endpoint, nil)
 	if err != nil {
@@ -238,26 +507,23 @@ func (c *LWSClient) GetDNSZone(ctx context.Context, zoneName string) (*DNSZone,
 	}
 
 	// For DNS zone, the data is an array of records
-	dataBytes, err := json.Marshal(resp.Data)
+	records, err := decodeData[[]DNSRecord](resp)
 	if err != nil {
-		return nil, fmt.Errorf("error marshaling zone data: %w", err)
-	}
-
-	var records []DNSRecord
-	if err := json.Unmarshal(dataBytes, &records); err != nil {
 		return nil, fmt.Errorf("error unmarshaling zone records: %w", err)
 	}
 
-	zone := &DNSZone{
-		Name:    zoneName,
-		Records: records,
-	}
-
-	return zone, nil
+	return records, nil
 }
 
 // CreateDNSRecord creates a new DNS record
 func (c *LWSClient) CreateDNSRecord(ctx context.Context, record *DNSRecord) (*DNSRecord, error) {
+	// Changes to a zone are serialised, see LockZone
+	ctx, unlock, err := c.locks.lock(ctx, record.Zone)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	endpoint := fmt.Sprintf("domain/%s/zdns", record.Zone)
 
 	// Prepare request body (only type, name, value, ttl)
@@ -268,78 +534,187 @@ func (c *LWSClient) CreateDNSRecord(ctx context.Context, record *DNSRecord) (*DN
 		TTL:   record.TTL,
 	}
 
-	// Log detailed request information
-	log.Printf("[DEBUG] Creating DNS record for zone '%s':", record.Zone)
-	log.Printf("[DEBUG] - Endpoint: %s/%s", c.BaseURL, endpoint)
-	log.Printf("[DEBUG] - Record Type: %s", reqBody.Type)
-	log.Printf("[DEBUG] - Record Name: '%s'", reqBody.Name)
-	log.Printf("[DEBUG] - Record Value: %s", reqBody.Value)
-	log.Printf("[DEBUG] - Record TTL: %d", reqBody.TTL)
-	log.Printf("[DEBUG] - Test Mode: %t", c.TestMode)
+	ctx = withOperation(ctx)
+	c.log(ctx, LogDebug, "Creating DNS record", map[string]interface{}{
+		"zone":      record.Zone,
+		"endpoint":  endpoint,
+		"type":      reqBody.Type,
+		"name":      reqBody.Name,
+		"value":     c.redactor.Value(reqBody.Value),
+		"ttl":       reqBody.TTL,
+		"test_mode": c.TestMode,
+	})
+
+	// A create that timed out or failed with a server error may still have
+	// landed: look for the record before sending it again.
+	var landed *DNSRecord
+	guard := func(ctx context.Context) (bool, error) {
+		c.zones.invalidate(record.Zone)
+		found, err := c.findDNSRecord(ctx, record)
+		if IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		landed = found
+		return true, nil
+	}
 
-	resp, err := c.makeRequest(ctx, "POST", endpoint, reqBody)
+	resp, err := c.makeGuardedRequest(ctx, "POST", endpoint, reqBody, guard)
+	if err == nil && landed != nil {
+		c.log(ctx, LogInfo, "DNS record was created by a previous attempt", map[string]interface{}{
+			"zone": record.Zone,
+			"id":   landed.ID,
+		})
+		return landed, nil
+	}
 	if err != nil {
-		log.Printf("[ERROR] Failed to make request: %v", err)
+		c.log(ctx, LogError, "Failed to create DNS record", map[string]interface{}{
+			"zone":  record.Zone,
+			"error": err.Error(),
+		})
+		// The zone changed behind our back or the create may have landed
+		// despite the error, callers will want a fresh listing
+		c.zones.invalidate(record.Zone)
 		return nil, err
 	}
 
 	if resp.Code != 200 {
-		log.Printf("[ERROR] API returned error code %d: %s", resp.Code, resp.GetInfoMessage())
+		c.log(ctx, LogError, "LWS API returned an error code", map[string]interface{}{
+			"code": resp.Code,
+			"info": resp.GetInfoMessage(),
+		})
 		return nil, fmt.Errorf("API error: %s", resp.GetInfoMessage())
 	}
 
-	dataBytes, err := json.Marshal(resp.Data)
+	createdRecord, err := decodeData[DNSRecord](resp)
 	if err != nil {
-		return nil, fmt.Errorf("error marshaling record data: %w", err)
-	}
-
-	var createdRecord DNSRecord
-	if err := json.Unmarshal(dataBytes, &createdRecord); err != nil {
 		return nil, fmt.Errorf("error unmarshaling record data: %w", err)
 	}
 
 	// Set the zone since it's not in API response
 	createdRecord.Zone = record.Zone
 
-	log.Printf("[DEBUG] Successfully created record, but no ID returned from API")
+	c.log(ctx, LogDebug, "Created DNS record, but no ID returned from API", map[string]interface{}{
+		"zone": record.Zone,
+	})
+
+	// The cached listing cannot contain the new record yet
+	c.zones.invalidate(record.Zone)
+
+	if c.TestMode {
+		// LWS validates the record without adding it: a single lookup, in
+		// case the API listed it anyway, and no ID otherwise
+		found, err := c.findDNSRecord(ctx, record)
+		if err != nil && !IsNotFound(err) {
+			return nil, fmt.Errorf("record was validated but failed to look it up: %w", err)
+		}
+		if found != nil {
+			createdRecord.ID = found.ID
+		}
+		c.log(ctx, LogDebug, "Validated DNS record in test mode", map[string]interface{}{
+			"zone": record.Zone,
+			"id":   createdRecord.ID,
+		})
+		return &createdRecord, nil
+	}
 
 	// Since the API doesn't return an ID after creation, we need to find it
-	// by searching for the record we just created
-	log.Printf("[DEBUG] Searching for newly created record to get its ID...")
-	foundRecord, err := c.findDNSRecordByName(ctx, record.Zone, record.Name, record.Type)
+	// by searching for the record we just created, which can take a moment
+	// to show up in the listing. Matching on the value tells it apart from
+	// other records with the same name and type; the name lookup covers
+	// values the API rewrote, as long as no sibling makes it ambiguous.
+	var foundRecord *DNSRecord
+	err = c.waitForZone(ctx, record.Zone, "created record not listed", func(records []DNSRecord) bool {
+		foundRecord = findRecord(records, record)
+		return foundRecord != nil
+	})
+	if IsNotConsistent(err) {
+		foundRecord, err = c.findDNSRecordByName(ctx, record.Zone, record.Name, record.Type)
+	}
 	if err != nil {
-		log.Printf("[ERROR] Failed to find newly created record: %v", err)
+		c.log(ctx, LogError, "Failed to find newly created DNS record", map[string]interface{}{
+			"zone":  record.Zone,
+			"error": err.Error(),
+		})
 		return nil, fmt.Errorf("record was created but failed to retrieve its ID: %w", err)
 	}
 
 	// Update the created record with the found ID
 	createdRecord.ID = foundRecord.ID
 
-	log.Printf("[DEBUG] Successfully created record with ID: %d", createdRecord.ID)
+	c.log(ctx, LogDebug, "Successfully created DNS record", map[string]interface{}{
+		"zone": record.Zone,
+		"id":   createdRecord.ID,
+	})
 
 	return &createdRecord, nil
 }
 
-// findDNSRecordByName finds a DNS record by name and type in the zone
+// findDNSRecordByName finds the only record of the zone with the given
+// name and type. It fails when several records match, since picking
+// one of them could return a sibling of the record looked for.
 func (c *LWSClient) findDNSRecordByName(ctx context.Context, zoneName, recordName, recordType string) (*DNSRecord, error) {
-	// Get the entire zone first
 	zone, err := c.GetDNSZone(ctx, zoneName)
 	if err != nil {
 		return nil, fmt.Errorf("error getting DNS zone '%s': %w", zoneName, err)
 	}
 
-	// Find the record with the matching name and type
+	var found *DNSRecord
 	for _, record := range zone.Records {
-		if record.Name == recordName && record.Type == recordType {
-			// Set the zone since it's not in API response
-			record.Zone = zoneName
-			// Return a copy to avoid modifying the original slice
-			foundRecord := record
-			return &foundRecord, nil
+		if !strings.EqualFold(normalizeName(record.Name), normalizeName(recordName)) || !strings.EqualFold(record.Type, recordType) {
+			continue
+		}
+		if found != nil {
+			return nil, newAmbiguousError("several DNS records named '%s' of type '%s' found in zone '%s'", recordName, recordType, zoneName)
+		}
+		record.Zone = zoneName
+		found = &record
+	}
+
+	if found == nil {
+		return nil, newNotFoundError("DNS record with name '%s' and type '%s' not found in zone '%s'", recordName, recordType, zoneName)
+	}
+	return found, nil
+}
+
+// findDNSRecord finds the record of the zone with the name, type and value
+// of want
+func (c *LWSClient) findDNSRecord(ctx context.Context, want *DNSRecord) (*DNSRecord, error) {
+	zone, err := c.GetDNSZone(ctx, want.Zone)
+	if err != nil {
+		return nil, fmt.Errorf("error getting DNS zone '%s': %w", want.Zone, err)
+	}
+
+	if record := findRecord(zone.Records, want); record != nil {
+		return record, nil
+	}
+
+	return nil, newNotFoundError("DNS record '%s' of type '%s' with the requested value not found in zone '%s'", want.Name, want.Type, want.Zone)
+}
+
+// findRecord returns a copy of the record of records with the name, type
+// and value of want, with its zone set, or nil.
+func findRecord(records []DNSRecord, want *DNSRecord) *DNSRecord {
+	for _, record := range records {
+		if sameRecord(record, *want) {
+			record.Zone = want.Zone
+			return &record
 		}
 	}
+	return nil
+}
 
-	return nil, fmt.Errorf("DNS record with name '%s' and type '%s' not found in zone '%s'", recordName, recordType, zoneName)
+// sameRecord reports whether two records have the same name, type and
+// value, whatever their zone. See IdentityOf for the normalisation applied.
+func sameRecord(a, b DNSRecord) bool {
+	a.Zone, b.Zone = "", ""
+	return IdentityOf(a) == IdentityOf(b)
+}
+
+func normalizeName(name string) string {
+	return strings.TrimSuffix(strings.TrimSpace(name), ".")
 }
 
 // GetDNSRecord retrieves a DNS record by ID from a specific domain
@@ -368,7 +743,7 @@ func (c *LWSClient) GetDNSRecord(ctx context.Context, domain, recordID string) (
 		}
 	}
 
-	return nil, fmt.Errorf("record with ID %s not found in domain %s", recordID, domain)
+	return nil, newNotFoundError("record with ID %s not found in domain %s", recordID, domain)
 }
 
 // UpdateDNSRecord updates an existing DNS record
@@ -378,6 +753,13 @@ func (c *LWSClient) UpdateDNSRecord(ctx context.Context, record *DNSRecord) (*DN
 		return nil, fmt.Errorf("record ID is required for update operation")
 	}
 
+	// Changes to a zone are serialised, see LockZone
+	ctx, unlock, err := c.locks.lock(ctx, record.Zone)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	endpoint := fmt.Sprintf("domain/%s/zdns", record.Zone)
 
 	// Prepare request body (id, type, name, value, ttl)
@@ -389,14 +771,17 @@ func (c *LWSClient) UpdateDNSRecord(ctx context.Context, record *DNSRecord) (*DN
 		TTL:   record.TTL,
 	}
 
-	log.Printf("[DEBUG] Updating DNS record for zone '%s':", record.Zone)
-	log.Printf("[DEBUG] - Endpoint: %s/%s", c.BaseURL, endpoint)
-	log.Printf("[DEBUG] - Record ID: %d", reqBody.ID)
-	log.Printf("[DEBUG] - Record Type: %s", reqBody.Type)
-	log.Printf("[DEBUG] - Record Name: '%s'", reqBody.Name)
-	log.Printf("[DEBUG] - Record Value: %s", reqBody.Value)
-	log.Printf("[DEBUG] - Record TTL: %d", reqBody.TTL)
-	log.Printf("[DEBUG] - Test Mode: %t", c.TestMode)
+	ctx = withOperation(ctx)
+	c.log(ctx, LogDebug, "Updating DNS record", map[string]interface{}{
+		"zone":      record.Zone,
+		"endpoint":  endpoint,
+		"id":        reqBody.ID,
+		"type":      reqBody.Type,
+		"name":      reqBody.Name,
+		"value":     c.redactor.Value(reqBody.Value),
+		"ttl":       reqBody.TTL,
+		"test_mode": c.TestMode,
+	})
 
 	resp, err := c.makeRequest(ctx, "PUT", endpoint, reqBody)
 	if err != nil {
@@ -407,20 +792,54 @@ func (c *LWSClient) UpdateDNSRecord(ctx context.Context, record *DNSRecord) (*DN
 		return nil, fmt.Errorf("API error: %s", resp.GetInfoMessage())
 	}
 
-	dataBytes, err := json.Marshal(resp.Data)
+	updatedRecord, err := decodeData[DNSRecord](resp)
 	if err != nil {
-		return nil, fmt.Errorf("error marshaling record data: %w", err)
-	}
-
-	var updatedRecord DNSRecord
-	if err := json.Unmarshal(dataBytes, &updatedRecord); err != nil {
 		return nil, fmt.Errorf("error unmarshaling record data: %w", err)
 	}
 
 	// Set the zone since it's not in API response
 	updatedRecord.Zone = record.Zone
 
-	log.Printf("[DEBUG] Successfully updated record with ID: %d", updatedRecord.ID)
+	switch {
+	case c.TestMode:
+		// LWS validates the change without applying it, there is nothing
+		// to wait for
+		c.zones.invalidate(record.Zone)
+	case c.consistency.Enabled():
+		// The fresh listing the waiter fetched replaces the cached one. The
+		// record is matched on its identity rather than its ID, since LWS
+		// may renumber it.
+		err = c.waitForZone(ctx, record.Zone, "updated record not listed", func(records []DNSRecord) bool {
+			for _, listed := range records {
+				if sameRecord(listed, *record) && (record.TTL == 0 || listed.TTL == record.TTL) {
+					updatedRecord.ID = listed.ID
+					return true
+				}
+			}
+			return false
+		})
+		if IsNotConsistent(err) {
+			// LWS accepted the change, it may have rewritten the value or
+			// the TTL; the next read shows what it holds
+			c.log(ctx, LogWarn, "Updated DNS record is not listed as sent, not waiting any longer", map[string]interface{}{
+				"zone":  record.Zone,
+				"id":    record.ID,
+				"error": err.Error(),
+			})
+			c.zones.invalidate(record.Zone)
+		} else if err != nil {
+			return nil, err
+		}
+	case updatedRecord.ID > 0:
+		c.zones.upsert(record.Zone, record.ID, updatedRecord)
+	default:
+		c.zones.invalidate(record.Zone)
+	}
+
+	c.log(ctx, LogDebug, "Successfully updated DNS record", map[string]interface{}{
+		"zone": record.Zone,
+		"id":   updatedRecord.ID,
+	})
 
 	return &updatedRecord, nil
 }
@@ -432,6 +851,13 @@ func (c *LWSClient) DeleteDNSRecord(ctx context.Context, recordID int, zoneName
 		return fmt.Errorf("record ID is required for delete operation")
 	}
 
+	// Changes to a zone are serialised, see LockZone
+	ctx, unlock, err := c.locks.lock(ctx, zoneName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	endpoint := fmt.Sprintf("domain/%s/zdns", zoneName)
 
 	// Prepare request body with ID
@@ -439,32 +865,53 @@ func (c *LWSClient) DeleteDNSRecord(ctx context.Context, recordID int, zoneName
 		"id": recordID,
 	}
 
-	// Log detailed request information
-	log.Printf("[DEBUG] Deleting DNS record:")
-	log.Printf("[DEBUG] - Endpoint: %s/%s", c.BaseURL, endpoint)
-	log.Printf("[DEBUG] - Record ID: %d", recordID)
-	log.Printf("[DEBUG] - Zone: %s", zoneName)
-	log.Printf("[DEBUG] - Test Mode: %t", c.TestMode)
+	ctx = withOperation(ctx)
+	c.log(ctx, LogDebug, "Deleting DNS record", map[string]interface{}{
+		"zone":      zoneName,
+		"endpoint":  endpoint,
+		"id":        recordID,
+		"test_mode": c.TestMode,
+	})
 
 	resp, err := c.makeRequest(ctx, "DELETE", endpoint, reqBody)
 	if err != nil {
-		log.Printf("[ERROR] Failed to make delete request: %v", err)
+		c.log(ctx, LogError, "Failed to delete DNS record", map[string]interface{}{
+			"zone":  zoneName,
+			"error": err.Error(),
+		})
 		return err
 	}
 
 	// Accept both 200 and 201 as success codes for deletion
 	if resp.Code != 200 && resp.Code != 201 {
-		log.Printf("[ERROR] API returned error code %d: %s", resp.Code, resp.GetInfoMessage())
+		c.log(ctx, LogError, "LWS API returned an error code", map[string]interface{}{
+			"code": resp.Code,
+			"info": resp.GetInfoMessage(),
+		})
 		return fmt.Errorf("API error: %s", resp.GetInfoMessage())
 	}
 
-	log.Printf("[DEBUG] Successfully deleted record with ID: %d", recordID)
+	if err := c.waitDeleted(ctx, zoneName, recordID); err != nil {
+		return err
+	}
+
+	c.log(ctx, LogDebug, "Successfully deleted DNS record", map[string]interface{}{
+		"zone": zoneName,
+		"id":   recordID,
+	})
 
 	return nil
 }
 
 // DeleteDNSRecordByID deletes a DNS record by ID (legacy method for backward compatibility)
 func (c *LWSClient) DeleteDNSRecordByID(ctx context.Context, recordID string, zoneName string) error {
+	// Changes to a zone are serialised, see LockZone
+	ctx, unlock, err := c.locks.lock(ctx, zoneName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	endpoint := fmt.Sprintf("domain/%s/zdns", zoneName)
 
 	// Convert string ID to int
@@ -478,26 +925,76 @@ func (c *LWSClient) DeleteDNSRecordByID(ctx context.Context, recordID string, zo
 		"id": recordIDInt,
 	}
 
-	// Log detailed request information
-	log.Printf("[DEBUG] Deleting DNS record by ID:")
-	log.Printf("[DEBUG] - Endpoint: %s/%s", c.BaseURL, endpoint)
-	log.Printf("[DEBUG] - Record ID: %d", recordIDInt)
-	log.Printf("[DEBUG] - Zone: %s", zoneName)
-	log.Printf("[DEBUG] - Test Mode: %t", c.TestMode)
+	ctx = withOperation(ctx)
+	c.log(ctx, LogDebug, "Deleting DNS record", map[string]interface{}{
+		"zone":      zoneName,
+		"endpoint":  endpoint,
+		"id":        recordIDInt,
+		"test_mode": c.TestMode,
+	})
 
 	resp, err := c.makeRequest(ctx, "DELETE", endpoint, reqBody)
 	if err != nil {
-		log.Printf("[ERROR] Failed to make delete request: %v", err)
+		c.log(ctx, LogError, "Failed to delete DNS record", map[string]interface{}{
+			"zone":  zoneName,
+			"error": err.Error(),
+		})
 		return err
 	}
 
 	// Accept both 200 and 201 as success codes for deletion
 	if resp.Code != 200 && resp.Code != 201 {
-		log.Printf("[ERROR] API returned error code %d: %s", resp.Code, resp.GetInfoMessage())
+		c.log(ctx, LogError, "LWS API returned an error code", map[string]interface{}{
+			"code": resp.Code,
+			"info": resp.GetInfoMessage(),
+		})
 		return fmt.Errorf("API error: %s", resp.GetInfoMessage())
 	}
 
-	log.Printf("[DEBUG] Successfully deleted record with ID: %d", recordIDInt)
+	if err := c.waitDeleted(ctx, zoneName, recordIDInt); err != nil {
+		return err
+	}
+
+	c.log(ctx, LogDebug, "Successfully deleted DNS record", map[string]interface{}{
+		"zone": zoneName,
+		"id":   recordIDInt,
+	})
 
 	return nil
 }
+
+// waitDeleted drops a deleted record from the cached listing, or waits for
+// the API listing to stop showing it when the consistency waiter is enabled.
+// The delete succeeded either way, so a listing that never catches up is
+// only logged. In test mode LWS may keep the record, so there is nothing
+// to wait for.
+func (c *LWSClient) waitDeleted(ctx context.Context, zoneName string, recordID int) error {
+	if c.TestMode {
+		c.zones.invalidate(zoneName)
+		return nil
+	}
+	if !c.consistency.Enabled() {
+		c.zones.remove(zoneName, recordID)
+		return nil
+	}
+
+	err := c.waitForZone(ctx, zoneName, "deleted record still listed", func(records []DNSRecord) bool {
+		for _, listed := range records {
+			if listed.ID == recordID {
+				return false
+			}
+		}
+		return true
+	})
+	if IsNotConsistent(err) {
+		// LWS accepted the delete, the listing is only slow to show it
+		c.log(ctx, LogWarn, "Deleted DNS record is still listed, not waiting any longer", map[string]interface{}{
+			"zone":  zoneName,
+			"id":    recordID,
+			"error": err.Error(),
+		})
+		c.zones.invalidate(zoneName)
+		return nil
+	}
+	return err
+}
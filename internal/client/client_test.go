@@ -247,7 +247,7 @@ func TestLWSClient_GetDNSRecord(t *testing.T) {
 
 func TestLWSClient_UpdateDNSRecord(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Only expect PUT request now, no GET needed
+		// Only expect PUT request now, no GET needed without the consistency waiter
 		if r.Method != http.MethodPut {
 			t.Errorf("Expected PUT request for update, got %s", r.Method)
 		}
@@ -269,7 +269,7 @@ func TestLWSClient_UpdateDNSRecord(t *testing.T) {
 	}))
 	defer server.Close()
 
-	client := NewLWSClient("testlogin", "testkey", server.URL, true, 30, 0, 0, 0)
+	client := newTestClient(t, server.URL)
 
 	record := &DNSRecord{
 		ID:    12345, // ID must be provided for update
@@ -302,7 +302,7 @@ func TestLWSClient_DeleteDNSRecord(t *testing.T) {
 	}))
 	defer server.Close()
 
-	client := NewLWSClient("testlogin", "testkey", server.URL, true, 30, 0, 0, 0)
+	client := newTestClient(t, server.URL)
 
 	err := client.DeleteDNSRecord(context.Background(), 12345, "example.com")
 	if err != nil {
@@ -338,7 +338,7 @@ func TestLWSClient_DeleteDNSRecordByID(t *testing.T) {
 	}))
 	defer server.Close()
 
-	client := NewLWSClient("testlogin", "testkey", server.URL, true, 30, 0, 0, 0)
+	client := newTestClient(t, server.URL)
 
 	err := client.DeleteDNSRecordByID(context.Background(), "12345", "example.com")
 	if err != nil {
@@ -355,6 +355,7 @@ func TestLWSClient_findDNSRecordByName(t *testing.T) {
 		recordName     string
 		recordType     string
 		expectError    bool
+		ambiguous      bool
 		expectedRecord *DNSRecord
 	}{
 		{
@@ -413,6 +414,23 @@ func TestLWSClient_findDNSRecordByName(t *testing.T) {
 			expectError:    true,
 			expectedRecord: nil,
 		},
+		{
+			name: "several records with the name and type",
+			responseBody: `{
+				"code": 200,
+				"info": "Fetched DNS Zone",
+				"data": [
+					{"id": 12345, "name": "@", "type": "TXT", "value": "v=spf1 -all", "ttl": 3600},
+					{"id": 12346, "name": "@", "type": "TXT", "value": "google-site-verification=abc", "ttl": 3600}
+				]
+			}`,
+			responseStatus: http.StatusOK,
+			recordName:     "@",
+			recordType:     "TXT",
+			expectError:    true,
+			ambiguous:      true,
+			expectedRecord: nil,
+		},
 		{
 			name: "record found but wrong type",
 			responseBody: `{
@@ -461,6 +479,10 @@ func TestLWSClient_findDNSRecordByName(t *testing.T) {
 				if err == nil {
 					t.Errorf("Expected error, but got none")
 				}
+				// The records of an ambiguous lookup exist
+				if IsAmbiguous(err) != tt.ambiguous || IsNotFound(err) == tt.ambiguous {
+					t.Errorf("Expected ambiguous=%v and not found=%v, got %v", tt.ambiguous, !tt.ambiguous, err)
+				}
 			} else {
 				if err != nil {
 					t.Errorf("Unexpected error: %v", err)
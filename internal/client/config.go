@@ -0,0 +1,563 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Defaults applied by New when an option does not override them.
+const (
+	DefaultBaseURL   = "https://api.lws.net/v1"
+	DefaultTimeout   = 30 * time.Second
+	DefaultUserAgent = "terraform-provider-lws"
+)
+
+// Clock tells the client the current time. It drives zone cache expiry, the
+// refill of the rate limiter and the parsing of Retry-After dates, so tests
+// can control them.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Config holds every setting of an LWSClient. Use New with options to build
+// one, so defaults and validation are applied consistently.
+type Config struct {
+	Login    string
+	APIKey   string
+	BaseURL  string
+	TestMode bool
+
+	// BaseURLs lists API addresses tried in order, failing over to the next
+	// one on transport errors, 5xx answers and challenge pages. When set,
+	// it replaces BaseURL, which becomes its first entry.
+	BaseURLs []string
+	// FailoverCooldown is how long a failed address is passed over.
+	FailoverCooldown time.Duration
+
+	// HTTPClient sends the requests. When nil, a client is built from
+	// Transport and Timeout.
+	HTTPClient *http.Client
+	// Transport is used when HTTPClient is nil. When it is nil as well, a
+	// transport is built from TransportOptions.
+	Transport        http.RoundTripper
+	TransportOptions TransportOptions
+	// Timeout bounds a single HTTP attempt when HTTPClient is nil.
+	Timeout time.Duration
+
+	RetryPolicy RetryPolicy
+
+	// RateLimiter is shared by every request of the client. When nil, one is
+	// built from RequestsPerSecond, Burst and MaxConcurrentRequests.
+	RateLimiter           *RateLimiter
+	RequestsPerSecond     float64
+	Burst                 int
+	MaxConcurrentRequests int
+
+	// CircuitBreaker is shared by every request of the client. When nil, one
+	// is built from BreakerThreshold and BreakerCooldown.
+	CircuitBreaker   *CircuitBreaker
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	// ZoneCacheTTL is how long zone listings are reused. Zero disables the
+	// cache, concurrent reads of the same zone are still merged.
+	ZoneCacheTTL time.Duration
+
+	// ConsistencyInterval, ConsistencyMaxWait and ConsistencyMultiplier
+	// configure how the zone listing is polled after a change until it
+	// reflects it. A zero ConsistencyMaxWait disables waiting.
+	ConsistencyInterval   time.Duration
+	ConsistencyMaxWait    time.Duration
+	ConsistencyMultiplier float64
+
+	// Logger receives the client output, TFLogger when nil.
+	Logger Logger
+	// RedactPatterns are regular expressions whose matches are masked in
+	// the logs, on top of credentials and record values.
+	RedactPatterns []string
+
+	// Metrics records the API calls of the client when set. It may be
+	// shared between clients.
+	Metrics *Metrics
+	// TracerProvider creates a span for every attempt of an API call when
+	// set. When nil, the client does no tracing work at all.
+	TracerProvider trace.TracerProvider
+	// HTTPCapture records every HTTP exchange of the client when set. It
+	// may be shared between clients.
+	HTTPCapture *HARCapture
+
+	UserAgent string
+	Clock     Clock
+}
+
+// Option changes a Config before the client is built.
+type Option func(*Config)
+
+// DefaultConfig returns the configuration New starts from.
+func DefaultConfig() Config {
+	return Config{
+		BaseURL:               DefaultBaseURL,
+		FailoverCooldown:      DefaultFailoverCooldown,
+		Timeout:               DefaultTimeout,
+		RetryPolicy:           NewDefaultRetryPolicy(3, 15*time.Second, 2),
+		RequestsPerSecond:     DefaultRequestsPerSecond,
+		MaxConcurrentRequests: DefaultMaxConcurrentRequests,
+		BreakerThreshold:      DefaultBreakerThreshold,
+		BreakerCooldown:       DefaultBreakerCooldown,
+		ZoneCacheTTL:          DefaultZoneCacheTTL,
+		ConsistencyInterval:   DefaultConsistencyInterval,
+		ConsistencyMaxWait:    DefaultConsistencyMaxWait,
+		ConsistencyMultiplier: DefaultConsistencyMultiplier,
+		Logger:                TFLogger{},
+		UserAgent:             DefaultUserAgent,
+		Clock:                 systemClock{},
+	}
+}
+
+// WithCredentials sets the login and API key sent with every request.
+func WithCredentials(login, apiKey string) Option {
+	return func(c *Config) {
+		c.Login = login
+		c.APIKey = apiKey
+	}
+}
+
+// WithBaseURL sets the API address.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Config) {
+		c.BaseURL = baseURL
+	}
+}
+
+// WithBaseURLs sets the API addresses, the primary one first. Calls fail
+// over to the next address when one can't be reached, answers with a 5xx or
+// with a challenge page, and come back to it after the failover cooldown.
+func WithBaseURLs(baseURLs ...string) Option {
+	return func(c *Config) {
+		c.BaseURLs = baseURLs
+	}
+}
+
+// WithFailoverCooldown sets how long an API address that failed is passed
+// over before it is tried first again.
+func WithFailoverCooldown(cooldown time.Duration) Option {
+	return func(c *Config) {
+		c.FailoverCooldown = cooldown
+	}
+}
+
+// WithTestMode makes the client send the X-Test-Mode header.
+func WithTestMode(testMode bool) Option {
+	return func(c *Config) {
+		c.TestMode = testMode
+	}
+}
+
+// WithHTTPClient sends requests through httpClient. Transport and timeout
+// options are ignored when it is set.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Config) {
+		c.HTTPClient = httpClient
+	}
+}
+
+// WithTransport sets the round tripper of the HTTP client built by New.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Config) {
+		c.Transport = transport
+	}
+}
+
+// WithTransportOptions configures the proxy, certificate authorities, client
+// certificate and pinning of the transport built by New.
+func WithTransportOptions(opts TransportOptions) Option {
+	return func(c *Config) {
+		c.TransportOptions = opts
+	}
+}
+
+// WithTimeout bounds a single HTTP attempt.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.Timeout = timeout
+	}
+}
+
+// WithRetryPolicy decides how failed calls are retried.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Config) {
+		c.RetryPolicy = policy
+	}
+}
+
+// WithRateLimit allows requestsPerSecond on average with bursts of up to
+// burst requests, and at most maxConcurrent requests in flight. A burst of
+// zero or less defaults to the rate rounded up.
+func WithRateLimit(requestsPerSecond float64, burst, maxConcurrent int) Option {
+	return func(c *Config) {
+		c.RateLimiter = nil
+		c.RequestsPerSecond = requestsPerSecond
+		c.Burst = burst
+		c.MaxConcurrentRequests = maxConcurrent
+	}
+}
+
+// WithRateLimiter shares an existing limiter, e.g. between several clients
+// using the same account.
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(c *Config) {
+		c.RateLimiter = limiter
+	}
+}
+
+// WithCircuitBreaker opens the breaker after threshold consecutive challenge
+// or 5xx answers and probes the API again after cooldown. A threshold of
+// zero or less disables it.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(c *Config) {
+		c.CircuitBreaker = nil
+		c.BreakerThreshold = threshold
+		c.BreakerCooldown = cooldown
+	}
+}
+
+// WithZoneCacheTTL sets how long zone listings are reused.
+func WithZoneCacheTTL(ttl time.Duration) Option {
+	return func(c *Config) {
+		c.ZoneCacheTTL = ttl
+	}
+}
+
+// WithConsistencyWait polls the zone listing after create, update and
+// delete until it reflects the change: first immediately, then after
+// interval, the interval growing by multiplier, for at most maxWait. A
+// maxWait of zero disables waiting.
+func WithConsistencyWait(interval, maxWait time.Duration, multiplier float64) Option {
+	return func(c *Config) {
+		c.ConsistencyInterval = interval
+		c.ConsistencyMaxWait = maxWait
+		c.ConsistencyMultiplier = multiplier
+	}
+}
+
+// WithLogger sends the client output to logger instead of tflog.
+func WithLogger(logger Logger) Option {
+	return func(c *Config) {
+		c.Logger = logger
+	}
+}
+
+// WithRedactPatterns masks matches of the given regular expressions in the
+// logs.
+func WithRedactPatterns(patterns ...string) Option {
+	return func(c *Config) {
+		c.RedactPatterns = append(c.RedactPatterns, patterns...)
+	}
+}
+
+// WithMetrics records the API calls of the client in metrics.
+func WithMetrics(metrics *Metrics) Option {
+	return func(c *Config) {
+		c.Metrics = metrics
+	}
+}
+
+// WithTracerProvider traces the API calls of the client with provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *Config) {
+		c.TracerProvider = provider
+	}
+}
+
+// WithHTTPCapture records the HTTP exchanges of the client in capture.
+func WithHTTPCapture(capture *HARCapture) Option {
+	return func(c *Config) {
+		c.HTTPCapture = capture
+	}
+}
+
+// WithUserAgent sets the User-Agent header of every request.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Config) {
+		c.UserAgent = userAgent
+	}
+}
+
+// WithClock replaces the wall clock, for tests.
+func WithClock(clock Clock) Option {
+	return func(c *Config) {
+		c.Clock = clock
+	}
+}
+
+// ConfigError describes one invalid setting. Field is the name of the
+// matching provider configuration attribute, e.g. "base_url".
+type ConfigError struct {
+	Field   string
+	Summary string
+	Detail  string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Detail)
+}
+
+// ConfigErrors lists every problem found by Config.Validate.
+type ConfigErrors []*ConfigError
+
+func (e ConfigErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return "invalid LWS client configuration: " + strings.Join(messages, "; ")
+}
+
+// Validate checks the configuration and returns ConfigErrors listing every
+// invalid setting, or nil.
+func (c Config) Validate() error {
+	var errs ConfigErrors
+	add := func(field, summary, detail string) {
+		errs = append(errs, &ConfigError{Field: field, Summary: summary, Detail: detail})
+	}
+
+	if c.Login == "" {
+		add("login", "Missing LWS API Login", "The provider requires a LWS login ID.")
+	}
+
+	if c.APIKey == "" {
+		add("api_key", "Missing LWS API Key", "The provider requires a LWS API key.")
+	}
+
+	if len(c.BaseURLs) == 0 {
+		if !validBaseURL(c.BaseURL) {
+			add("base_url", "Invalid base_url value", fmt.Sprintf("The base_url value %q must be an absolute http or https URL.", c.BaseURL))
+		}
+	} else {
+		seen := make(map[string]bool)
+		for _, baseURL := range c.BaseURLs {
+			switch {
+			case !validBaseURL(baseURL):
+				add("base_urls", "Invalid base_urls value", fmt.Sprintf("The base_urls entry %q must be an absolute http or https URL.", baseURL))
+			case seen[strings.TrimSuffix(baseURL, "/")]:
+				add("base_urls", "Invalid base_urls value", fmt.Sprintf("The base_urls entry %q is listed twice.", baseURL))
+			}
+			seen[strings.TrimSuffix(baseURL, "/")] = true
+		}
+	}
+
+	if c.FailoverCooldown < 0 {
+		add("failover_cooldown", "Invalid failover_cooldown value", "The failover_cooldown value cannot be negative.")
+	}
+
+	if c.HTTPClient == nil && c.Transport == nil && !c.TransportOptions.IsZero() {
+		if _, err := NewTransport(c.transportOptions()); err != nil {
+			var transportErrs ConfigErrors
+			if errors.As(err, &transportErrs) {
+				errs = append(errs, transportErrs...)
+			} else {
+				add("proxy_url", "Invalid transport configuration", err.Error())
+			}
+		}
+	}
+
+	if c.HTTPClient == nil && c.Timeout <= 0 {
+		add("timeout", "Invalid timeout value", "The timeout value must be a positive integer representing seconds.")
+	}
+
+	switch p := c.RetryPolicy.(type) {
+	case nil:
+		add("retries", "Missing retry policy", "A retry policy is required.")
+	case *DefaultRetryPolicy:
+		if p.Retries < 0 {
+			add("retries", "Invalid retries value", "The retries value cannot be negative.")
+		}
+		if p.BaseDelay < 0 {
+			add("delay", "Invalid delay value", "The delay value cannot be negative.")
+		}
+		if p.Multiplier < 1 {
+			add("backoff", "Invalid backoff value", "The backoff value must be at least 1.")
+		}
+	}
+
+	if c.RateLimiter == nil {
+		if c.RequestsPerSecond < 0 {
+			add("requests_per_second", "Invalid requests_per_second value",
+				"The requests_per_second value cannot be negative. Use 0 to disable rate limiting.")
+		}
+		if c.MaxConcurrentRequests < 1 {
+			add("max_concurrent_requests", "Invalid max_concurrent_requests value",
+				"The max_concurrent_requests value must be at least 1.")
+		}
+	}
+
+	if c.CircuitBreaker == nil {
+		if c.BreakerThreshold < 0 {
+			add("circuit_breaker_threshold", "Invalid circuit_breaker_threshold value",
+				"The circuit_breaker_threshold value cannot be negative. Use 0 to disable the circuit breaker.")
+		}
+		if c.BreakerCooldown < 0 {
+			add("circuit_breaker_cooldown", "Invalid circuit_breaker_cooldown value",
+				"The circuit_breaker_cooldown value cannot be negative.")
+		}
+	}
+
+	if c.ZoneCacheTTL < 0 {
+		add("zone_cache_ttl", "Invalid zone_cache_ttl value",
+			"The zone_cache_ttl value cannot be negative. Use 0 to disable the zone cache.")
+	}
+
+	if c.ConsistencyMaxWait < 0 {
+		add("consistency_max_wait", "Invalid consistency_max_wait value",
+			"The consistency_max_wait value cannot be negative. Use 0 to disable the consistency waiter.")
+	}
+	if c.ConsistencyMaxWait > 0 && c.ConsistencyInterval <= 0 {
+		add("consistency_poll_interval", "Invalid consistency_poll_interval value",
+			"The consistency_poll_interval value must be positive when consistency_max_wait is set.")
+	}
+	if c.ConsistencyMaxWait > 0 && c.ConsistencyMultiplier < 1 {
+		add("consistency_backoff", "Invalid consistency_backoff value", "The consistency_backoff value must be at least 1.")
+	}
+
+	if _, err := NewRedactor(c.RedactPatterns...); err != nil {
+		add("log_redact_patterns", "Invalid log_redact_patterns value", err.Error())
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validBaseURL reports whether baseURL is an absolute http or https URL.
+func validBaseURL(baseURL string) bool {
+	u, err := url.Parse(baseURL)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// baseURLs returns the API addresses, the primary one first.
+func (c Config) baseURLs() []string {
+	if len(c.BaseURLs) == 0 {
+		return []string{c.BaseURL}
+	}
+	return c.BaseURLs
+}
+
+// transportOptions returns TransportOptions with the pinned host defaulting
+// to the primary API host.
+func (c Config) transportOptions() TransportOptions {
+	opts := c.TransportOptions
+	if opts.PinnedHost == "" {
+		if u, err := url.Parse(c.baseURLs()[0]); err == nil {
+			opts.PinnedHost = u.Hostname()
+		}
+	}
+	return opts
+}
+
+// New creates an LWS API client from DefaultConfig modified by opts. It
+// returns ConfigErrors when the resulting configuration is invalid.
+func New(opts ...Option) (*LWSClient, error) {
+	cfg := DefaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return newClient(cfg), nil
+}
+
+// newClient builds a client from cfg without validating it. Missing
+// collaborators are replaced by their defaults.
+func newClient(cfg Config) *LWSClient {
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		transport := cfg.Transport
+		if transport == nil && !cfg.TransportOptions.IsZero() {
+			// Validate reports invalid options, fall back to the default transport
+			if custom, err := NewTransport(cfg.transportOptions()); err == nil {
+				transport = custom
+			}
+		}
+		httpClient = &http.Client{
+			Transport: transport,
+			Timeout:   cfg.Timeout,
+		}
+	}
+
+	clock := cfg.Clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+
+	limiter := cfg.RateLimiter
+	if limiter == nil {
+		burst := cfg.Burst
+		if burst <= 0 {
+			burst = int(math.Ceil(cfg.RequestsPerSecond))
+		}
+		limiter = newRateLimiter(cfg.RequestsPerSecond, burst, cfg.MaxConcurrentRequests, clock)
+	}
+
+	retryPolicy := cfg.RetryPolicy
+	if retryPolicy == nil {
+		retryPolicy = NewDefaultRetryPolicy(0, 0, 1)
+	}
+
+	logger := cfg.Logger
+	if logger == nil {
+		logger = TFLogger{}
+	}
+
+	redactor, err := NewRedactor(cfg.RedactPatterns...)
+	if err != nil {
+		// Validate reports invalid patterns, fall back to the built-in rules
+		redactor = &Redactor{}
+	}
+
+	breaker := cfg.CircuitBreaker
+	if breaker == nil {
+		breaker = NewCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown)
+		breaker.clock = clock
+	}
+
+	baseURLs := cfg.baseURLs()
+
+	return &LWSClient{
+		Login:       cfg.Login,
+		ApiKey:      cfg.APIKey,
+		BaseURL:     baseURLs[0],
+		TestMode:    cfg.TestMode,
+		endpoints:   newEndpointPool(baseURLs, cfg.FailoverCooldown, clock),
+		client:      httpClient,
+		retryPolicy: retryPolicy,
+		limiter:     limiter,
+		breaker:     breaker,
+		zones:       newZoneCache(cfg.ZoneCacheTTL, clock),
+		locks:       newZoneLocks(),
+		consistency: NewConsistencyWaiter(cfg.ConsistencyInterval, cfg.ConsistencyMaxWait, cfg.ConsistencyMultiplier),
+		logger:      logger,
+		redactor:    redactor,
+		userAgent:   cfg.UserAgent,
+		clock:       clock,
+		metrics:     cfg.Metrics,
+		tracer:      newTracer(cfg.TracerProvider),
+		capture:     cfg.HTTPCapture,
+	}
+}
@@ -0,0 +1,200 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock that only moves when told to.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// newTestClient builds a client for an httptest server without retries.
+func newTestClient(t *testing.T, baseURL string, opts ...Option) *LWSClient {
+	t.Helper()
+
+	opts = append([]Option{
+		WithCredentials("testlogin", "testkey"),
+		WithBaseURL(baseURL),
+		WithTestMode(true),
+		WithRetryPolicy(NewDefaultRetryPolicy(0, 0, 1)),
+		// Fixtures answer with fixed listings that never reflect mutations
+		WithConsistencyWait(0, 0, 1),
+	}, opts...)
+
+	client, err := New(opts...)
+	if err != nil {
+		t.Fatalf("Unexpected configuration error: %v", err)
+	}
+	return client
+}
+
+func TestConfig_Validate(t *testing.T) {
+	valid := []Option{WithCredentials("testlogin", "testkey")}
+
+	tests := []struct {
+		name   string
+		opts   []Option
+		fields []string
+	}{
+		{
+			name: "defaults",
+			opts: valid,
+		},
+		{
+			name:   "missing_credentials",
+			opts:   nil,
+			fields: []string{"login", "api_key"},
+		},
+		{
+			name:   "relative_base_url",
+			opts:   append(valid, WithBaseURL("api.lws.net/v1")),
+			fields: []string{"base_url"},
+		},
+		{
+			name: "base_urls_replace_base_url",
+			opts: append(valid, WithBaseURL("api.lws.net/v1"), WithBaseURLs("https://api.lws.net/v1", "https://relay.example.com/v1")),
+		},
+		{
+			name:   "invalid_base_urls",
+			opts:   append(valid, WithBaseURLs("https://api.lws.net/v1", "relay.example.com", "https://api.lws.net/v1/")),
+			fields: []string{"base_urls", "base_urls"},
+		},
+		{
+			name:   "negative_failover_cooldown",
+			opts:   append(valid, WithFailoverCooldown(-time.Second)),
+			fields: []string{"failover_cooldown"},
+		},
+		{
+			name:   "invalid_timeout",
+			opts:   append(valid, WithTimeout(0)),
+			fields: []string{"timeout"},
+		},
+		{
+			name: "timeout_ignored_with_http_client",
+			opts: append(valid, WithTimeout(0), WithHTTPClient(http.DefaultClient)),
+		},
+		{
+			name:   "invalid_retry_policy",
+			opts:   append(valid, WithRetryPolicy(NewDefaultRetryPolicy(-1, -time.Second, 0))),
+			fields: []string{"retries", "delay", "backoff"},
+		},
+		{
+			name:   "invalid_rate_limit",
+			opts:   append(valid, WithRateLimit(-1, 0, 0)),
+			fields: []string{"requests_per_second", "max_concurrent_requests"},
+		},
+		{
+			name:   "negative_zone_cache_ttl",
+			opts:   append(valid, WithZoneCacheTTL(-time.Second)),
+			fields: []string{"zone_cache_ttl"},
+		},
+		{
+			name:   "invalid_consistency_wait",
+			opts:   append(valid, WithConsistencyWait(0, 10*time.Second, 0)),
+			fields: []string{"consistency_poll_interval", "consistency_backoff"},
+		},
+		{
+			name:   "negative_consistency_max_wait",
+			opts:   append(valid, WithConsistencyWait(time.Second, -time.Second, 2)),
+			fields: []string{"consistency_max_wait"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.opts...)
+			if len(tt.fields) == 0 {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				return
+			}
+
+			var cfgErrs ConfigErrors
+			if !errors.As(err, &cfgErrs) {
+				t.Fatalf("Expected ConfigErrors, got %v", err)
+			}
+			if len(cfgErrs) != len(tt.fields) {
+				t.Fatalf("Expected %d errors, got %d: %v", len(tt.fields), len(cfgErrs), err)
+			}
+			for i, field := range tt.fields {
+				if cfgErrs[i].Field != field {
+					t.Errorf("Expected error %d on %s, got %s", i, field, cfgErrs[i].Field)
+				}
+			}
+		})
+	}
+}
+
+type recordingLogger struct {
+	mu    sync.Mutex
+	lines int
+}
+
+func (l *recordingLogger) Log(ctx context.Context, level LogLevel, msg string, fields map[string]interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines++
+}
+
+func TestNew_Options(t *testing.T) {
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		_, _ = w.Write([]byte(`{"code": 200, "info": "Fetched DNS Zone", "data": []}`))
+	}))
+	defer server.Close()
+
+	var roundTrips int
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		roundTrips++
+		return http.DefaultTransport.RoundTrip(req)
+	})
+	logger := &recordingLogger{}
+
+	client := newTestClient(t, server.URL,
+		WithTransport(transport),
+		WithLogger(logger),
+		WithUserAgent("lws-tooling/1.0"),
+	)
+
+	if _, err := client.GetDNSZone(context.Background(), testDomainName); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if userAgent != "lws-tooling/1.0" {
+		t.Errorf("Expected custom User-Agent, got %q", userAgent)
+	}
+	if roundTrips != 1 {
+		t.Errorf("Expected the request to go through the custom transport, got %d round trips", roundTrips)
+	}
+	if logger.lines == 0 {
+		t.Errorf("Expected debug output to go to the custom logger")
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Defaults of the consistency waiter.
+const (
+	DefaultConsistencyInterval   = time.Second
+	DefaultConsistencyMaxWait    = 30 * time.Second
+	DefaultConsistencyMultiplier = 2
+)
+
+// ConsistencyWaiter polls the zone listing after a change until it reflects
+// it. LWS can accept a change several seconds before the listing shows it,
+// and the provider reads the zone right after applying changes.
+type ConsistencyWaiter struct {
+	// Interval is the wait before the second check, the first one is made
+	// immediately.
+	Interval time.Duration
+	// MaxWait bounds the total wait. Zero disables waiting: a single check
+	// is made when the change must be read back, e.g. to find the ID of a
+	// created record.
+	MaxWait time.Duration
+	// Multiplier is applied to the interval after each check. Values below
+	// 1 are treated as 1.
+	Multiplier float64
+}
+
+// NewConsistencyWaiter returns a waiter checking after interval, then at
+// exponentially spaced times until maxWait has elapsed.
+func NewConsistencyWaiter(interval, maxWait time.Duration, multiplier float64) *ConsistencyWaiter {
+	return &ConsistencyWaiter{
+		Interval:   interval,
+		MaxWait:    maxWait,
+		Multiplier: multiplier,
+	}
+}
+
+// Enabled reports whether changes are waited for.
+func (w *ConsistencyWaiter) Enabled() bool {
+	return w != nil && w.MaxWait > 0
+}
+
+// Wait calls check until it reports true. It returns an error matching
+// ErrNotConsistent once MaxWait has elapsed, and the error of check as soon
+// as one fails.
+func (w *ConsistencyWaiter) Wait(ctx context.Context, check func(ctx context.Context) (bool, error)) error {
+	multiplier := 1.0
+	var interval, maxWait time.Duration
+	if w != nil {
+		interval, maxWait = w.Interval, w.MaxWait
+		multiplier = math.Max(w.Multiplier, 1)
+	}
+
+	var waited time.Duration
+	for checks := 1; ; checks++ {
+		done, err := check(ctx)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		if waited >= maxWait || interval <= 0 {
+			return fmt.Errorf("%w after %d checks over %s", ErrNotConsistent, checks, waited)
+		}
+
+		wait := interval
+		if remaining := maxWait - waited; wait > remaining {
+			wait = remaining
+		}
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+		waited += wait
+		interval = time.Duration(float64(interval) * multiplier)
+	}
+}
+
+// waitForZone waits until the zone listing satisfies visible. The cached
+// listing is dropped before each check so the API is asked every time.
+func (c *LWSClient) waitForZone(ctx context.Context, zoneName, change string, visible func(records []DNSRecord) bool) error {
+	checks := 0
+	err := c.consistency.Wait(ctx, func(ctx context.Context) (bool, error) {
+		checks++
+		c.zones.invalidate(zoneName)
+		zone, err := c.GetDNSZone(ctx, zoneName)
+		if err != nil {
+			return false, fmt.Errorf("error getting DNS zone '%s': %w", zoneName, err)
+		}
+
+		done := visible(zone.Records)
+		if !done {
+			c.log(ctx, LogDebug, "Zone listing does not reflect the change yet", map[string]interface{}{
+				"zone":   zoneName,
+				"change": change,
+				"check":  checks,
+			})
+		}
+		return done, nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s in zone '%s': %w", change, zoneName, err)
+	}
+	return nil
+}
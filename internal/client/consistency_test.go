@@ -0,0 +1,259 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/M4XGO/terraform-provider-lws/internal/lwsfake"
+)
+
+// laggingZoneServer applies mutations immediately but keeps serving the
+// previous listing for the next lag GETs, like a slowly indexed zone.
+type laggingZoneServer struct {
+	mu      sync.Mutex
+	lag     int
+	records []DNSRecord
+	listed  []DNSRecord
+	pending int
+	nextID  int
+	gets    int
+}
+
+func (s *laggingZoneServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodGet:
+		s.gets++
+		if s.pending > 0 {
+			s.pending--
+		} else {
+			s.listed = append([]DNSRecord(nil), s.records...)
+		}
+		data, _ := json.Marshal(s.listed)
+		_, _ = fmt.Fprintf(w, `{"code": 200, "info": "Fetched DNS Zone", "data": %s}`, data)
+		return
+
+	case http.MethodPost:
+		var req CreateDNSRecordRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		s.nextID++
+		s.records = append(s.records, DNSRecord{ID: s.nextID, Name: req.Name, Type: req.Type, Value: req.Value, TTL: req.TTL})
+		_, _ = w.Write([]byte(`{"code": 200, "info": "Added a new line in the DNS Zone", "data": {}}`))
+
+	case http.MethodPut:
+		var req UpdateDNSRecordRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		for i := range s.records {
+			if s.records[i].ID == req.ID {
+				s.records[i] = DNSRecord{ID: req.ID, Name: req.Name, Type: req.Type, Value: req.Value, TTL: req.TTL}
+			}
+		}
+		data, _ := json.Marshal(req)
+		_, _ = fmt.Fprintf(w, `{"code": 200, "info": "Record updated", "data": %s}`, data)
+
+	case http.MethodDelete:
+		var req struct {
+			ID int `json:"id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		kept := s.records[:0]
+		for _, record := range s.records {
+			if record.ID != req.ID {
+				kept = append(kept, record)
+			}
+		}
+		s.records = kept
+		_, _ = w.Write([]byte(`{"code": 200, "info": "Record deleted", "data": null}`))
+	}
+
+	s.pending = s.lag
+}
+
+func (s *laggingZoneServer) getCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gets
+}
+
+func TestLWSClient_ConsistencyWaiter(t *testing.T) {
+	server := &laggingZoneServer{lag: 2}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	client := newTestClient(t, ts.URL, WithTestMode(false), WithConsistencyWait(5*time.Millisecond, time.Second, 2), WithRateLimit(0, 0, 4))
+	ctx := context.Background()
+
+	record, err := client.CreateDNSRecord(ctx, &DNSRecord{Name: "www", Type: "A", Value: "192.168.1.1", Zone: testDomainName, TTL: 3600})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if record.ID != 1 {
+		t.Errorf("Expected the created record ID 1, got %d", record.ID)
+	}
+	// Two stale listings, then the one showing the record
+	if gets := server.getCount(); gets != 3 {
+		t.Errorf("Expected 3 listings before the record showed up, got %d", gets)
+	}
+
+	record.Value = testIP4Address
+	if _, err := client.UpdateDNSRecord(ctx, record); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if listed, err := client.GetDNSRecord(ctx, testDomainName, "1"); err != nil || listed.Value != testIP4Address {
+		t.Errorf("Expected the cached listing to show the update, got %+v (%v)", listed, err)
+	}
+
+	if err := client.DeleteDNSRecord(ctx, record.ID, testDomainName); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := client.GetDNSRecord(ctx, testDomainName, "1"); !IsNotFound(err) {
+		t.Errorf("Expected the deleted record to be gone from the listing, got %v", err)
+	}
+}
+
+func TestLWSClient_ConsistencyWaiterGivesUp(t *testing.T) {
+	// The listing never catches up with the changes
+	existing := []DNSRecord{{ID: 1, Name: "www", Type: "A", Value: "192.168.1.1", TTL: 3600}}
+	server := &laggingZoneServer{lag: 100, records: existing, listed: append([]DNSRecord(nil), existing...), nextID: 1}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	client := newTestClient(t, ts.URL, WithTestMode(false), WithConsistencyWait(5*time.Millisecond, 30*time.Millisecond, 2), WithRateLimit(0, 0, 4))
+	ctx := context.Background()
+
+	// LWS accepted the changes, a listing that never shows them is only
+	// logged
+	if _, err := client.UpdateDNSRecord(ctx, &DNSRecord{ID: 1, Name: "www", Type: "A", Value: testIP4Address, Zone: testDomainName, TTL: 3600}); err != nil {
+		t.Errorf("Expected the update to succeed after the wait, got %v", err)
+	}
+	gets := server.getCount()
+	if gets < 2 {
+		t.Errorf("Expected the update to be waited for, got %d listings", gets)
+	}
+
+	if err := client.DeleteDNSRecord(ctx, 1, testDomainName); err != nil {
+		t.Errorf("Expected the delete to succeed after the wait, got %v", err)
+	}
+	if server.getCount() < gets+2 {
+		t.Errorf("Expected the delete to be waited for, got %d listings", server.getCount()-gets)
+	}
+}
+
+func TestLWSClient_ConsistencyWaiterRenumberedUpdate(t *testing.T) {
+	seeded := lwsfake.Record{ID: 1, Name: "www", Type: "A", Value: "192.168.1.1", TTL: 3600}
+	fake := lwsfake.New(lwsfake.WithCredentials("testlogin", "testkey"), lwsfake.WithZone(testDomainName, seeded))
+	fake.Inject(lwsfake.Fault{Method: http.MethodPut, Renumber: true, Lag: 1})
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+
+	client := newTestClient(t, ts.URL, WithTestMode(false), WithConsistencyWait(5*time.Millisecond, time.Second, 2))
+
+	updated, err := client.UpdateDNSRecord(context.Background(), &DNSRecord{ID: 1, Name: "www", Type: "A", Value: testIP4Address, Zone: testDomainName, TTL: 3600})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	records := fake.Records(testDomainName)
+	if len(records) != 1 || updated.ID != records[0].ID || updated.ID == 1 {
+		t.Errorf("Expected the new ID of the record, got %+v for %+v", updated, records)
+	}
+}
+
+func TestLWSClient_ConsistencyWaiterDisabled(t *testing.T) {
+	server := &laggingZoneServer{lag: 1}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	client := newTestClient(t, ts.URL, WithTestMode(false), WithConsistencyWait(time.Second, 0, 2))
+
+	// A single listing is made to find the ID, falling back to the name
+	// lookup, which finds nothing in the stale listing
+	_, err := client.CreateDNSRecord(context.Background(), &DNSRecord{Name: "www", Type: "A", Value: "192.168.1.1", Zone: testDomainName, TTL: 3600})
+	if !IsNotFound(err) {
+		t.Errorf("Expected the record not to be found without waiting, got %v", err)
+	}
+	if gets := server.getCount(); gets != 1 {
+		t.Errorf("Expected a single listing, got %d", gets)
+	}
+}
+
+func TestLWSClient_TestModeSkipsConsistencyWait(t *testing.T) {
+	seeded := lwsfake.Record{ID: 1, Name: "www", Type: "A", Value: "192.168.1.1", TTL: 3600}
+	fake := lwsfake.New(lwsfake.WithCredentials("testlogin", "testkey"), lwsfake.WithZone(testDomainName, seeded))
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+
+	// LWS validates test mode changes without applying them, so the listing
+	// never shows them and the default wait would run for its whole length
+	client := newTestClient(t, ts.URL, WithTestMode(true),
+		WithConsistencyWait(DefaultConsistencyInterval, DefaultConsistencyMaxWait, DefaultConsistencyMultiplier))
+	ctx := context.Background()
+	start := time.Now()
+
+	created, err := client.CreateDNSRecord(ctx, &DNSRecord{Name: "api", Type: "A", Value: testIP4Address, Zone: testDomainName, TTL: 3600})
+	if err != nil {
+		t.Fatalf("Unexpected create error: %v", err)
+	}
+	if created.ID != 0 || created.Name != "api" {
+		t.Errorf("Expected the validated record without an ID, got %+v", created)
+	}
+
+	_, err = client.UpdateDNSRecord(ctx, &DNSRecord{ID: 1, Name: "www", Type: "A", Value: testIP4Address, Zone: testDomainName, TTL: 3600})
+	if err != nil {
+		t.Fatalf("Unexpected update error: %v", err)
+	}
+
+	if err := client.DeleteDNSRecord(ctx, 1, testDomainName); err != nil {
+		t.Fatalf("Unexpected delete error: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected test mode changes not to be waited for, took %s", elapsed)
+	}
+	if records := fake.Records(testDomainName); len(records) != 1 || records[0] != seeded {
+		t.Errorf("Expected the zone to be unchanged, got %+v", records)
+	}
+	if listed, err := client.GetDNSRecord(ctx, testDomainName, "1"); err != nil || listed.Value != seeded.Value {
+		t.Errorf("Expected the listing to still show the seeded record, got %+v (%v)", listed, err)
+	}
+}
+
+func TestConsistencyWaiter_Wait(t *testing.T) {
+	waiter := NewConsistencyWaiter(10*time.Millisecond, 70*time.Millisecond, 2)
+
+	var checks []time.Duration
+	start := time.Now()
+	err := waiter.Wait(context.Background(), func(ctx context.Context) (bool, error) {
+		checks = append(checks, time.Since(start))
+		return false, nil
+	})
+	if !IsNotConsistent(err) {
+		t.Fatalf("Expected ErrNotConsistent, got %v", err)
+	}
+
+	// Checks at 0, 10ms, 30ms and 70ms
+	if len(checks) != 4 {
+		t.Fatalf("Expected 4 checks, got %d", len(checks))
+	}
+	for i, min := range []time.Duration{0, 10 * time.Millisecond, 30 * time.Millisecond, 70 * time.Millisecond} {
+		if checks[i] < min {
+			t.Errorf("Check %d happened after %s, expected at least %s", i+1, checks[i], min)
+		}
+	}
+
+	boom := errors.New("boom")
+	err = waiter.Wait(context.Background(), func(ctx context.Context) (bool, error) {
+		return false, boom
+	})
+	if !errors.Is(err, boom) {
+		t.Errorf("Expected the check error to be returned, got %v", err)
+	}
+}
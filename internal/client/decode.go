@@ -0,0 +1,78 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// decodeData decodes the data field of a response into a T. The field is
+// kept raw when the response is read, so it is only decoded once, into the
+// type the caller expects. A missing or null field leaves the zero value.
+func decodeData[T any](resp *LWSAPIResponse) (T, error) {
+	var data T
+	if len(bytes.TrimSpace(resp.Data)) == 0 {
+		return data, nil
+	}
+	err := json.Unmarshal(resp.Data, &data)
+	return data, err
+}
+
+// UnmarshalJSON decodes a record as sent by LWS, which gives IDs and TTLs
+// either as numbers or as numeric strings.
+func (r *DNSRecord) UnmarshalJSON(data []byte) error {
+	type plain DNSRecord
+	aux := struct {
+		*plain
+		ID  lenientInt `json:"id"`
+		TTL lenientInt `json:"ttl"`
+	}{plain: (*plain)(r)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	r.ID = int(aux.ID)
+	r.TTL = int(aux.TTL)
+	return nil
+}
+
+// lenientInt is an integer that may be sent as a JSON number or a string
+// holding one. Null and empty strings decode to zero.
+type lenientInt int
+
+func (n *lenientInt) UnmarshalJSON(data []byte) error {
+	text := string(bytes.TrimSpace(data))
+	if text == "null" {
+		*n = 0
+		return nil
+	}
+
+	if strings.HasPrefix(text, `"`) {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		text = strings.TrimSpace(s)
+		if text == "" {
+			*n = 0
+			return nil
+		}
+	}
+
+	if i, err := strconv.Atoi(text); err == nil {
+		*n = lenientInt(i)
+		return nil
+	}
+
+	// Integral values written as floats, e.g. 3600.0 or 3.6e3
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil || f != math.Trunc(f) || math.Abs(f) > math.MaxInt32 {
+		return fmt.Errorf("invalid integer %s", data)
+	}
+	*n = lenientInt(f)
+	return nil
+}
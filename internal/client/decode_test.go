@@ -0,0 +1,179 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDNSRecord_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    DNSRecord
+		wantErr bool
+	}{
+		{
+			name: "numbers",
+			json: `{"id": 123, "name": "www", "type": "A", "value": "192.168.1.1", "ttl": 3600}`,
+			want: DNSRecord{ID: 123, Name: "www", Type: "A", Value: "192.168.1.1", TTL: 3600},
+		},
+		{
+			name: "numeric_strings",
+			json: `{"id": "123", "name": "www", "type": "A", "value": "192.168.1.1", "ttl": " 3600 "}`,
+			want: DNSRecord{ID: 123, Name: "www", Type: "A", Value: "192.168.1.1", TTL: 3600},
+		},
+		{
+			name: "integral_float",
+			json: `{"id": 123.0, "name": "www", "type": "A", "value": "192.168.1.1", "ttl": 3.6e3}`,
+			want: DNSRecord{ID: 123, Name: "www", Type: "A", Value: "192.168.1.1", TTL: 3600},
+		},
+		{
+			name: "null_and_empty",
+			json: `{"id": null, "name": "www", "type": "A", "value": "192.168.1.1", "ttl": ""}`,
+			want: DNSRecord{Name: "www", Type: "A", Value: "192.168.1.1"},
+		},
+		{
+			name: "missing",
+			json: `{"name": "www", "type": "A", "value": "192.168.1.1"}`,
+			want: DNSRecord{Name: "www", Type: "A", Value: "192.168.1.1"},
+		},
+		{
+			name:    "not_a_number",
+			json:    `{"id": "abc", "name": "www"}`,
+			wantErr: true,
+		},
+		{
+			name:    "fractional",
+			json:    `{"ttl": 1.5}`,
+			wantErr: true,
+		},
+		{
+			name:    "object",
+			json:    `{"id": {"value": 1}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got DNSRecord
+			err := json.Unmarshal([]byte(tt.json), &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDecodeData(t *testing.T) {
+	var resp LWSAPIResponse
+	if err := json.Unmarshal([]byte(`{"code": 200, "info": "Fetched DNS Zone", "data": [{"id": "1", "name": "www", "type": "A", "value": "192.168.1.1", "ttl": "300"}]}`), &resp); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	records, err := decodeData[[]DNSRecord](&resp)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(records) != 1 || records[0].ID != 1 || records[0].TTL != 300 {
+		t.Errorf("Unexpected records: %+v", records)
+	}
+
+	if _, err := decodeData[DNSRecord](&resp); err == nil {
+		t.Errorf("Expected an error decoding a list as a record")
+	}
+
+	for _, data := range []string{"", "null"} {
+		resp := LWSAPIResponse{Data: json.RawMessage(data)}
+		records, err := decodeData[[]DNSRecord](&resp)
+		if err != nil || records != nil {
+			t.Errorf("Expected no records for data %q, got %v, %v", data, records, err)
+		}
+	}
+}
+
+func TestLWSClient_GetDNSZoneLenientFields(t *testing.T) {
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return fakeResponse(req, `{"code": 200, "info": "Fetched DNS Zone", "data": [`+
+			`{"id": "10", "name": "www", "type": "A", "value": "192.168.1.1", "ttl": "3600"},`+
+			`{"id": 11, "name": "@", "type": "MX", "value": "10 mail.example.com", "ttl": 300}]}`), nil
+	})
+	client := newTestClient(t, "https://api.lws.test/v1", WithTransport(transport))
+
+	zone, err := client.GetDNSZone(context.Background(), testDomainName)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(zone.Records) != 2 || zone.Records[0].ID != 10 || zone.Records[0].TTL != 3600 || zone.Records[1].ID != 11 {
+		t.Errorf("Unexpected records: %+v", zone.Records)
+	}
+}
+
+// fakeResponse answers req with a 200 and the given body.
+func fakeResponse(req *http.Request, body string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}
+}
+
+// FuzzDecodeResponse feeds arbitrary bodies through the response decoding of
+// a zone listing and of a create. Malformed payloads must yield errors, never
+// panics.
+func FuzzDecodeResponse(f *testing.F) {
+	seeds := []string{
+		`{"code": 200, "info": "Fetched DNS Zone", "data": [{"id": 1, "name": "www", "type": "A", "value": "192.168.1.1", "ttl": 3600}]}`,
+		`{"code": 200, "info": "Fetched DNS Zone", "data": [{"id": "1", "ttl": "3600"}]}`,
+		`{"code": 200, "info": "Added a new line in the DNS Zone", "data": {}}`,
+		`{"code": 404, "info": {"name": "Record not found"}, "data": null}`,
+		`{"code": 400, "info": ["unexpected"], "data": "text"}`,
+		`{"code": "200", "info": 12, "data": [null, 1, "x"]}`,
+		`{"code": 200, "data": [{"id": 1e400}]}`,
+		`<!DOCTYPE html><html><title>Just a moment...</title></html>`,
+		``,
+		`null`,
+	}
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		var resp LWSAPIResponse
+		if err := json.Unmarshal(body, &resp); err == nil {
+			_ = resp.GetInfoMessage()
+			_, _ = decodeData[[]DNSRecord](&resp)
+			_, _ = decodeData[DNSRecord](&resp)
+		}
+
+		transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return fakeResponse(req, string(body)), nil
+		})
+		client := newTestClient(t, "https://api.lws.test/v1",
+			WithTransport(transport),
+			WithRateLimit(0, 0, 4),
+			WithLogger(&recordingLogger{}),
+		)
+
+		if _, err := client.GetDNSZone(context.Background(), testDomainName); err != nil {
+			_ = err.Error()
+		}
+		if _, err := client.CreateDNSRecord(context.Background(), &DNSRecord{Name: "www", Type: "A", Value: "192.168.1.1", Zone: testDomainName}); err != nil {
+			_ = err.Error()
+		}
+	})
+}
@@ -0,0 +1,215 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultFailoverCooldown is how long an API address that failed is passed
+// over in favour of the next one before it is tried again.
+const DefaultFailoverCooldown = time.Minute
+
+// EndpointStatus is the health of one API address, see LWSClient.Endpoints.
+type EndpointStatus struct {
+	BaseURL string
+	// Healthy is false while the address cools down after a failure.
+	Healthy bool
+	// Failures counts the failures since the address last answered.
+	Failures int
+	// LastError is the last failure of the address, if any.
+	LastError error
+	// RetryAt is when an unhealthy address is preferred again.
+	RetryAt time.Time
+}
+
+// endpointPool tracks the health of the API addresses of a client and
+// decides in which order they are tried.
+//
+// Addresses are tried in configuration order. One that can't be reached,
+// answers with a 5xx or with a challenge page is put behind the others for
+// the cooldown, so calls stick to the address that works; once the cooldown
+// has elapsed, it takes its place again and the primary address is back in
+// use as soon as it recovers.
+type endpointPool struct {
+	mu        sync.Mutex
+	cooldown  time.Duration
+	clock     Clock
+	endpoints []*endpointHealth
+}
+
+type endpointHealth struct {
+	baseURL  string
+	failures int
+	lastErr  error
+	failedAt time.Time
+}
+
+func newEndpointPool(baseURLs []string, cooldown time.Duration, clock Clock) *endpointPool {
+	pool := &endpointPool{cooldown: cooldown, clock: clock}
+	for _, baseURL := range baseURLs {
+		pool.endpoints = append(pool.endpoints, &endpointHealth{baseURL: baseURL})
+	}
+	return pool
+}
+
+// healthy reports whether an address is not cooling down after a failure.
+func (p *endpointPool) healthy(e *endpointHealth, now time.Time) bool {
+	return e.failures == 0 || !now.Before(e.failedAt.Add(p.cooldown))
+}
+
+// order returns the addresses to try for a call: the healthy ones in
+// configuration order, then those cooling down, the first to recover first.
+func (p *endpointPool) order() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.clock.Now()
+	var healthy, cooling []*endpointHealth
+	for _, e := range p.endpoints {
+		if p.healthy(e, now) {
+			healthy = append(healthy, e)
+		} else {
+			cooling = append(cooling, e)
+		}
+	}
+	sort.SliceStable(cooling, func(i, j int) bool {
+		return cooling[i].failedAt.Before(cooling[j].failedAt)
+	})
+
+	order := make([]string, 0, len(p.endpoints))
+	for _, e := range append(healthy, cooling...) {
+		order = append(order, e.baseURL)
+	}
+	return order
+}
+
+// failed records a failure of an address.
+func (p *endpointPool) failed(baseURL string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, e := range p.endpoints {
+		if e.baseURL == baseURL {
+			e.failures++
+			e.lastErr = err
+			e.failedAt = p.clock.Now()
+		}
+	}
+}
+
+// succeeded records that an address answered.
+func (p *endpointPool) succeeded(baseURL string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, e := range p.endpoints {
+		if e.baseURL == baseURL {
+			e.failures = 0
+			e.lastErr = nil
+		}
+	}
+}
+
+// status returns the health of every address, in configuration order.
+func (p *endpointPool) status() []EndpointStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.clock.Now()
+	statuses := make([]EndpointStatus, len(p.endpoints))
+	for i, e := range p.endpoints {
+		statuses[i] = EndpointStatus{
+			BaseURL:   e.baseURL,
+			Healthy:   p.healthy(e, now),
+			Failures:  e.failures,
+			LastError: e.lastErr,
+		}
+		if !statuses[i].Healthy {
+			statuses[i].RetryAt = e.failedAt.Add(p.cooldown)
+		}
+	}
+	return statuses
+}
+
+// Endpoints returns the health of the API addresses of the client, the
+// primary one first.
+func (c *LWSClient) Endpoints() []EndpointStatus {
+	return c.endpoints.status()
+}
+
+// isFailoverError reports whether err is a failure of the API address that
+// another address may not have: a transport error, a 5xx answer or a
+// challenge page. Cancellations, an open circuit breaker and requests
+// missing from a replayed recording are not.
+func isFailoverError(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil || IsCircuitOpen(err) || IsNotRecorded(err) {
+		return false
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return true
+	}
+	return isBreakerFailure(err)
+}
+
+// doRequestFailover makes an attempt of a call, moving on to the next API
+// address when one fails with a failover error. Calls that are not
+// idempotent only move on when guard reports that the failed address did
+// not apply them, or when a challenge page proves it never processed them.
+// It returns the URL of the last address tried.
+func (c *LWSClient) doRequestFailover(ctx context.Context, method, endpoint string, reqBodyBytes []byte, attempt int, guard retryGuard) (*LWSAPIResponse, string, error) {
+	baseURLs := c.endpoints.order()
+
+	var (
+		apiResp *LWSAPIResponse
+		url     string
+		err     error
+	)
+	for i, baseURL := range baseURLs {
+		if i > 0 && guard != nil {
+			applied, guardErr := guard(ctx)
+			if guardErr != nil {
+				return nil, url, fmt.Errorf("unable to check whether %s %s was applied, not sending it to %s: %w (last error: %s)", method, endpoint, baseURL, guardErr, err)
+			}
+			if applied {
+				c.log(ctx, LogInfo, "LWS API applied the request despite the error, not sending it again", map[string]interface{}{
+					"method":   method,
+					"endpoint": endpoint,
+					"attempt":  attempt,
+					"base_url": baseURLs[i-1],
+					"error":    err.Error(),
+				})
+				return nil, url, nil
+			}
+		}
+
+		url = fmt.Sprintf("%s/%s", baseURL, endpoint)
+		apiResp, err = c.doRequest(ctx, method, endpoint, baseURL, url, reqBodyBytes, attempt)
+		if !isFailoverError(ctx, err) {
+			var apiErr *APIError
+			if err == nil || errors.As(err, &apiErr) {
+				c.endpoints.succeeded(baseURL)
+			}
+			return apiResp, url, err
+		}
+
+		c.endpoints.failed(baseURL, err)
+		if i+1 == len(baseURLs) || (guard == nil && !isIdempotent(method) && !IsChallenge(err)) {
+			break
+		}
+		c.log(ctx, LogWarn, "LWS API address failed, trying the next one", map[string]interface{}{
+			"method":   method,
+			"endpoint": endpoint,
+			"attempt":  attempt,
+			"base_url": baseURL,
+			"next":     baseURLs[i+1],
+			"error":    err.Error(),
+		})
+	}
+
+	return apiResp, url, err
+}
@@ -0,0 +1,274 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// endpointServer is an API address counting its requests. It passes them to
+// backend, or answers with fail while it is set.
+type endpointServer struct {
+	*httptest.Server
+	backend http.Handler
+
+	mu   sync.Mutex
+	hits int
+	fail func(w http.ResponseWriter, r *http.Request, backend http.Handler)
+}
+
+func newEndpointServer(t *testing.T, backend http.Handler) *endpointServer {
+	t.Helper()
+
+	s := &endpointServer{backend: backend}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.hits++
+		fail := s.fail
+		s.mu.Unlock()
+
+		if fail != nil {
+			fail(w, r, s.backend)
+			return
+		}
+		s.backend.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *endpointServer) setFail(fail func(w http.ResponseWriter, r *http.Request, backend http.Handler)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fail = fail
+}
+
+func (s *endpointServer) requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits
+}
+
+func serverError(w http.ResponseWriter, r *http.Request, backend http.Handler) {
+	w.WriteHeader(http.StatusBadGateway)
+	_, _ = w.Write([]byte(`{"code": 502, "info": "Bad gateway", "data": null}`))
+}
+
+func challenge(w http.ResponseWriter, r *http.Request, backend http.Handler) {
+	w.WriteHeader(http.StatusForbidden)
+	_, _ = w.Write([]byte(challengePage))
+}
+
+// fieldsLogger records the fields of each message.
+type fieldsLogger struct {
+	mu      sync.Mutex
+	entries []map[string]interface{}
+	msgs    []string
+}
+
+func (l *fieldsLogger) Log(ctx context.Context, level LogLevel, msg string, fields map[string]interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.msgs = append(l.msgs, msg)
+	l.entries = append(l.entries, fields)
+}
+
+// servedBy returns the base_url of the "Received LWS API response" entries.
+func (l *fieldsLogger) servedBy() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var baseURLs []string
+	for i, msg := range l.msgs {
+		if msg == "Received LWS API response" {
+			baseURLs = append(baseURLs, l.entries[i]["base_url"].(string))
+		}
+	}
+	return baseURLs
+}
+
+func TestLWSClient_Failover(t *testing.T) {
+	tests := []struct {
+		name string
+		fail func(w http.ResponseWriter, r *http.Request, backend http.Handler)
+		// down closes the primary server instead
+		down bool
+	}{
+		{name: "connection_error", down: true},
+		{name: "server_error", fail: serverError},
+		{name: "challenge", fail: challenge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &laggingZoneServer{records: []DNSRecord{{ID: 1, Name: "www", Type: "A", Value: "192.168.1.1", TTL: 3600}}}
+			primary := newEndpointServer(t, backend)
+			fallback := newEndpointServer(t, backend)
+			primary.setFail(tt.fail)
+			if tt.down {
+				primary.Close()
+			}
+
+			logger := &fieldsLogger{}
+			client := newTestClient(t, "", WithBaseURLs(primary.URL, fallback.URL), WithZoneCacheTTL(0), WithLogger(logger))
+
+			zone, err := client.GetDNSZone(context.Background(), testDomainName)
+			if err != nil {
+				t.Fatalf("Expected the fallback address to answer, got %v", err)
+			}
+			if len(zone.Records) != 1 {
+				t.Errorf("Expected the fallback listing, got %+v", zone.Records)
+			}
+			if got := logger.servedBy(); len(got) == 0 || got[len(got)-1] != fallback.URL {
+				t.Errorf("Expected the response to be logged as served by %s, got %v", fallback.URL, got)
+			}
+
+			status := client.Endpoints()
+			if len(status) != 2 || status[0].Healthy || status[0].Failures != 1 || status[0].LastError == nil {
+				t.Errorf("Expected the primary address to be unhealthy, got %+v", status)
+			}
+			if !status[1].Healthy || status[1].Failures != 0 {
+				t.Errorf("Expected the fallback address to be healthy, got %+v", status[1])
+			}
+		})
+	}
+}
+
+func TestLWSClient_FailoverReturnsToPrimary(t *testing.T) {
+	backend := &laggingZoneServer{}
+	primary := newEndpointServer(t, backend)
+	fallback := newEndpointServer(t, backend)
+	primary.setFail(serverError)
+
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	client := newTestClient(t, "",
+		WithBaseURLs(primary.URL, fallback.URL),
+		WithFailoverCooldown(time.Minute),
+		WithZoneCacheTTL(0),
+		WithCircuitBreaker(0, 0),
+		WithClock(clock),
+	)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.GetDNSZone(ctx, testDomainName); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if primary.requests() != 1 || fallback.requests() != 3 {
+		t.Errorf("Expected calls to stick to the fallback address, got %d primary and %d fallback requests",
+			primary.requests(), fallback.requests())
+	}
+	if retryAt := client.Endpoints()[0].RetryAt; !retryAt.Equal(clock.Now().Add(time.Minute)) {
+		t.Errorf("Expected the primary address to be retried after the cooldown, got %s", retryAt)
+	}
+
+	// Once the cooldown has elapsed the primary address is tried first again
+	primary.setFail(nil)
+	clock.Advance(time.Minute)
+	if _, err := client.GetDNSZone(ctx, testDomainName); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if primary.requests() != 2 || fallback.requests() != 3 {
+		t.Errorf("Expected the primary address to be back in use, got %d primary and %d fallback requests",
+			primary.requests(), fallback.requests())
+	}
+	if status := client.Endpoints()[0]; !status.Healthy || status.Failures != 0 {
+		t.Errorf("Expected the primary address to be healthy again, got %+v", status)
+	}
+}
+
+func TestLWSClient_FailoverCreate(t *testing.T) {
+	record := DNSRecord{Name: "www", Type: "A", Value: "192.168.1.1", TTL: 300, Zone: testDomainName}
+
+	tests := []struct {
+		name string
+		// applied makes the primary address apply the create before failing
+		applied bool
+	}{
+		{name: "not_applied"},
+		{name: "applied", applied: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &laggingZoneServer{}
+			primary := newEndpointServer(t, backend)
+			fallback := newEndpointServer(t, backend)
+			primary.setFail(func(w http.ResponseWriter, r *http.Request, backend http.Handler) {
+				if r.Method != http.MethodPost {
+					backend.ServeHTTP(w, r)
+					return
+				}
+				if tt.applied {
+					backend.ServeHTTP(httptest.NewRecorder(), r)
+				}
+				serverError(w, r, backend)
+			})
+
+			client := newTestClient(t, "", WithBaseURLs(primary.URL, fallback.URL))
+			created, err := client.CreateDNSRecord(context.Background(), &record)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if created == nil || created.ID != 1 {
+				t.Errorf("Expected the created record, got %+v", created)
+			}
+
+			backend.mu.Lock()
+			defer backend.mu.Unlock()
+			if len(backend.records) != 1 {
+				t.Errorf("Expected the record to be created once, got %+v", backend.records)
+			}
+		})
+	}
+}
+
+func TestLWSClient_NoFailover(t *testing.T) {
+	tests := []struct {
+		name     string
+		fail     func(w http.ResponseWriter, r *http.Request, backend http.Handler)
+		method   string
+		contains string
+	}{
+		{
+			name: "client_error",
+			fail: func(w http.ResponseWriter, r *http.Request, backend http.Handler) {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"code": 401, "info": "Invalid credentials", "data": null}`))
+			},
+			method:   http.MethodGet,
+			contains: "Invalid credentials",
+		},
+		{
+			// A POST answered with a 5xx may have been applied, and without
+			// a guard nothing tells whether it was
+			name:     "unguarded_post",
+			fail:     serverError,
+			method:   http.MethodPost,
+			contains: "Bad gateway",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &laggingZoneServer{}
+			primary := newEndpointServer(t, backend)
+			fallback := newEndpointServer(t, backend)
+			primary.setFail(tt.fail)
+
+			client := newTestClient(t, "", WithBaseURLs(primary.URL, fallback.URL))
+			_, err := client.makeRequest(context.Background(), tt.method, "domain/"+testDomainName+"/zdns", nil)
+			if err == nil || !strings.Contains(err.Error(), tt.contains) {
+				t.Fatalf("Expected an error containing %q, got %v", tt.contains, err)
+			}
+			if fallback.requests() != 0 {
+				t.Errorf("Expected no request to the fallback address, got %d", fallback.requests())
+			}
+		})
+	}
+}
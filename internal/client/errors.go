@@ -0,0 +1,201 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Sentinel errors used to classify failures returned by the client.
+// They can be matched with errors.Is, or through the Is* helpers below.
+var (
+	ErrNotFound    = errors.New("lws: resource not found")
+	ErrConflict    = errors.New("lws: resource conflict")
+	ErrAuth        = errors.New("lws: authentication failed")
+	ErrRateLimited = errors.New("lws: rate limited")
+	ErrChallenge   = errors.New("lws: cloudflare challenge")
+	ErrCircuitOpen = errors.New("lws: circuit breaker open")
+	// ErrRejected is returned when the API refused a request as invalid,
+	// which for a create may also mean that the record already exists.
+	ErrRejected = errors.New("lws: request rejected")
+	// ErrNotConsistent is returned when a change was accepted by the API
+	// but the zone listing did not reflect it in time.
+	ErrNotConsistent = errors.New("lws: change not visible in the zone listing")
+	// ErrAmbiguous is returned when a lookup matches several records and
+	// picking one could return the wrong record.
+	ErrAmbiguous = errors.New("lws: several records match")
+	// ErrNotRecorded is returned by the transports replaying recorded
+	// exchanges, such as HARTransport, for a request they hold no response
+	// for. Sending it again cannot help, so the client neither retries it
+	// nor tries another API address.
+	ErrNotRecorded = errors.New("lws: request not in the replayed recording")
+)
+
+// APIError is returned when the LWS API answers a request with an error,
+// either through the HTTP status or through the "code" field of the
+// response envelope.
+type APIError struct {
+	// StatusCode is the HTTP status of the response.
+	StatusCode int
+	// Code is the LWS "code" field of the response envelope (0 if the body
+	// could not be decoded).
+	Code int
+	// Info is the decoded LWS "info" field, either a string or an object.
+	Info interface{}
+	// Method and Endpoint identify the failing call. Endpoint is relative
+	// to the client base URL, URL is the full address that was requested.
+	Method   string
+	Endpoint string
+	URL      string
+	// RetryAfter is the wait requested by the API through the Retry-After
+	// header, zero when none was sent.
+	RetryAfter time.Duration
+	// Challenge is set when the API answered with a Cloudflare HTML
+	// challenge page instead of a JSON envelope.
+	Challenge bool
+	// Detail replaces the default message when the response could not be
+	// described from the envelope alone (challenge pages, empty bodies).
+	Detail string
+	// RequestID is the X-Request-ID sent with the failing call.
+	RequestID string
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	msg := e.Detail
+	if msg == "" {
+		msg = fmt.Sprintf("API error for %s %s (HTTP %d): Code=%d, Info=%s", e.Method, e.URL, e.StatusCode, e.Code, e.InfoMessage())
+	}
+	if e.RequestID != "" {
+		msg += fmt.Sprintf(" (request ID: %s)", e.RequestID)
+	}
+	return msg
+}
+
+// InfoMessage extracts a readable message from the Info payload
+func (e *APIError) InfoMessage() string {
+	resp := LWSAPIResponse{Code: e.Code, Info: e.Info}
+	return resp.GetInfoMessage()
+}
+
+// Is reports whether the error belongs to the class described by target,
+// which makes errors.Is(err, ErrNotFound) and friends work on API errors.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.hasStatus(http.StatusNotFound)
+	case ErrConflict:
+		// LWS also answers a zone entry that collides with an existing one
+		// with a plain 400, as it does an invalid one: such an error is only
+		// ErrRejected, a conflict once the colliding record is found.
+		return e.hasStatus(http.StatusConflict)
+	case ErrRejected:
+		return e.hasStatus(http.StatusBadRequest)
+	case ErrAuth:
+		return e.hasStatus(http.StatusUnauthorized) || e.hasStatus(http.StatusForbidden)
+	case ErrRateLimited:
+		return e.hasStatus(http.StatusTooManyRequests)
+	case ErrChallenge:
+		return e.Challenge
+	}
+	return false
+}
+
+// hasStatus checks both the HTTP status and the LWS envelope code, since the
+// API sometimes reports errors with an HTTP 200 and an error code in the body.
+func (e *APIError) hasStatus(status int) bool {
+	return e.StatusCode == status || e.Code == status
+}
+
+// notFoundError is returned when a lookup inside a zone listing finds nothing.
+// It is classified as ErrNotFound without changing the message.
+type notFoundError struct {
+	msg string
+}
+
+func (e *notFoundError) Error() string {
+	return e.msg
+}
+
+func (e *notFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+func newNotFoundError(format string, args ...interface{}) error {
+	return &notFoundError{msg: fmt.Sprintf(format, args...)}
+}
+
+// ambiguousError is returned when a lookup inside a zone listing finds
+// several records. It is classified as ErrAmbiguous, never as ErrNotFound,
+// since the record looked for may well exist.
+type ambiguousError struct {
+	msg string
+}
+
+func (e *ambiguousError) Error() string {
+	return e.msg
+}
+
+func (e *ambiguousError) Is(target error) bool {
+	return target == ErrAmbiguous
+}
+
+func newAmbiguousError(format string, args ...interface{}) error {
+	return &ambiguousError{msg: fmt.Sprintf(format, args...)}
+}
+
+// IsNotFound reports whether err means the requested zone or record does not exist.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsConflict reports whether err means the record collides with an existing one.
+func IsConflict(err error) bool {
+	return errors.Is(err, ErrConflict)
+}
+
+// IsRejected reports whether err means the API refused the request as
+// invalid. A rejected create may collide with an existing record, which
+// only a look at the zone tells.
+func IsRejected(err error) bool {
+	return errors.Is(err, ErrRejected)
+}
+
+// IsAuth reports whether err is an authentication or authorization failure.
+func IsAuth(err error) bool {
+	return errors.Is(err, ErrAuth)
+}
+
+// IsRateLimited reports whether err means the API is throttling the client.
+func IsRateLimited(err error) bool {
+	return errors.Is(err, ErrRateLimited)
+}
+
+// IsChallenge reports whether err is a Cloudflare challenge page.
+func IsChallenge(err error) bool {
+	return errors.Is(err, ErrChallenge)
+}
+
+// IsCircuitOpen reports whether err was returned without calling the API
+// because the circuit breaker is open.
+func IsCircuitOpen(err error) bool {
+	return errors.Is(err, ErrCircuitOpen)
+}
+
+// IsNotConsistent reports whether err means an accepted change did not show
+// up in the zone listing before the consistency waiter gave up.
+func IsNotConsistent(err error) bool {
+	return errors.Is(err, ErrNotConsistent)
+}
+
+// IsAmbiguous reports whether err means a lookup matched several records.
+func IsAmbiguous(err error) bool {
+	return errors.Is(err, ErrAmbiguous)
+}
+
+// IsNotRecorded reports whether err was returned by a replay transport for
+// a request missing from the recording.
+func IsNotRecorded(err error) bool {
+	return errors.Is(err, ErrNotRecorded)
+}
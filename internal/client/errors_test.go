@@ -0,0 +1,178 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIError_Classification(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         *APIError
+		notFound    bool
+		conflict    bool
+		rejected    bool
+		auth        bool
+		rateLimited bool
+		challenge   bool
+	}{
+		{
+			name:     "http 404",
+			err:      &APIError{StatusCode: http.StatusNotFound, Method: http.MethodGet},
+			notFound: true,
+		},
+		{
+			name:     "envelope code 404 with http 200",
+			err:      &APIError{StatusCode: http.StatusOK, Code: 404, Method: http.MethodDelete},
+			notFound: true,
+		},
+		{
+			name:     "http 409",
+			err:      &APIError{StatusCode: http.StatusConflict, Method: http.MethodPost},
+			conflict: true,
+		},
+		{
+			name:     "rejected create is not a conflict by itself",
+			err:      &APIError{StatusCode: http.StatusBadRequest, Code: 400, Method: http.MethodPost},
+			rejected: true,
+		},
+		{
+			name:     "rejected update is not a conflict",
+			err:      &APIError{StatusCode: http.StatusBadRequest, Code: 400, Method: http.MethodPut},
+			rejected: true,
+		},
+		{
+			name: "unauthorized",
+			err:  &APIError{StatusCode: http.StatusUnauthorized, Code: 401, Method: http.MethodGet},
+			auth: true,
+		},
+		{
+			name: "forbidden",
+			err:  &APIError{StatusCode: http.StatusForbidden, Method: http.MethodGet},
+			auth: true,
+		},
+		{
+			name:        "too many requests",
+			err:         &APIError{StatusCode: http.StatusTooManyRequests, Method: http.MethodGet},
+			rateLimited: true,
+		},
+		{
+			name:      "challenge page",
+			err:       &APIError{StatusCode: http.StatusServiceUnavailable, Challenge: true, Method: http.MethodGet},
+			challenge: true,
+		},
+		{
+			name: "server error",
+			err:  &APIError{StatusCode: http.StatusInternalServerError, Code: 500, Method: http.MethodGet},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Classifiers must see through wrapping
+			err := fmt.Errorf("wrapped: %w", tt.err)
+
+			if got := IsNotFound(err); got != tt.notFound {
+				t.Errorf("IsNotFound = %v, want %v", got, tt.notFound)
+			}
+			if got := IsConflict(err); got != tt.conflict {
+				t.Errorf("IsConflict = %v, want %v", got, tt.conflict)
+			}
+			if got := IsRejected(err); got != tt.rejected {
+				t.Errorf("IsRejected = %v, want %v", got, tt.rejected)
+			}
+			if got := IsAuth(err); got != tt.auth {
+				t.Errorf("IsAuth = %v, want %v", got, tt.auth)
+			}
+			if got := IsRateLimited(err); got != tt.rateLimited {
+				t.Errorf("IsRateLimited = %v, want %v", got, tt.rateLimited)
+			}
+			if got := IsChallenge(err); got != tt.challenge {
+				t.Errorf("IsChallenge = %v, want %v", got, tt.challenge)
+			}
+		})
+	}
+}
+
+func TestAPIError_FromResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"code": 400, "info": {"name": "Cannot add record to the DNS Zone. Record invalid."}, "data": null}`))
+	}))
+	defer server.Close()
+
+	client := NewLWSClient("testlogin", "testkey", server.URL, true, 30, 0, 0, 0)
+
+	record := &DNSRecord{Name: "www", Type: "A", Value: "192.168.1.1", Zone: testDomainName, TTL: 3600}
+	_, err := client.CreateDNSRecord(context.Background(), record)
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected *APIError, got %T: %v", err, err)
+	}
+
+	if apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", apiErr.StatusCode)
+	}
+	if apiErr.Code != 400 {
+		t.Errorf("Expected code 400, got %d", apiErr.Code)
+	}
+	if apiErr.Method != http.MethodPost {
+		t.Errorf("Expected method POST, got %s", apiErr.Method)
+	}
+	if apiErr.Endpoint != "domain/example.com/zdns" {
+		t.Errorf("Expected endpoint 'domain/example.com/zdns', got '%s'", apiErr.Endpoint)
+	}
+	if apiErr.InfoMessage() != "Cannot add record to the DNS Zone. Record invalid." {
+		t.Errorf("Unexpected info message: %s", apiErr.InfoMessage())
+	}
+	if !IsRejected(err) || IsConflict(err) {
+		t.Errorf("Expected rejected create to be classified as rejected, not as a conflict")
+	}
+}
+
+func TestAPIError_Challenge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`<!DOCTYPE html><html><head><title>Just a moment...</title></head></html>`))
+	}))
+	defer server.Close()
+
+	client := NewLWSClient("testlogin", "testkey", server.URL, true, 30, 0, 0, 0)
+
+	_, err := client.GetDNSZone(context.Background(), testDomainName)
+	if !IsChallenge(err) {
+		t.Fatalf("Expected challenge error, got %v", err)
+	}
+	if IsNotFound(err) {
+		t.Errorf("Challenge must not be classified as not found")
+	}
+}
+
+func TestLWSClient_LookupNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"code": 200, "info": "Fetched DNS Zone", "data": []}`))
+	}))
+	defer server.Close()
+
+	client := NewLWSClient("testlogin", "testkey", server.URL, true, 30, 0, 0, 0)
+
+	_, err := client.GetDNSRecord(context.Background(), testDomainName, "12345")
+	if !IsNotFound(err) {
+		t.Errorf("Expected missing record to be classified as not found, got %v", err)
+	}
+
+	_, err = client.findDNSRecordByName(context.Background(), testDomainName, "www", "A")
+	if !IsNotFound(err) {
+		t.Errorf("Expected missing record to be classified as not found, got %v", err)
+	}
+}
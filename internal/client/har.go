@@ -0,0 +1,492 @@
+package client
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HAR is an HTTP Archive 1.2 document, the format browser devtools import
+// and export. See http://www.softwareishard.com/blog/har-12-spec/.
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog is the root object of a HAR archive.
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+
+	// RunID is the run HARCapture.MergeFile merged the entries for.
+	RunID string `json:"_runId,omitempty"`
+}
+
+// HARCreator names the program that wrote the archive.
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry is one HTTP exchange. Every attempt of an API call, retries and
+// failovers included, is its own entry; the underscore fields are HAR
+// custom fields tying it to the call.
+type HAREntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Comment         string      `json:"comment,omitempty"`
+
+	RequestID string `json:"_requestId,omitempty"`
+	Attempt   int    `json:"_attempt,omitempty"`
+	// Error is the transport error of an exchange that got no response.
+	Error string `json:"_error,omitempty"`
+}
+
+// HARRequest is the request of an entry.
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARResponse is the response of an entry. Its status is 0 when the
+// exchange failed before a response was received.
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARNameValue is a header, cookie or query string parameter.
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData is the body of a request.
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// HARContent is the body of a response.
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+}
+
+// HARTimings splits the time of an entry in milliseconds. Phases that did
+// not happen, such as DNS resolution on a reused connection, are -1.
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// HARCaptureOptions configures a HARCapture.
+type HARCaptureOptions struct {
+	// Creator and Version name the program in the archive. Creator
+	// defaults to "terraform-provider-lws".
+	Creator string
+	Version string
+	// RedactValues masks the DNS record values of JSON bodies, on top of
+	// the authentication headers and the redaction patterns of the client,
+	// which are always masked.
+	RedactValues bool
+}
+
+// HARCapture records the HTTP exchanges of one or more clients as a HAR
+// archive, for support tickets or as a replay fixture, see HARTransport.
+// Bodies are kept as sent and received, Cloudflare challenge pages
+// included. A nil *HARCapture records nothing.
+type HARCapture struct {
+	opts HARCaptureOptions
+
+	mu      sync.Mutex
+	entries []HAREntry
+}
+
+// NewHARCapture returns an empty capture.
+func NewHARCapture(opts HARCaptureOptions) *HARCapture {
+	if opts.Creator == "" {
+		opts.Creator = DefaultUserAgent
+	}
+	return &HARCapture{opts: opts}
+}
+
+// HAR returns the archive of the exchanges recorded so far, in the order
+// they started.
+func (h *HARCapture) HAR() *HAR {
+	har := &HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: h.opts.Creator, Version: h.opts.Version},
+		Entries: []HAREntry{},
+	}}
+	h.mu.Lock()
+	har.Log.Entries = append(har.Log.Entries, h.entries...)
+	h.mu.Unlock()
+
+	sort.SliceStable(har.Log.Entries, func(i, j int) bool {
+		return har.Log.Entries[i].StartedDateTime.Before(har.Log.Entries[j].StartedDateTime)
+	})
+	return har
+}
+
+// WriteFile writes the archive to path, replacing it atomically.
+func (h *HARCapture) WriteFile(path string) error {
+	return writeHARFile(path, h.HAR())
+}
+
+// MergeFile adds the exchanges recorded so far to the archive merged into
+// path for the same run, keeping the entries in the order they started. It
+// writes a new archive, tagged with run, when there is none or when it
+// belongs to another run, so that the archive only covers the provider
+// processes of one Terraform run. It holds a lock meanwhile, so that those
+// processes can exit concurrently.
+func (h *HARCapture) MergeFile(path, run string) error {
+	unlock, err := lockFile(path)
+	if err != nil {
+		return fmt.Errorf("error locking HTTP capture %s: %w", path, err)
+	}
+	defer unlock()
+
+	har := h.HAR()
+	har.Log.RunID = run
+	existing, err := LoadHAR(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return err
+	case existing.Log.RunID == run:
+		har.Log.Entries = append(existing.Log.Entries, har.Log.Entries...)
+		sort.SliceStable(har.Log.Entries, func(i, j int) bool {
+			return har.Log.Entries[i].StartedDateTime.Before(har.Log.Entries[j].StartedDateTime)
+		})
+	}
+	return writeHARFile(path, har)
+}
+
+func writeHARFile(path string, har *HAR) error {
+	err := writeFileAtomic(path, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(har)
+	})
+	if err != nil {
+		return fmt.Errorf("error writing HTTP capture %s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadHAR reads a HAR archive, such as one written by HARCapture.WriteFile.
+func LoadHAR(path string) (*HAR, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- the path is chosen by the user
+	if err != nil {
+		return nil, fmt.Errorf("error reading HAR file: %w", err)
+	}
+	var har HAR
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("error decoding HAR file %s: %w", path, err)
+	}
+	return &har, nil
+}
+
+// harExchange follows an HTTP exchange of the client until it is recorded.
+type harExchange struct {
+	capture *HARCapture
+	start   time.Time
+
+	// Set by the httptrace hooks, which may run on other goroutines
+	mu           sync.Mutex
+	getConn      time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	gotConn      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	remoteAddr   string
+}
+
+// startExchange prepares the recording of req, returning the request to
+// send instead, which reports the connection timings. It returns req
+// unchanged and a nil exchange when nothing is captured.
+func (h *HARCapture) startExchange(req *http.Request) (*http.Request, *harExchange) {
+	if h == nil {
+		return req, nil
+	}
+
+	x := &harExchange{capture: h, start: time.Now()}
+	stamp := func(t *time.Time) {
+		x.mu.Lock()
+		defer x.mu.Unlock()
+		*t = time.Now()
+	}
+	trace := &httptrace.ClientTrace{
+		GetConn:              func(string) { stamp(&x.getConn) },
+		DNSStart:             func(httptrace.DNSStartInfo) { stamp(&x.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { stamp(&x.dnsDone) },
+		ConnectStart:         func(string, string) { stamp(&x.connectStart) },
+		ConnectDone:          func(string, string, error) { stamp(&x.connectDone) },
+		TLSHandshakeStart:    func() { stamp(&x.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { stamp(&x.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { stamp(&x.wroteRequest) },
+		GotFirstResponseByte: func() { stamp(&x.firstByte) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			stamp(&x.gotConn)
+			if info.Conn != nil {
+				x.mu.Lock()
+				x.remoteAddr = info.Conn.RemoteAddr().String()
+				x.mu.Unlock()
+			}
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), x
+}
+
+// phase returns the milliseconds between from and to, -1 when either did
+// not happen.
+func phase(from, to time.Time) float64 {
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return -1
+	}
+	return float64(to.Sub(from).Microseconds()) / 1000
+}
+
+// record adds the exchange to the capture once it is over. resp is nil and
+// transportErr set when no response was received.
+func (x *harExchange) record(c *LWSClient, req *http.Request, attempt int, resp *http.Response, respBody []byte, transportErr error) {
+	if x == nil {
+		return
+	}
+	end := time.Now()
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	entry := HAREntry{
+		StartedDateTime: x.start,
+		Time:            float64(end.Sub(x.start).Microseconds()) / 1000,
+		Request:         x.capture.request(c.redactor, req),
+		Response:        HARResponse{Cookies: []HARNameValue{}, Headers: []HARNameValue{}, HeadersSize: -1, BodySize: -1},
+		RequestID:       RequestIDFromContext(req.Context()),
+		Attempt:         attempt,
+		Comment:         fmt.Sprintf("attempt %d", attempt),
+	}
+	if host, _, err := net.SplitHostPort(x.remoteAddr); err == nil {
+		entry.ServerIPAddress = host
+	}
+
+	// In HAR, connect includes the TLS handshake and blocked is the wait
+	// for a connection without its setup
+	connectDone := x.connectDone
+	if x.tlsDone.After(connectDone) {
+		connectDone = x.tlsDone
+	}
+	entry.Timings = HARTimings{
+		Blocked: phase(x.getConn, x.gotConn),
+		DNS:     phase(x.dnsStart, x.dnsDone),
+		Connect: phase(x.connectStart, connectDone),
+		SSL:     phase(x.tlsStart, x.tlsDone),
+		Send:    max(phase(x.gotConn, x.wroteRequest), 0),
+		Wait:    max(phase(x.wroteRequest, x.firstByte), 0),
+		Receive: max(phase(x.firstByte, end), 0),
+	}
+	if entry.Timings.Blocked >= 0 {
+		entry.Timings.Blocked = max(entry.Timings.Blocked-max(entry.Timings.DNS, 0)-max(entry.Timings.Connect, 0), 0)
+	}
+
+	if transportErr != nil {
+		entry.Error = c.redactor.String(transportErr.Error())
+	}
+	if resp != nil {
+		entry.Response = x.capture.response(c.redactor, resp, respBody)
+	}
+
+	x.capture.mu.Lock()
+	x.capture.entries = append(x.capture.entries, entry)
+	x.capture.mu.Unlock()
+}
+
+func (h *HARCapture) request(redactor *Redactor, req *http.Request) HARRequest {
+	harReq := HARRequest{
+		Method:      req.Method,
+		URL:         redactor.String(req.URL.String()),
+		HTTPVersion: req.Proto,
+		Cookies:     []HARNameValue{},
+		Headers:     harHeaders(redactor, req.Header),
+		QueryString: []HARNameValue{},
+		HeadersSize: -1,
+		BodySize:    0,
+	}
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			harReq.QueryString = append(harReq.QueryString, HARNameValue{Name: name, Value: redactor.String(value)})
+		}
+	}
+	sort.Slice(harReq.QueryString, func(i, j int) bool { return harReq.QueryString[i].Name < harReq.QueryString[j].Name })
+
+	if req.GetBody != nil && req.ContentLength != 0 {
+		if body, err := req.GetBody(); err == nil {
+			data, _ := io.ReadAll(body)
+			_ = body.Close()
+			harReq.BodySize = len(data)
+			harReq.PostData = &HARPostData{MimeType: req.Header.Get("Content-Type"), Text: h.body(redactor, data)}
+		}
+	}
+	return harReq
+}
+
+func (h *HARCapture) response(redactor *Redactor, resp *http.Response, body []byte) HARResponse {
+	mimeType := resp.Header.Get("Content-Type")
+	if mimeType == "" {
+		mimeType = http.DetectContentType(body)
+	}
+	return HARResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Cookies:     []HARNameValue{},
+		Headers:     harHeaders(redactor, resp.Header),
+		Content: HARContent{
+			Size:     len(body),
+			MimeType: mimeType,
+			Text:     h.body(redactor, body),
+		},
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+}
+
+// body renders a body for the archive. Only JSON bodies are rewritten when
+// record values are masked, so challenge pages are kept as received.
+func (h *HARCapture) body(redactor *Redactor, body []byte) string {
+	if h.opts.RedactValues && json.Valid(body) {
+		return redactor.Body(body)
+	}
+	return redactor.String(string(body))
+}
+
+// harHeaders lists headers in name order with credentials masked.
+func harHeaders(redactor *Redactor, header http.Header) []HARNameValue {
+	rendered := redactor.Headers(header)
+	names := make([]string, 0, len(rendered))
+	for name := range rendered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	headers := make([]HARNameValue, 0, len(names))
+	for _, name := range names {
+		headers = append(headers, HARNameValue{Name: name, Value: rendered[name]})
+	}
+	return headers
+}
+
+// HARTransport replays the responses of a HAR archive, so a capture can be
+// used as a test fixture. Each request gets the response of the first
+// unused entry with the same method, path and query; the host is ignored,
+// so the archive can be replayed against any base URL. Entries recorded
+// without a response replay their transport error.
+type HARTransport struct {
+	mu      sync.Mutex
+	entries []HAREntry
+	used    []bool
+}
+
+// NewHARTransport returns a transport replaying the entries of har.
+func NewHARTransport(har *HAR) *HARTransport {
+	return &HARTransport{entries: har.Log.Entries, used: make([]bool, len(har.Log.Entries))}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *HARTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i, entry := range t.entries {
+		if t.used[i] || entry.Request.Method != req.Method {
+			continue
+		}
+		recorded, err := url.Parse(entry.Request.URL)
+		if err != nil || recorded.Path != req.URL.Path || recorded.RawQuery != req.URL.RawQuery {
+			continue
+		}
+		t.used[i] = true
+
+		if entry.Response.Status == 0 {
+			return nil, fmt.Errorf("replayed transport error: %s", entry.Error)
+		}
+		header := make(http.Header, len(entry.Response.Headers))
+		for _, h := range entry.Response.Headers {
+			header.Add(h.Name, h.Value)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", entry.Response.Status, entry.Response.StatusText),
+			StatusCode:    entry.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewBufferString(entry.Response.Content.Text)),
+			ContentLength: int64(len(entry.Response.Content.Text)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("no HAR entry left for %s %s: %w", req.Method, req.URL.RequestURI(), ErrNotRecorded)
+}
+
+// Unused returns the entries that were never replayed, as "METHOD URL".
+func (t *HARTransport) Unused() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var unused []string
+	for i, entry := range t.entries {
+		if !t.used[i] {
+			unused = append(unused, entry.Request.Method+" "+entry.Request.URL)
+		}
+	}
+	return unused
+}
@@ -0,0 +1,238 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// challengedZoneServer fails the first zone listing with a 502, then with a
+// challenge page, before answering.
+func challengedZoneServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		call := calls
+		mu.Unlock()
+
+		switch call {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte(`{"code": 502, "info": "Bad gateway", "data": null}`))
+		case 2:
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(challengePage))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"code": 200, "info": "Fetched DNS Zone", "data": [{"id": 1, "name": "_acme-challenge", "type": "TXT", "value": "secret-token", "ttl": 60}]}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHARCapture(t *testing.T) {
+	tests := []struct {
+		name         string
+		redactValues bool
+	}{
+		{name: "values_kept"},
+		{name: "values_redacted", redactValues: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := challengedZoneServer(t)
+			capture := NewHARCapture(HARCaptureOptions{Version: "1.2.3", RedactValues: tt.redactValues})
+			client := newTestClient(t, server.URL,
+				WithRetryPolicy(&DefaultRetryPolicy{Retries: 2, Multiplier: 1}),
+				WithHTTPCapture(capture),
+			)
+			if _, err := client.GetDNSZone(WithRequestID(context.Background(), "req-1"), testDomainName); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			har := capture.HAR()
+			if har.Log.Version != "1.2" || har.Log.Creator != (HARCreator{Name: DefaultUserAgent, Version: "1.2.3"}) {
+				t.Errorf("Unexpected archive header %+v", har.Log)
+			}
+			entries := har.Log.Entries
+			if len(entries) != 3 {
+				t.Fatalf("Expected an entry per attempt, got %d", len(entries))
+			}
+			for i, entry := range entries {
+				if entry.Attempt != i+1 || entry.RequestID != "req-1" {
+					t.Errorf("Expected attempt %d of req-1, got attempt %d of %q", i+1, entry.Attempt, entry.RequestID)
+				}
+				if entry.Request.Method != http.MethodGet || entry.Request.URL != server.URL+"/domain/"+testDomainName+"/zdns" {
+					t.Errorf("Unexpected request %s %s", entry.Request.Method, entry.Request.URL)
+				}
+				if entry.Time <= 0 || entry.Timings.Send < 0 || entry.Timings.Wait < 0 || entry.Timings.Receive < 0 {
+					t.Errorf("Expected timings for attempt %d, got %v %+v", i+1, entry.Time, entry.Timings)
+				}
+				if entry.ServerIPAddress != "127.0.0.1" {
+					t.Errorf("Expected the server address, got %q", entry.ServerIPAddress)
+				}
+				for _, header := range entry.Request.Headers {
+					if strings.HasPrefix(header.Name, "X-Auth-") && header.Value != redactedValue {
+						t.Errorf("Expected %s to be redacted, got %q", header.Name, header.Value)
+					}
+				}
+			}
+
+			if entries[0].Response.Status != 502 || entries[1].Response.Status != 403 || entries[2].Response.Status != 200 {
+				t.Errorf("Unexpected statuses %d, %d, %d", entries[0].Response.Status, entries[1].Response.Status, entries[2].Response.Status)
+			}
+			if content := entries[1].Response.Content; content.Text != challengePage || content.MimeType != "text/html" {
+				t.Errorf("Expected the challenge page to be kept, got %+v", content)
+			}
+			if got := strings.Contains(entries[2].Response.Content.Text, "secret-token"); got == tt.redactValues {
+				t.Errorf("Expected the record value to be kept: %t, got %s", !tt.redactValues, entries[2].Response.Content.Text)
+			}
+		})
+	}
+}
+
+func TestHARCapture_MergeFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.har")
+
+	// Each provider process of a run captures its own exchanges
+	for _, requestID := range []string{"req-1", "req-2"} {
+		capture := NewHARCapture(HARCaptureOptions{Version: "1.2.3"})
+		client := newTestClient(t, challengedZoneServer(t).URL,
+			WithRetryPolicy(&DefaultRetryPolicy{Retries: 2, Multiplier: 1}),
+			WithHTTPCapture(capture),
+		)
+		if _, err := client.GetDNSZone(WithRequestID(context.Background(), requestID), testDomainName); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := capture.MergeFile(path, "run-1"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	har, err := LoadHAR(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	entries := har.Log.Entries
+	if len(entries) != 6 {
+		t.Fatalf("Expected the entries of both captures, got %d", len(entries))
+	}
+	for i, entry := range entries {
+		want := "req-1"
+		if i >= 3 {
+			want = "req-2"
+		}
+		if entry.RequestID != want || entry.Attempt != i%3+1 {
+			t.Errorf("Expected attempt %d of %s at %d, got attempt %d of %s", i%3+1, want, i, entry.Attempt, entry.RequestID)
+		}
+	}
+
+	entriesLeft, _ := os.ReadDir(filepath.Dir(path))
+	if len(entriesLeft) != 2 {
+		t.Errorf("Expected no temporary file left behind, got %v", entriesLeft)
+	}
+
+	// Another run replaces the archive
+	capture := NewHARCapture(HARCaptureOptions{Version: "1.2.3"})
+	client := newTestClient(t, challengedZoneServer(t).URL, WithHTTPCapture(capture))
+	if _, err := client.GetDNSZone(context.Background(), testDomainName); err == nil {
+		t.Fatalf("Expected the bad gateway to fail the call without retries")
+	}
+	if err := capture.MergeFile(path, "run-2"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	har, err = LoadHAR(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if har.Log.RunID != "run-2" || len(har.Log.Entries) != 1 {
+		t.Errorf("Expected only the entries of run-2, got %d entries of %q", len(har.Log.Entries), har.Log.RunID)
+	}
+
+	if err := os.WriteFile(path, []byte("not a HAR"), 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := NewHARCapture(HARCaptureOptions{}).MergeFile(path, "run-1"); err == nil {
+		t.Errorf("Expected an error merging into a file that holds no archive")
+	}
+}
+
+func TestHARCapture_TransportError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	capture := NewHARCapture(HARCaptureOptions{})
+	client := newTestClient(t, server.URL, WithHTTPCapture(capture))
+	if _, err := client.GetDNSZone(context.Background(), testDomainName); err == nil {
+		t.Fatalf("Expected an error from a closed server")
+	}
+
+	entries := capture.HAR().Log.Entries
+	if len(entries) != 1 || entries[0].Response.Status != 0 || !strings.Contains(entries[0].Error, "connection refused") {
+		t.Errorf("Expected an entry without response carrying the error, got %+v", entries)
+	}
+}
+
+func TestHARTransport(t *testing.T) {
+	server := challengedZoneServer(t)
+	capture := NewHARCapture(HARCaptureOptions{})
+	recorder := newTestClient(t, server.URL,
+		WithRetryPolicy(&DefaultRetryPolicy{Retries: 2, Multiplier: 1}),
+		WithHTTPCapture(capture),
+	)
+	if _, err := recorder.GetDNSZone(context.Background(), testDomainName); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "run.har")
+	if err := capture.WriteFile(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(string(data), `"version": "1.2"`) {
+		t.Errorf("Expected a HAR 1.2 archive, got %s", data)
+	}
+
+	har, err := LoadHAR(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	transport := NewHARTransport(har)
+	replay := newTestClient(t, "https://replay.invalid",
+		WithRetryPolicy(&DefaultRetryPolicy{Retries: 2, Multiplier: 1}),
+		WithTransport(transport),
+	)
+
+	zone, err := replay.GetDNSZone(context.Background(), testDomainName)
+	if err != nil {
+		t.Fatalf("Unexpected replay error: %v", err)
+	}
+	if len(zone.Records) != 1 || zone.Records[0].Value != "secret-token" {
+		t.Errorf("Expected the recorded listing, got %+v", zone.Records)
+	}
+	if unused := transport.Unused(); len(unused) != 0 {
+		t.Errorf("Expected every entry to be replayed, got %v", unused)
+	}
+
+	_, err = replay.GetDNSZone(context.Background(), "example.org")
+	if !IsNotRecorded(err) || !strings.Contains(err.Error(), "no HAR entry left for GET /domain/example.org/zdns") {
+		t.Errorf("Expected an unmatched request error, got %v", err)
+	}
+}
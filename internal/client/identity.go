@@ -0,0 +1,98 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/M4XGO/terraform-provider-lws/internal/client/rdata"
+)
+
+// RecordIdentity identifies a record by its zone, name, type and value
+// rather than by its LWS numeric ID, which LWS may reassign. Records sharing
+// a name and type, such as several TXT records on the apex, have different
+// identities.
+type RecordIdentity struct {
+	Zone string
+	Name string
+	Type string
+	// ValueHash is a hash of the canonical value, see ValueHash.
+	ValueHash string
+}
+
+// IdentityOf returns the identity of a record. Zone, name and type are
+// normalised so that spellings LWS treats as equal have the same identity.
+func IdentityOf(record DNSRecord) RecordIdentity {
+	return RecordIdentity{
+		Zone:      normalizeName(strings.ToLower(record.Zone)),
+		Name:      normalizeName(strings.ToLower(record.Name)),
+		Type:      strings.ToUpper(strings.TrimSpace(record.Type)),
+		ValueHash: ValueHash(record.Type, record.Value),
+	}
+}
+
+// ParseRecordIdentity parses the "zone/name/type/hash" form returned by
+// RecordIdentity.String. The name may itself hold slashes, such as the
+// "0/26" of RFC 2317 classless delegations, which neither a domain, a type
+// nor a hash can.
+func ParseRecordIdentity(id string) (RecordIdentity, error) {
+	parts := strings.Split(id, "/")
+	if len(parts) < 4 {
+		return RecordIdentity{}, fmt.Errorf("record ID %q is not of the form zone/name/type/value-hash", id)
+	}
+	for _, part := range parts {
+		if part == "" {
+			return RecordIdentity{}, fmt.Errorf("record ID %q has an empty part", id)
+		}
+	}
+
+	last := len(parts) - 1
+	return RecordIdentity{
+		Zone:      normalizeName(strings.ToLower(parts[0])),
+		Name:      normalizeName(strings.ToLower(strings.Join(parts[1:last-1], "/"))),
+		Type:      strings.ToUpper(parts[last-1]),
+		ValueHash: strings.ToLower(parts[last]),
+	}, nil
+}
+
+// String returns the identity as "zone/name/type/hash".
+func (id RecordIdentity) String() string {
+	return strings.Join([]string{id.Zone, id.Name, id.Type, id.ValueHash}, "/")
+}
+
+// Matches reports whether record has this identity. The zone of record is
+// ignored, since zone listings do not carry it.
+func (id RecordIdentity) Matches(record DNSRecord) bool {
+	other := IdentityOf(record)
+	return other.Name == id.Name && other.Type == id.Type && other.ValueHash == id.ValueHash
+}
+
+// FindRecord returns a copy of the record of records with the given
+// identity, with its zone set, or nil.
+func FindRecord(records []DNSRecord, id RecordIdentity) *DNSRecord {
+	for _, record := range records {
+		if id.Matches(record) {
+			record.Zone = id.Zone
+			return &record
+		}
+	}
+	return nil
+}
+
+// ValueHash returns the first 16 hex digits of the SHA-256 of the canonical
+// form of a record value, see rdata.Canonical.
+func ValueHash(recordType, value string) string {
+	sum := sha256.Sum256([]byte(rdata.Canonical(recordType, value)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// SingleValued reports whether a name holds at most one record of the type,
+// in which case a record with another value is replaced rather than added.
+func SingleValued(recordType string) bool {
+	switch strings.ToUpper(strings.TrimSpace(recordType)) {
+	case "CNAME", "SOA":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"testing"
+)
+
+func TestIdentityOf(t *testing.T) {
+	base := DNSRecord{Zone: "example.com", Name: "www", Type: "A", Value: "192.168.1.1"}
+
+	tests := []struct {
+		name  string
+		other DNSRecord
+		same  bool
+	}{
+		{
+			name:  "identical",
+			other: base,
+			same:  true,
+		},
+		{
+			name:  "spelling",
+			other: DNSRecord{Zone: "Example.COM.", Name: " WWW. ", Type: "a", Value: "192.168.1.1 "},
+			same:  true,
+		},
+		{
+			name:  "other_value",
+			other: DNSRecord{Zone: "example.com", Name: "www", Type: "A", Value: "192.168.1.2"},
+		},
+		{
+			name:  "other_type",
+			other: DNSRecord{Zone: "example.com", Name: "www", Type: "AAAA", Value: "192.168.1.1"},
+		},
+		{
+			name:  "other_zone",
+			other: DNSRecord{Zone: "example.org", Name: "www", Type: "A", Value: "192.168.1.1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IdentityOf(tt.other) == IdentityOf(base); got != tt.same {
+				t.Errorf("Expected same identity %t, got %t (%s vs %s)", tt.same, got, IdentityOf(tt.other), IdentityOf(base))
+			}
+		})
+	}
+}
+
+func TestValueHash(t *testing.T) {
+	tests := []struct {
+		name       string
+		recordType string
+		a, b       string
+		same       bool
+	}{
+		{"hostname_case_and_dot", "CNAME", "www.example.com", "WWW.Example.com.", true},
+		{"txt_quotes", "TXT", "v=spf1 -all", `"v=spf1 -all"`, true},
+		{"txt_case", "TXT", "token=ABC", "token=abc", false},
+		{"mx_priority", "MX", "10 mail.example.com", "20 mail.example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValueHash(tt.recordType, tt.a) == ValueHash(tt.recordType, tt.b); got != tt.same {
+				t.Errorf("Expected same hash %t for %q and %q, got %t", tt.same, tt.a, tt.b, got)
+			}
+		})
+	}
+}
+
+func TestParseRecordIdentity(t *testing.T) {
+	id := IdentityOf(DNSRecord{Zone: "example.com", Name: "@", Type: "TXT", Value: "v=spf1 -all"})
+
+	parsed, err := ParseRecordIdentity(id.String())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if parsed != id {
+		t.Errorf("Expected %s, got %s", id, parsed)
+	}
+
+	// RFC 2317 delegations put slashes in names
+	classless := IdentityOf(DNSRecord{Zone: "2.0.192.in-addr.arpa", Name: "0/26", Type: "NS", Value: "ns1.example.com."})
+	parsed, err = ParseRecordIdentity(classless.String())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if parsed != classless || parsed.Name != "0/26" {
+		t.Errorf("Expected %s, got %+v", classless, parsed)
+	}
+
+	for _, invalid := range []string{"12345", "example.com:12345", "example.com/www/A", "example.com//A/abc", "example.com/0//26/A/abc"} {
+		if _, err := ParseRecordIdentity(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestFindRecord(t *testing.T) {
+	records := []DNSRecord{
+		{ID: 1, Name: "@", Type: "TXT", Value: "v=spf1 -all"},
+		{ID: 2, Name: "@", Type: "TXT", Value: "google-site-verification=abc"},
+		{ID: 3, Name: "www", Type: "A", Value: "192.168.1.1"},
+	}
+
+	id := IdentityOf(DNSRecord{Zone: "example.com", Name: "@", Type: "TXT", Value: "google-site-verification=abc"})
+	found := FindRecord(records, id)
+	if found == nil || found.ID != 2 || found.Zone != "example.com" {
+		t.Fatalf("Expected record 2 of example.com, got %+v", found)
+	}
+
+	missing := IdentityOf(DNSRecord{Zone: "example.com", Name: "@", Type: "TXT", Value: "v=DMARC1; p=none"})
+	if found := FindRecord(records, missing); found != nil {
+		t.Errorf("Expected no record, got %+v", found)
+	}
+}
+
+func TestSingleValued(t *testing.T) {
+	for recordType, want := range map[string]bool{"CNAME": true, "cname": true, "SOA": true, "A": false, "TXT": false, "MX": false} {
+		if got := SingleValued(recordType); got != want {
+			t.Errorf("SingleValued(%q) = %t, want %t", recordType, got, want)
+		}
+	}
+}
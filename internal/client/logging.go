@@ -0,0 +1,236 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// LogSubsystem is the tflog subsystem the client logs to. Its level can be
+// set on its own with the TF_LOG_PROVIDER_LWS_API environment variable.
+const LogSubsystem = "lws_api"
+
+// redactedValue replaces masked values in the logs.
+const redactedValue = "[REDACTED]"
+
+// LogLevel is the severity of a client log entry.
+type LogLevel int
+
+const (
+	LogTrace LogLevel = iota
+	LogDebug
+	LogInfo
+	LogWarn
+	LogError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogTrace:
+		return "TRACE"
+	case LogDebug:
+		return "DEBUG"
+	case LogInfo:
+		return "INFO"
+	case LogWarn:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
+
+// Logger receives the structured client output. Fields have already been
+// redacted when Log is called.
+type Logger interface {
+	Log(ctx context.Context, level LogLevel, msg string, fields map[string]interface{})
+}
+
+// TFLogger logs to the tflog "lws_api" subsystem carried by the context, so
+// client entries are correlated with the provider ones. It is the default.
+type TFLogger struct{}
+
+// tflogSubsystemKey marks contexts already carrying the client subsystem.
+type tflogSubsystemKey struct{}
+
+// Log implements Logger
+func (TFLogger) Log(ctx context.Context, level LogLevel, msg string, fields map[string]interface{}) {
+	switch level {
+	case LogTrace:
+		tflog.SubsystemTrace(ctx, LogSubsystem, msg, fields)
+	case LogDebug:
+		tflog.SubsystemDebug(ctx, LogSubsystem, msg, fields)
+	case LogInfo:
+		tflog.SubsystemInfo(ctx, LogSubsystem, msg, fields)
+	case LogWarn:
+		tflog.SubsystemWarn(ctx, LogSubsystem, msg, fields)
+	default:
+		tflog.SubsystemError(ctx, LogSubsystem, msg, fields)
+	}
+}
+
+// withLogSubsystem returns ctx with the client tflog subsystem, creating it
+// only once per operation.
+func withLogSubsystem(ctx context.Context) context.Context {
+	if ctx.Value(tflogSubsystemKey{}) != nil {
+		return ctx
+	}
+
+	ctx = tflog.NewSubsystem(ctx, LogSubsystem,
+		tflog.WithLevelFromEnv("TF_LOG_PROVIDER_LWS_API"),
+		// Skip Log and LWSClient.log so entries point at the caller.
+		tflog.WithAdditionalLocationOffset(2),
+	)
+	return context.WithValue(ctx, tflogSubsystemKey{}, true)
+}
+
+// StdLogger adapts a standard library logger, for programs embedding the
+// client outside Terraform. Entries below MinLevel are dropped.
+type StdLogger struct {
+	Logger   *log.Logger
+	MinLevel LogLevel
+}
+
+// Log implements Logger
+func (l StdLogger) Log(ctx context.Context, level LogLevel, msg string, fields map[string]interface{}) {
+	if level < l.MinLevel {
+		return
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s", level, msg)
+	for _, key := range keys {
+		fmt.Fprintf(&b, " %s=%v", key, fields[key])
+	}
+
+	logger := l.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	logger.Print(b.String())
+}
+
+// Redactor masks secrets before they reach the logs: authentication headers,
+// DNS record values (TXT records carry DKIM keys and ACME tokens) and any
+// text matching the configured patterns.
+type Redactor struct {
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor compiles the extra patterns whose matches are masked in every
+// logged string.
+func NewRedactor(patterns ...string) (*Redactor, error) {
+	r := &Redactor{}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// String masks the configured patterns in s.
+func (r *Redactor) String(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, redactedValue)
+	}
+	return s
+}
+
+// Value masks a DNS record value.
+func (r *Redactor) Value(value string) string {
+	return RedactValue(value)
+}
+
+// RedactValue masks a DNS record value, so callers outside the client log
+// record values the way the client does.
+func RedactValue(value string) string {
+	if value == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// Headers renders headers for the logs with credentials masked.
+func (r *Redactor) Headers(header http.Header) map[string]string {
+	rendered := make(map[string]string, len(header))
+	for name, values := range header {
+		canonical := http.CanonicalHeaderKey(name)
+		if IsSecretHeader(canonical) {
+			rendered[canonical] = redactedValue
+			continue
+		}
+		rendered[canonical] = r.String(strings.Join(values, ", "))
+	}
+	return rendered
+}
+
+// Body renders a request or response body for the logs. Record values found
+// in JSON bodies are masked, then the configured patterns are applied.
+func (r *Redactor) Body(body []byte) string {
+	var decoded interface{}
+	if err := json.Unmarshal(body, &decoded); err == nil {
+		if masked, err := json.Marshal(redactJSON(decoded)); err == nil {
+			return r.String(string(masked))
+		}
+	}
+	return r.String(string(body))
+}
+
+// redactJSON masks the "value" members of a decoded JSON document.
+func redactJSON(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for key, member := range t {
+			if s, ok := member.(string); ok && strings.EqualFold(key, "value") && s != "" {
+				t[key] = redactedValue
+				continue
+			}
+			t[key] = redactJSON(member)
+		}
+	case []interface{}:
+		for i := range t {
+			t[i] = redactJSON(t[i])
+		}
+	}
+	return v
+}
+
+// IsSecretHeader reports whether a header carries credentials, which the
+// logs, captures and cassettes never hold.
+func IsSecretHeader(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+	return strings.HasPrefix(name, "X-Auth-") || name == "Authorization" || name == "Cookie" || name == "Set-Cookie"
+}
+
+// log sends an entry to the client logger with the request ID of the
+// operation and the configured patterns masked in the message and string
+// fields.
+func (c *LWSClient) log(ctx context.Context, level LogLevel, msg string, fields map[string]interface{}) {
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		if fields == nil {
+			fields = make(map[string]interface{})
+		}
+		fields["request_id"] = requestID
+	}
+	for key, value := range fields {
+		if s, ok := value.(string); ok {
+			fields[key] = c.redactor.String(s)
+		}
+	}
+	c.logger.Log(ctx, level, c.redactor.String(msg), fields)
+}
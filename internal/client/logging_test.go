@@ -0,0 +1,175 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+)
+
+func TestRedactor_Body(t *testing.T) {
+	redactor, err := NewRedactor(`acme-[a-z0-9]+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		body        string
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:        "record_value",
+			body:        `{"type": "TXT", "name": "_dmarc", "value": "v=DKIM1; k=rsa; p=MIIBIjANBg", "ttl": 3600}`,
+			contains:    []string{`"name":"_dmarc"`, `"value":"[REDACTED]"`},
+			notContains: []string{"MIIBIjANBg"},
+		},
+		{
+			name:        "zone_listing",
+			body:        `{"code": 200, "info": "Fetched DNS Zone", "data": [{"id": 1, "value": "192.168.1.1"}]}`,
+			contains:    []string{`"info":"Fetched DNS Zone"`},
+			notContains: []string{"192.168.1.1"},
+		},
+		{
+			name:        "configured_pattern",
+			body:        `not json: token acme-abc123`,
+			contains:    []string{"token [REDACTED]"},
+			notContains: []string{"acme-abc123"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered := redactor.Body([]byte(tt.body))
+			for _, s := range tt.contains {
+				if !strings.Contains(rendered, s) {
+					t.Errorf("Expected %q in %s", s, rendered)
+				}
+			}
+			for _, s := range tt.notContains {
+				if strings.Contains(rendered, s) {
+					t.Errorf("Expected %q to be redacted from %s", s, rendered)
+				}
+			}
+		})
+	}
+}
+
+func TestRedactor_Headers(t *testing.T) {
+	redactor, _ := NewRedactor()
+
+	header := http.Header{}
+	header.Set("X-Auth-Login", "testlogin")
+	header.Set("X-Auth-Pass", "testkey")
+	header.Set("Content-Type", "application/json")
+
+	rendered := redactor.Headers(header)
+	if rendered["X-Auth-Login"] != redactedValue || rendered["X-Auth-Pass"] != redactedValue {
+		t.Errorf("Expected X-Auth-* headers to be redacted, got %v", rendered)
+	}
+	if rendered["Content-Type"] != "application/json" {
+		t.Errorf("Expected Content-Type to be kept, got %q", rendered["Content-Type"])
+	}
+}
+
+func TestIsSecretHeader(t *testing.T) {
+	for name, want := range map[string]bool{
+		"X-Auth-Pass": true, "x-auth-login": true, "authorization": true, "Set-Cookie": true,
+		"Content-Type": false, "X-Request-Id": false,
+	} {
+		if got := IsSecretHeader(name); got != want {
+			t.Errorf("IsSecretHeader(%q) = %t, want %t", name, got, want)
+		}
+	}
+}
+
+func TestNewRedactor_InvalidPattern(t *testing.T) {
+	if _, err := NewRedactor(`(`); err == nil {
+		t.Errorf("Expected an error for an invalid pattern")
+	}
+}
+
+func TestLWSClient_StructuredLogging(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code": 200, "info": "Record created", "data": {"id": 42, "name": "_acme-challenge", "type": "TXT", "value": "secret-token", "ttl": 300}}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("TF_LOG_PROVIDER_LWS_API", "TRACE")
+
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+
+	client := newTestClient(t, server.URL)
+	_, err := client.UpdateDNSRecord(ctx, &DNSRecord{ID: 42, Zone: testDomainName, Name: "_acme-challenge", Type: "TXT", Value: "secret-token", TTL: 300})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	entries, err := tflogtest.MultilineJSONDecode(&output)
+	if err != nil {
+		t.Fatalf("Unable to decode log output: %v", err)
+	}
+
+	if strings.Contains(output.String(), "secret-token") || strings.Contains(output.String(), "testkey") {
+		t.Errorf("Expected record values and credentials to be redacted, got:\n%s", output.String())
+	}
+
+	var response, bodyDump map[string]interface{}
+	for _, entry := range entries {
+		if entry["@module"] != "provider."+LogSubsystem {
+			t.Errorf("Expected entries in the %s subsystem, got %v", LogSubsystem, entry["@module"])
+		}
+		switch entry["@message"] {
+		case "Received LWS API response":
+			response = entry
+		case "LWS API response details":
+			bodyDump = entry
+		}
+	}
+
+	if response == nil {
+		t.Fatalf("Expected a response entry, got %v", entries)
+	}
+	for _, field := range []string{"method", "endpoint", "base_url", "attempt", "status", "latency_ms"} {
+		if _, ok := response[field]; !ok {
+			t.Errorf("Expected field %q in the response entry, got %v", field, response)
+		}
+	}
+	if response["@level"] != "debug" {
+		t.Errorf("Expected the response entry at debug level, got %v", response["@level"])
+	}
+
+	if bodyDump == nil || bodyDump["@level"] != "trace" {
+		t.Errorf("Expected the body dump at trace level, got %v", bodyDump)
+	}
+}
+
+func TestLWSClient_BodyDumpsOnlyAtTrace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code": 200, "info": "Fetched DNS Zone", "data": []}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("TF_LOG_PROVIDER_LWS_API", "DEBUG")
+
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+
+	client := newTestClient(t, server.URL)
+	if _, err := client.GetDNSZone(ctx, testDomainName); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !strings.Contains(output.String(), "Received LWS API response") {
+		t.Errorf("Expected debug entries, got:\n%s", output.String())
+	}
+	if strings.Contains(output.String(), "Fetched DNS Zone") {
+		t.Errorf("Expected no body dump below trace, got:\n%s", output.String())
+	}
+}
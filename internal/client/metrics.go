@@ -0,0 +1,593 @@
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LatencyBuckets are the upper bounds, in seconds, of the latency histogram
+// buckets of Metrics.
+var LatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Metrics counts the API calls of one or more clients, per API address,
+// method and endpoint. Endpoints are recorded with the zone replaced by
+// "{zone}", e.g. "domain/{zone}/zdns". A nil *Metrics records nothing.
+type Metrics struct {
+	mu     sync.Mutex
+	series map[metricsKey]*EndpointMetrics
+}
+
+type metricsKey struct {
+	baseURL  string
+	method   string
+	endpoint string
+}
+
+// EndpointMetrics are the counters of one API address, method and
+// endpoint, or the totals of a Metrics when those are empty.
+type EndpointMetrics struct {
+	BaseURL  string `json:"base_url,omitempty"`
+	Method   string `json:"method,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Requests counts the HTTP requests sent, Retries those sent again
+	// after a failed attempt.
+	Requests int64 `json:"requests"`
+	Retries  int64 `json:"retries"`
+	// TransportErrors counts requests that got no response.
+	TransportErrors int64 `json:"transport_errors"`
+	// Challenges counts Cloudflare challenge pages.
+	Challenges int64 `json:"challenges"`
+	// StatusCodes counts responses by HTTP status.
+	StatusCodes   map[int]int64 `json:"status_codes"`
+	BytesSent     int64         `json:"bytes_sent"`
+	BytesReceived int64         `json:"bytes_received"`
+	// Latency is the time from sending a request to reading its response.
+	Latency Histogram `json:"latency"`
+}
+
+// Histogram counts observations per bucket.
+type Histogram struct {
+	// Bounds are the upper bounds of the buckets, in seconds. Observations
+	// above the last one are only counted in Count.
+	Bounds []float64 `json:"bounds_seconds"`
+	// Counts holds the observations of each bucket, not cumulated.
+	Counts []int64 `json:"counts"`
+	Count  int64   `json:"count"`
+	// Sum is the total of the observations, in seconds.
+	Sum float64 `json:"sum_seconds"`
+}
+
+// requestSample is what an attempt reports to Metrics.
+type requestSample struct {
+	baseURL       string
+	method        string
+	endpoint      string
+	attempt       int
+	status        int
+	challenge     bool
+	transportErr  bool
+	bytesSent     int64
+	bytesReceived int64
+	latency       time.Duration
+}
+
+// NewMetrics returns empty metrics, to share between clients with
+// WithMetrics.
+func NewMetrics() *Metrics {
+	return &Metrics{series: make(map[metricsKey]*EndpointMetrics)}
+}
+
+// record adds an attempt to the metrics.
+func (m *Metrics) record(sample requestSample) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := metricsKey{baseURL: sample.baseURL, method: sample.method, endpoint: metricsEndpoint(sample.endpoint)}
+	series, ok := m.series[key]
+	if !ok {
+		series = newEndpointMetrics(key)
+		m.series[key] = series
+	}
+
+	series.Requests++
+	if sample.attempt > 1 {
+		series.Retries++
+	}
+	if sample.transportErr {
+		series.TransportErrors++
+	} else {
+		series.StatusCodes[sample.status]++
+	}
+	if sample.challenge {
+		series.Challenges++
+	}
+	series.BytesSent += sample.bytesSent
+	series.BytesReceived += sample.bytesReceived
+	series.Latency.observe(sample.latency.Seconds())
+}
+
+func newEndpointMetrics(key metricsKey) *EndpointMetrics {
+	return &EndpointMetrics{
+		BaseURL:     key.baseURL,
+		Method:      key.method,
+		Endpoint:    key.endpoint,
+		StatusCodes: make(map[int]int64),
+		Latency: Histogram{
+			Bounds: LatencyBuckets,
+			Counts: make([]int64, len(LatencyBuckets)),
+		},
+	}
+}
+
+// metricsEndpoint replaces the zone of an endpoint by "{zone}", so that
+// the metrics of every zone add up.
+func metricsEndpoint(endpoint string) string {
+	parts := strings.Split(endpoint, "/")
+	if len(parts) >= 2 && parts[0] == "domain" {
+		parts[1] = "{zone}"
+	}
+	return strings.Join(parts, "/")
+}
+
+func (h *Histogram) observe(seconds float64) {
+	h.Count++
+	h.Sum += seconds
+	for i, bound := range h.Bounds {
+		if seconds <= bound {
+			h.Counts[i]++
+			return
+		}
+	}
+}
+
+// add merges other, which has the same bounds, into h.
+func (h *Histogram) add(other Histogram) {
+	for i := range h.Counts {
+		h.Counts[i] += other.Counts[i]
+	}
+	h.Count += other.Count
+	h.Sum += other.Sum
+}
+
+// Quantile returns an upper estimate of the q quantile (0 to 1) of the
+// observations: the bound of the bucket holding it. It returns 0 without
+// observations, and the last bound when the quantile lies past it.
+func (h Histogram) Quantile(q float64) float64 {
+	if h.Count == 0 || len(h.Bounds) == 0 {
+		return 0
+	}
+	rank := max(int64(math.Ceil(q*float64(h.Count))), 1)
+	var seen int64
+	for i, count := range h.Counts {
+		seen += count
+		if seen >= rank {
+			return h.Bounds[i]
+		}
+	}
+	return h.Bounds[len(h.Bounds)-1]
+}
+
+// Snapshot returns a copy of the metrics of every API address, method and
+// endpoint, sorted.
+func (m *Metrics) Snapshot() []EndpointMetrics {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make([]EndpointMetrics, 0, len(m.series))
+	for _, series := range m.series {
+		snapshot = append(snapshot, series.clone())
+	}
+	sort.Slice(snapshot, func(i, j int) bool {
+		a, b := snapshot[i], snapshot[j]
+		if a.BaseURL != b.BaseURL {
+			return a.BaseURL < b.BaseURL
+		}
+		if a.Endpoint != b.Endpoint {
+			return a.Endpoint < b.Endpoint
+		}
+		return a.Method < b.Method
+	})
+	return snapshot
+}
+
+// Totals returns the metrics of every API address, method and endpoint
+// added up.
+func (m *Metrics) Totals() EndpointMetrics {
+	totals := *newEndpointMetrics(metricsKey{})
+	for _, series := range m.Snapshot() {
+		totals.Requests += series.Requests
+		totals.Retries += series.Retries
+		totals.TransportErrors += series.TransportErrors
+		totals.Challenges += series.Challenges
+		for status, count := range series.StatusCodes {
+			totals.StatusCodes[status] += count
+		}
+		totals.BytesSent += series.BytesSent
+		totals.BytesReceived += series.BytesReceived
+		totals.Latency.add(series.Latency)
+	}
+	return totals
+}
+
+func (e *EndpointMetrics) clone() EndpointMetrics {
+	clone := *e
+	clone.StatusCodes = make(map[int]int64, len(e.StatusCodes))
+	for status, count := range e.StatusCodes {
+		clone.StatusCodes[status] = count
+	}
+	clone.Latency.Counts = append([]int64(nil), e.Latency.Counts...)
+	return clone
+}
+
+// Fields returns the metrics as log fields.
+func (e EndpointMetrics) Fields() map[string]interface{} {
+	statusCodes := make(map[string]int64, len(e.StatusCodes))
+	for status, count := range e.StatusCodes {
+		statusCodes[strconv.Itoa(status)] = count
+	}
+
+	fields := map[string]interface{}{
+		"requests":         e.Requests,
+		"retries":          e.Retries,
+		"transport_errors": e.TransportErrors,
+		"challenges":       e.Challenges,
+		"status_codes":     statusCodes,
+		"bytes_sent":       e.BytesSent,
+		"bytes_received":   e.BytesReceived,
+		"latency_p50_ms":   int64(e.Latency.Quantile(0.5) * 1000),
+		"latency_p95_ms":   int64(e.Latency.Quantile(0.95) * 1000),
+	}
+	if e.Latency.Count > 0 {
+		fields["latency_avg_ms"] = int64(e.Latency.Sum / float64(e.Latency.Count) * 1000)
+	}
+	if e.BaseURL != "" {
+		fields["base_url"] = e.BaseURL
+	}
+	if e.Method != "" {
+		fields["method"] = e.Method
+	}
+	if e.Endpoint != "" {
+		fields["endpoint"] = e.Endpoint
+	}
+	return fields
+}
+
+// metricsJSON is the JSON rendering of Metrics.
+type metricsJSON struct {
+	// Run is the run the metrics were merged for by MergeFile.
+	Run       string            `json:"run,omitempty"`
+	Totals    EndpointMetrics   `json:"totals"`
+	Endpoints []EndpointMetrics `json:"endpoints"`
+}
+
+// WriteJSON writes the totals and the metrics of every endpoint as JSON.
+func (m *Metrics) WriteJSON(w io.Writer) error {
+	return m.writeJSON(w, "")
+}
+
+func (m *Metrics) writeJSON(w io.Writer, run string) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(metricsJSON{Run: run, Totals: m.Totals(), Endpoints: m.Snapshot()})
+}
+
+// WriteOpenMetrics writes the metrics in the OpenMetrics text format, with
+// the lws_api_ prefix.
+func (m *Metrics) WriteOpenMetrics(w io.Writer) error {
+	return m.writeOpenMetrics(w, "")
+}
+
+func (m *Metrics) writeOpenMetrics(w io.Writer, run string) error {
+	snapshot := m.Snapshot()
+	bw := bufio.NewWriter(w)
+
+	if run != "" {
+		fmt.Fprintf(bw, "# TYPE lws_run info\n# HELP lws_run Run the metrics were merged for.\nlws_run_info{run=\"%s\"} 1\n", escapeLabel(run))
+	}
+
+	counters := []struct {
+		name, help string
+		value      func(EndpointMetrics) int64
+	}{
+		{"lws_api_requests", "HTTP requests sent to the LWS API.", func(e EndpointMetrics) int64 { return e.Requests }},
+		{"lws_api_retries", "HTTP requests sent again after a failed attempt.", func(e EndpointMetrics) int64 { return e.Retries }},
+		{"lws_api_transport_errors", "HTTP requests that got no response.", func(e EndpointMetrics) int64 { return e.TransportErrors }},
+		{"lws_api_challenges", "Cloudflare challenge pages received.", func(e EndpointMetrics) int64 { return e.Challenges }},
+		{"lws_api_sent_bytes", "Bytes of request bodies sent.", func(e EndpointMetrics) int64 { return e.BytesSent }},
+		{"lws_api_received_bytes", "Bytes of response bodies received.", func(e EndpointMetrics) int64 { return e.BytesReceived }},
+	}
+	for _, counter := range counters {
+		fmt.Fprintf(bw, "# TYPE %s counter\n# HELP %s %s\n", counter.name, counter.name, counter.help)
+		for _, series := range snapshot {
+			fmt.Fprintf(bw, "%s_total{%s} %d\n", counter.name, series.labels(), counter.value(series))
+		}
+	}
+
+	fmt.Fprintf(bw, "# TYPE lws_api_responses counter\n# HELP lws_api_responses LWS API responses by HTTP status.\n")
+	for _, series := range snapshot {
+		statuses := make([]int, 0, len(series.StatusCodes))
+		for status := range series.StatusCodes {
+			statuses = append(statuses, status)
+		}
+		sort.Ints(statuses)
+		for _, status := range statuses {
+			fmt.Fprintf(bw, "lws_api_responses_total{%s,code=\"%d\"} %d\n", series.labels(), status, series.StatusCodes[status])
+		}
+	}
+
+	fmt.Fprintf(bw, "# TYPE lws_api_request_duration_seconds histogram\n# HELP lws_api_request_duration_seconds Time from sending a request to reading its response.\n")
+	for _, series := range snapshot {
+		labels := series.labels()
+		var cumulated int64
+		for i, bound := range series.Latency.Bounds {
+			cumulated += series.Latency.Counts[i]
+			fmt.Fprintf(bw, "lws_api_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n", labels, strconv.FormatFloat(bound, 'g', -1, 64), cumulated)
+		}
+		fmt.Fprintf(bw, "lws_api_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, series.Latency.Count)
+		fmt.Fprintf(bw, "lws_api_request_duration_seconds_sum{%s} %s\n", labels, strconv.FormatFloat(series.Latency.Sum, 'g', -1, 64))
+		fmt.Fprintf(bw, "lws_api_request_duration_seconds_count{%s} %d\n", labels, series.Latency.Count)
+	}
+
+	fmt.Fprintf(bw, "# EOF\n")
+	return bw.Flush()
+}
+
+// labels returns the OpenMetrics labels of a series.
+func (e EndpointMetrics) labels() string {
+	return fmt.Sprintf(`base_url="%s",method="%s",endpoint="%s"`,
+		escapeLabel(e.BaseURL), escapeLabel(e.Method), escapeLabel(e.Endpoint))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+// WriteFile writes the metrics to path, as JSON when it ends with ".json"
+// and in the OpenMetrics text format otherwise. The file is replaced
+// atomically, so readers never see a partial file.
+func (m *Metrics) WriteFile(path string) error {
+	return m.writeFile(path, "")
+}
+
+func (m *Metrics) writeFile(path, run string) error {
+	write := func(w io.Writer) error { return m.writeOpenMetrics(w, run) }
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		write = func(w io.Writer) error { return m.writeJSON(w, run) }
+	}
+	if err := writeFileAtomic(path, write); err != nil {
+		return fmt.Errorf("error writing metrics file %s: %w", path, err)
+	}
+	return nil
+}
+
+// MergeFile adds the metrics to those merged into path for the same run,
+// and writes the sum back in the same format, tagged with run. The metrics
+// of another run are replaced instead, so that the file only covers the
+// provider processes of one Terraform run. It holds a lock meanwhile, so
+// that those processes can exit concurrently.
+func (m *Metrics) MergeFile(path, run string) error {
+	unlock, err := lockFile(path)
+	if err != nil {
+		return fmt.Errorf("error locking metrics file %s: %w", path, err)
+	}
+	defer unlock()
+
+	merged := NewMetrics()
+	existing, existingRun, err := readMetricsFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("error reading metrics file %s: %w", path, err)
+	case existingRun == run:
+		merged.merge(existing)
+	}
+	merged.merge(m.Snapshot())
+	return merged.writeFile(path, run)
+}
+
+// merge adds series, with the default latency buckets, to the metrics.
+func (m *Metrics) merge(series []EndpointMetrics) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, other := range series {
+		key := metricsKey{baseURL: other.BaseURL, method: other.Method, endpoint: other.Endpoint}
+		current, ok := m.series[key]
+		if !ok {
+			current = newEndpointMetrics(key)
+			m.series[key] = current
+		}
+
+		current.Requests += other.Requests
+		current.Retries += other.Retries
+		current.TransportErrors += other.TransportErrors
+		current.Challenges += other.Challenges
+		for status, count := range other.StatusCodes {
+			current.StatusCodes[status] += count
+		}
+		current.BytesSent += other.BytesSent
+		current.BytesReceived += other.BytesReceived
+		current.Latency.add(other.Latency)
+	}
+}
+
+// readMetricsFile reads the series of a file written by WriteFile or
+// MergeFile, and the run MergeFile tagged it with.
+func readMetricsFile(path string) ([]EndpointMetrics, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var series []EndpointMetrics
+	var run string
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var decoded metricsJSON
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			return nil, "", err
+		}
+		series, run = decoded.Endpoints, decoded.Run
+	} else if series, run, err = parseOpenMetrics(string(data)); err != nil {
+		return nil, "", err
+	}
+
+	for _, s := range series {
+		if !sameBounds(s.Latency.Bounds, LatencyBuckets) || len(s.Latency.Counts) != len(LatencyBuckets) {
+			return nil, "", fmt.Errorf("latency buckets %v differ from %v", s.Latency.Bounds, LatencyBuckets)
+		}
+	}
+	return series, run, nil
+}
+
+func sameBounds(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	openMetricsSample = regexp.MustCompile(`^(\w+)\{(.*)\} (\S+)$`)
+	openMetricsLabel  = regexp.MustCompile(`(\w+)="((?:[^"\\]|\\.)*)"`)
+	labelUnescaper    = strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\n`, "\n")
+)
+
+// parseOpenMetrics reads the series written by WriteOpenMetrics, and the
+// run of the lws_run info metric.
+func parseOpenMetrics(text string) ([]EndpointMetrics, string, error) {
+	series := make(map[metricsKey]*EndpointMetrics)
+	var run string
+	for i, line := range strings.Split(text, "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		match := openMetricsSample.FindStringSubmatch(line)
+		if match == nil {
+			return nil, "", fmt.Errorf("line %d: unexpected sample %q", i+1, line)
+		}
+		labels := make(map[string]string)
+		for _, label := range openMetricsLabel.FindAllStringSubmatch(match[2], -1) {
+			labels[label[1]] = labelUnescaper.Replace(label[2])
+		}
+		if match[1] == "lws_run_info" {
+			run = labels["run"]
+			continue
+		}
+		value, err := strconv.ParseFloat(match[3], 64)
+		if err != nil {
+			return nil, "", fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		key := metricsKey{baseURL: labels["base_url"], method: labels["method"], endpoint: labels["endpoint"]}
+		s, ok := series[key]
+		if !ok {
+			s = newEndpointMetrics(key)
+			series[key] = s
+		}
+
+		count := int64(value)
+		switch match[1] {
+		case "lws_api_requests_total":
+			s.Requests = count
+		case "lws_api_retries_total":
+			s.Retries = count
+		case "lws_api_transport_errors_total":
+			s.TransportErrors = count
+		case "lws_api_challenges_total":
+			s.Challenges = count
+		case "lws_api_sent_bytes_total":
+			s.BytesSent = count
+		case "lws_api_received_bytes_total":
+			s.BytesReceived = count
+		case "lws_api_responses_total":
+			status, err := strconv.Atoi(labels["code"])
+			if err != nil {
+				return nil, "", fmt.Errorf("line %d: %w", i+1, err)
+			}
+			s.StatusCodes[status] = count
+		case "lws_api_request_duration_seconds_bucket":
+			if labels["le"] == "+Inf" {
+				continue
+			}
+			bound, err := strconv.ParseFloat(labels["le"], 64)
+			if err != nil {
+				return nil, "", fmt.Errorf("line %d: %w", i+1, err)
+			}
+			bucket := -1
+			for j, b := range LatencyBuckets {
+				if b == bound {
+					bucket = j
+					break
+				}
+			}
+			if bucket < 0 {
+				return nil, "", fmt.Errorf("line %d: unknown latency bucket %s", i+1, labels["le"])
+			}
+			s.Latency.Counts[bucket] = count
+		case "lws_api_request_duration_seconds_sum":
+			s.Latency.Sum = value
+		case "lws_api_request_duration_seconds_count":
+			s.Latency.Count = count
+		default:
+			return nil, "", fmt.Errorf("line %d: unknown metric %s", i+1, match[1])
+		}
+	}
+
+	result := make([]EndpointMetrics, 0, len(series))
+	for _, s := range series {
+		// The file cumulates the buckets, Histogram does not
+		for j := len(s.Latency.Counts) - 1; j > 0; j-- {
+			s.Latency.Counts[j] -= s.Latency.Counts[j-1]
+		}
+		result = append(result, *s)
+	}
+	return result, run, nil
+}
+
+// writeFileAtomic writes a file through a temporary file renamed over path,
+// so readers never see it half written.
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	err = write(tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,318 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLWSClient_Metrics(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		call := calls
+		mu.Unlock()
+
+		switch call {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte(`{"code": 502, "info": "Bad gateway", "data": null}`))
+		case 2:
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(challengePage))
+		default:
+			_, _ = w.Write([]byte(`{"code": 200, "info": "Fetched DNS Zone", "data": []}`))
+		}
+	}))
+	defer server.Close()
+
+	metrics := NewMetrics()
+	client := newTestClient(t, server.URL,
+		WithRetryPolicy(&DefaultRetryPolicy{Retries: 2, Multiplier: 1}),
+		WithMetrics(metrics),
+	)
+	if _, err := client.GetDNSZone(context.Background(), testDomainName); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := client.GetDNSZone(context.Background(), "example.org"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	snapshot := metrics.Snapshot()
+	if len(snapshot) != 1 {
+		t.Fatalf("Expected the zones to share a series, got %+v", snapshot)
+	}
+	series := snapshot[0]
+	if series.BaseURL != server.URL || series.Method != http.MethodGet || series.Endpoint != "domain/{zone}/zdns" {
+		t.Errorf("Unexpected series labels %+v", series)
+	}
+	if series.Requests != 4 || series.Retries != 2 || series.Challenges != 1 || series.TransportErrors != 0 {
+		t.Errorf("Expected 4 requests, 2 retries and 1 challenge, got %+v", series)
+	}
+	if series.StatusCodes[200] != 2 || series.StatusCodes[502] != 1 || series.StatusCodes[403] != 1 {
+		t.Errorf("Unexpected status codes %v", series.StatusCodes)
+	}
+	if series.BytesReceived == 0 || series.Latency.Count != 4 {
+		t.Errorf("Expected bytes and latencies to be recorded, got %+v", series)
+	}
+
+	totals := metrics.Totals()
+	if totals.Requests != 4 || totals.BaseURL != "" || totals.StatusCodes[200] != 2 {
+		t.Errorf("Unexpected totals %+v", totals)
+	}
+
+	// Without metrics nothing is recorded
+	var none *Metrics
+	none.record(requestSample{method: http.MethodGet})
+	if none.Snapshot() != nil || none.Totals().Requests != 0 {
+		t.Errorf("Expected nil metrics to stay empty")
+	}
+}
+
+// sampleMetrics returns metrics with a GET and a POST.
+func sampleMetrics() *Metrics {
+	metrics := NewMetrics()
+	metrics.record(requestSample{
+		baseURL: "https://api.lws.net/v1", method: http.MethodGet, endpoint: "domain/example.com/zdns",
+		attempt: 1, status: 200, bytesReceived: 120, latency: 80 * time.Millisecond,
+	})
+	metrics.record(requestSample{
+		baseURL: "https://api.lws.net/v1", method: http.MethodGet, endpoint: "domain/example.org/zdns",
+		attempt: 2, status: 503, bytesReceived: 40, latency: 2 * time.Second,
+	})
+	metrics.record(requestSample{
+		baseURL: "https://api.lws.net/v1", method: http.MethodPost, endpoint: "domain/example.com/zdns",
+		attempt: 1, transportErr: true, bytesSent: 64, latency: 40 * time.Second,
+	})
+	return metrics
+}
+
+func TestMetrics_WriteOpenMetrics(t *testing.T) {
+	var out strings.Builder
+	if err := sampleMetrics().WriteOpenMetrics(&out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	get := `base_url="https://api.lws.net/v1",method="GET",endpoint="domain/{zone}/zdns"`
+	post := `base_url="https://api.lws.net/v1",method="POST",endpoint="domain/{zone}/zdns"`
+	for _, line := range []string{
+		"# TYPE lws_api_requests counter",
+		"lws_api_requests_total{" + get + "} 2",
+		"lws_api_retries_total{" + get + "} 1",
+		"lws_api_transport_errors_total{" + post + "} 1",
+		"lws_api_sent_bytes_total{" + post + "} 64",
+		"lws_api_received_bytes_total{" + get + "} 160",
+		"lws_api_responses_total{" + get + `,code="503"} 1`,
+		"# TYPE lws_api_request_duration_seconds histogram",
+		"lws_api_request_duration_seconds_bucket{" + get + `,le="0.1"} 1`,
+		"lws_api_request_duration_seconds_bucket{" + get + `,le="2.5"} 2`,
+		"lws_api_request_duration_seconds_bucket{" + post + `,le="30"} 0`,
+		"lws_api_request_duration_seconds_bucket{" + post + `,le="+Inf"} 1`,
+		"lws_api_request_duration_seconds_sum{" + get + "} 2.08",
+		"lws_api_request_duration_seconds_count{" + get + "} 2",
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("Expected line %q in:\n%s", line, out.String())
+		}
+	}
+	if !strings.HasSuffix(out.String(), "# EOF\n") {
+		t.Errorf("Expected the output to end with # EOF, got:\n%s", out.String())
+	}
+
+	if got := escapeLabel("a\"b\\c\nd"); got != `a\"b\\c\nd` {
+		t.Errorf("Unexpected escaped label %q", got)
+	}
+}
+
+func TestMetrics_WriteFile(t *testing.T) {
+	dir := t.TempDir()
+	metrics := sampleMetrics()
+
+	jsonPath := filepath.Join(dir, "metrics.json")
+	if err := metrics.WriteFile(jsonPath); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var decoded struct {
+		Totals    EndpointMetrics
+		Endpoints []EndpointMetrics
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unable to decode %s: %v", data, err)
+	}
+	if decoded.Totals.Requests != 3 || len(decoded.Endpoints) != 2 || decoded.Endpoints[0].StatusCodes[503] != 1 {
+		t.Errorf("Unexpected metrics file:\n%s", data)
+	}
+
+	textPath := filepath.Join(dir, "metrics.prom")
+	if err := metrics.WriteFile(textPath); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err = os.ReadFile(textPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "# TYPE lws_api_requests counter") {
+		t.Errorf("Expected an OpenMetrics file, got:\n%s", data)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("Expected no temporary file left behind, got %v", entries)
+	}
+
+	if err := metrics.WriteFile(filepath.Join(dir, "missing", "metrics.json")); err == nil {
+		t.Errorf("Expected an error for a missing directory")
+	}
+}
+
+func TestMetrics_MergeFile(t *testing.T) {
+	for _, name := range []string{"metrics.json", "metrics.prom"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+
+			// Provider processes exit concurrently at the end of a run
+			var wg sync.WaitGroup
+			for range 4 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if err := sampleMetrics().MergeFile(path, "run-1"); err != nil {
+						t.Errorf("Unexpected error: %v", err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			series, run, err := readMetricsFile(path)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if run != "run-1" {
+				t.Errorf("Expected the file to be tagged with run-1, got %q", run)
+			}
+			merged := NewMetrics()
+			merged.merge(series)
+
+			want := sampleMetrics()
+			for range 3 {
+				want.merge(sampleMetrics().Snapshot())
+			}
+			got, expected := merged.Snapshot(), want.Snapshot()
+			if len(got) != len(expected) {
+				t.Fatalf("Expected %d series, got %+v", len(expected), got)
+			}
+			for i := range got {
+				if got[i].Requests != expected[i].Requests || got[i].StatusCodes[503] != expected[i].StatusCodes[503] ||
+					got[i].BytesReceived != expected[i].BytesReceived || got[i].Latency.Count != expected[i].Latency.Count ||
+					fmt.Sprint(got[i].Latency.Counts) != fmt.Sprint(expected[i].Latency.Counts) {
+					t.Errorf("Expected %+v, got %+v", expected[i], got[i])
+				}
+			}
+
+			entries, _ := os.ReadDir(filepath.Dir(path))
+			if len(entries) != 2 {
+				t.Errorf("Expected no temporary file left behind, got %v", entries)
+			}
+
+			// Another run replaces the metrics
+			if err := sampleMetrics().MergeFile(path, "run-2"); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			series, run, err = readMetricsFile(path)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			replaced := NewMetrics()
+			replaced.merge(series)
+			if run != "run-2" || replaced.Totals().Requests != sampleMetrics().Totals().Requests {
+				t.Errorf("Expected only the metrics of run-2, got %+v of %q", replaced.Totals(), run)
+			}
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "metrics.prom")
+	if err := os.WriteFile(path, []byte("not metrics\n"), 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := sampleMetrics().MergeFile(path, "run-1"); err == nil {
+		t.Errorf("Expected an error merging into a file that holds no metrics")
+	}
+}
+
+func TestLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	unlock, err := lockFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	locked := make(chan struct{})
+	go func() {
+		defer close(locked)
+		unlockSecond, err := lockFile(path)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+		unlockSecond()
+	}()
+
+	select {
+	case <-locked:
+		t.Fatalf("Expected the second lock to wait for the first one")
+	case <-time.After(100 * time.Millisecond):
+	}
+	unlock()
+	<-locked
+
+	// A lock file left over by a killed process holds no lock
+	if err := os.WriteFile(path+".lock", nil, 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	unlock, err = lockFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	unlock()
+}
+
+func TestHistogram_Quantile(t *testing.T) {
+	h := newEndpointMetrics(metricsKey{}).Latency
+	if got := h.Quantile(0.5); got != 0 {
+		t.Errorf("Expected 0 without observations, got %v", got)
+	}
+
+	for _, seconds := range []float64{0.01, 0.02, 0.3, 0.4, 60} {
+		h.observe(seconds)
+	}
+	tests := []struct {
+		q    float64
+		want float64
+	}{
+		{0.2, 0.05},
+		{0.5, 0.5},
+		{0.8, 0.5},
+		{1, 30},
+	}
+	for _, tt := range tests {
+		if got := h.Quantile(tt.q); got != tt.want {
+			t.Errorf("Quantile(%v) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+}
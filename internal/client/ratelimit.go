@@ -0,0 +1,150 @@
+package client
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Defaults used when the provider configuration does not override them.
+const (
+	DefaultRequestsPerSecond     = 5.0
+	DefaultMaxConcurrentRequests = 4
+)
+
+// minAdaptiveRate is the floor the limiter backs off to when LWS keeps
+// throttling us: one request every five seconds.
+const minAdaptiveRate = 0.2
+
+// RateLimiter combines a token bucket, which spaces requests over time, with
+// a semaphore capping how many requests are in flight at once.
+//
+// The bucket adapts to the API: every throttled answer (429 or Cloudflare
+// challenge) halves the rate, and every successful call gives back a small
+// fraction of the configured rate until it is fully restored.
+type RateLimiter struct {
+	mu       sync.Mutex
+	rate     float64
+	maxRate  float64
+	burst    float64
+	tokens   float64
+	last     time.Time
+	clock    Clock
+	inflight chan struct{}
+}
+
+// NewRateLimiter creates a limiter allowing requestsPerSecond on average with
+// bursts of up to burst requests, and at most maxConcurrent requests in
+// flight. A rate of zero or less disables the token bucket and a
+// maxConcurrent of zero or less disables the concurrency cap.
+func NewRateLimiter(requestsPerSecond float64, burst int, maxConcurrent int) *RateLimiter {
+	return newRateLimiter(requestsPerSecond, burst, maxConcurrent, systemClock{})
+}
+
+// newRateLimiter creates a limiter refilling its bucket as told by clock.
+func newRateLimiter(requestsPerSecond float64, burst int, maxConcurrent int, clock Clock) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	l := &RateLimiter{
+		rate:    requestsPerSecond,
+		maxRate: requestsPerSecond,
+		burst:   float64(burst),
+		tokens:  float64(burst),
+		last:    clock.Now(),
+		clock:   clock,
+	}
+
+	if maxConcurrent > 0 {
+		l.inflight = make(chan struct{}, maxConcurrent)
+	}
+
+	return l
+}
+
+// Acquire blocks until a request may be sent. The returned function must be
+// called once the response has been consumed to free the concurrency slot.
+func (l *RateLimiter) Acquire(ctx context.Context) (func(), error) {
+	if l.inflight != nil {
+		select {
+		case l.inflight <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	release := func() {
+		if l.inflight != nil {
+			<-l.inflight
+		}
+	}
+
+	for {
+		wait := l.reserve()
+		if wait == 0 {
+			return release, nil
+		}
+
+		if err := sleepContext(ctx, wait); err != nil {
+			release()
+			return nil, err
+		}
+	}
+}
+
+// reserve takes a token if one is available and returns zero, or returns how
+// long to wait before the next token is expected.
+func (l *RateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rate <= 0 {
+		return 0
+	}
+
+	now := l.clock.Now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+// Throttled reports that the API pushed back, halving the allowed rate.
+func (l *RateLimiter) Throttled() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.maxRate <= 0 {
+		return
+	}
+
+	l.rate = math.Max(minAdaptiveRate, l.rate/2)
+	l.tokens = math.Min(l.tokens, 0)
+}
+
+// Succeeded reports a successful call, slowly restoring the configured rate.
+func (l *RateLimiter) Succeeded() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.maxRate <= 0 || l.rate >= l.maxRate {
+		return
+	}
+
+	l.rate = math.Min(l.maxRate, l.rate+l.maxRate/10)
+}
+
+// Rate returns the number of requests per second currently allowed.
+func (l *RateLimiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.rate
+}
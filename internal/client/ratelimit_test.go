@@ -0,0 +1,147 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_TokenBucket(t *testing.T) {
+	limiter := NewRateLimiter(20, 1, 0)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		release, err := limiter.Acquire(context.Background())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		release()
+	}
+
+	// One token available immediately, then one every 50ms
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("Expected requests to be spaced out, 5 acquisitions took %s", elapsed)
+	}
+}
+
+func TestLWSClient_RateLimiterFollowsClock(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	client := newTestClient(t, "http://127.0.0.1", WithRateLimit(2, 1, 1), WithClock(clock))
+
+	if wait := client.limiter.reserve(); wait != 0 {
+		t.Fatalf("Expected the first token to be available, got a wait of %s", wait)
+	}
+	if wait := client.limiter.reserve(); wait != 500*time.Millisecond {
+		t.Errorf("Expected to wait 500ms for the next token, got %s", wait)
+	}
+
+	// Only the clock of the client refills the bucket
+	clock.Advance(500 * time.Millisecond)
+	if wait := client.limiter.reserve(); wait != 0 {
+		t.Errorf("Expected a token after the clock advanced, got a wait of %s", wait)
+	}
+}
+
+func TestRateLimiter_AcquireHonoursContext(t *testing.T) {
+	limiter := NewRateLimiter(0, 1, 1)
+
+	release, err := limiter.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := limiter.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded while the only slot is taken, got %v", err)
+	}
+}
+
+func TestRateLimiter_Adaptive(t *testing.T) {
+	limiter := NewRateLimiter(8, 8, 0)
+
+	limiter.Throttled()
+	if rate := limiter.Rate(); rate != 4 {
+		t.Errorf("Expected rate to be halved to 4, got %.2f", rate)
+	}
+
+	for i := 0; i < 10; i++ {
+		limiter.Throttled()
+	}
+	if rate := limiter.Rate(); rate != minAdaptiveRate {
+		t.Errorf("Expected rate to bottom out at %.2f, got %.2f", minAdaptiveRate, rate)
+	}
+
+	for i := 0; i < 20; i++ {
+		limiter.Succeeded()
+	}
+	if rate := limiter.Rate(); rate != 8 {
+		t.Errorf("Expected rate to recover to 8, got %.2f", rate)
+	}
+}
+
+func TestLWSClient_ConcurrentRequests(t *testing.T) {
+	var inflight, maxInflight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inflight, 1)
+		defer atomic.AddInt32(&inflight, -1)
+
+		for {
+			previous := atomic.LoadInt32(&maxInflight)
+			if current <= previous || atomic.CompareAndSwapInt32(&maxInflight, previous, current) {
+				break
+			}
+		}
+
+		time.Sleep(50 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"code": 200, "info": "Fetched DNS Zone", "data": []}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server.URL, WithRateLimit(0, 1, 3))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 9; i++ {
+		wg.Add(1)
+		go func(zone string) {
+			defer wg.Done()
+			if _, err := client.GetDNSZone(context.Background(), zone); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}(fmt.Sprintf("zone%d.com", i))
+	}
+	wg.Wait()
+
+	if maxInflight < 2 {
+		t.Errorf("Expected requests to run in parallel, max in flight was %d", maxInflight)
+	}
+	if maxInflight > 3 {
+		t.Errorf("Expected at most 3 requests in flight, got %d", maxInflight)
+	}
+}
+
+func TestLWSClient_ThrottlingLowersRate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"code": 429, "info": "Too many requests", "data": null}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server.URL, WithRateLimiter(NewRateLimiter(10, 10, 0)))
+
+	_, err := client.GetDNSZone(context.Background(), testDomainName)
+	if !IsRateLimited(err) {
+		t.Fatalf("Expected rate limited error, got %v", err)
+	}
+	if rate := client.limiter.Rate(); rate != 5 {
+		t.Errorf("Expected rate to drop to 5 after a 429, got %.2f", rate)
+	}
+}
@@ -0,0 +1,63 @@
+package rdata
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// A is the data of an A record.
+type A struct {
+	Addr netip.Addr
+}
+
+// ParseA parses an IPv4 address.
+func ParseA(value string) (*A, error) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(value))
+	if err != nil || !addr.Is4() {
+		return nil, invalid("A", value, "expected an IPv4 address")
+	}
+	return &A{Addr: addr}, nil
+}
+
+// Type implements RData
+func (d *A) Type() string { return "A" }
+
+// String implements RData
+func (d *A) String() string { return d.Addr.String() }
+
+// Canonical implements RData
+func (d *A) Canonical() string { return d.Addr.String() }
+
+// AAAA is the data of an AAAA record.
+type AAAA struct {
+	Addr netip.Addr
+
+	// written is the address as parsed, which IPv6 allows to spell in many
+	// ways, e.g. "2001:DB8:0:0::1" for 2001:db8::1.
+	written string
+}
+
+// ParseAAAA parses an IPv6 address. IPv4-mapped addresses are accepted,
+// plain IPv4 addresses are not.
+func ParseAAAA(value string) (*AAAA, error) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(value))
+	if err != nil || !addr.Is6() || addr.Zone() != "" {
+		return nil, invalid("AAAA", value, "expected an IPv6 address")
+	}
+	return &AAAA{Addr: addr, written: strings.TrimSpace(value)}, nil
+}
+
+// Type implements RData
+func (d *AAAA) Type() string { return "AAAA" }
+
+// String implements RData. It returns the address as it was parsed, or in
+// its RFC 5952 form when the AAAA was built from Addr alone.
+func (d *AAAA) String() string {
+	if d.written != "" {
+		return d.written
+	}
+	return d.Addr.String()
+}
+
+// Canonical implements RData
+func (d *AAAA) Canonical() string { return d.Addr.String() }
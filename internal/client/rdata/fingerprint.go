@@ -0,0 +1,104 @@
+package rdata
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// TLSA is the data of a TLSA record, e.g. "3 1 1 0123...cdef".
+type TLSA struct {
+	Usage        uint8
+	Selector     uint8
+	MatchingType uint8
+	// Certificate is the certificate association data in hex.
+	Certificate string
+}
+
+// ParseTLSA parses a usage, selector, matching type and hex data.
+func ParseTLSA(value string) (*TLSA, error) {
+	parts, err := fields("TLSA", value, 4, "usage, selector, matching type and certificate data")
+	if err != nil {
+		return nil, err
+	}
+
+	var numbers [3]uint8
+	for i, field := range []string{"usage", "selector", "matching type"} {
+		n, err := parseUint("TLSA", value, field, parts[i], 8)
+		if err != nil {
+			return nil, err
+		}
+		numbers[i] = uint8(n)
+	}
+
+	if err := checkHex("TLSA", value, parts[3]); err != nil {
+		return nil, err
+	}
+
+	return &TLSA{Usage: numbers[0], Selector: numbers[1], MatchingType: numbers[2], Certificate: parts[3]}, nil
+}
+
+// Type implements RData
+func (d *TLSA) Type() string { return "TLSA" }
+
+// String implements RData
+func (d *TLSA) String() string {
+	return fmt.Sprintf("%d %d %d %s", d.Usage, d.Selector, d.MatchingType, d.Certificate)
+}
+
+// Canonical implements RData
+func (d *TLSA) Canonical() string {
+	return fmt.Sprintf("%d %d %d %s", d.Usage, d.Selector, d.MatchingType, strings.ToLower(d.Certificate))
+}
+
+// SSHFP is the data of an SSHFP record, e.g. "4 2 0123...cdef".
+type SSHFP struct {
+	Algorithm uint8
+	FPType    uint8
+	// Fingerprint is the key fingerprint in hex.
+	Fingerprint string
+}
+
+// ParseSSHFP parses an algorithm, fingerprint type and hex fingerprint.
+func ParseSSHFP(value string) (*SSHFP, error) {
+	parts, err := fields("SSHFP", value, 3, "algorithm, fingerprint type and fingerprint")
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm, err := parseUint("SSHFP", value, "algorithm", parts[0], 8)
+	if err != nil {
+		return nil, err
+	}
+	fpType, err := parseUint("SSHFP", value, "fingerprint type", parts[1], 8)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkHex("SSHFP", value, parts[2]); err != nil {
+		return nil, err
+	}
+
+	return &SSHFP{Algorithm: uint8(algorithm), FPType: uint8(fpType), Fingerprint: parts[2]}, nil
+}
+
+// Type implements RData
+func (d *SSHFP) Type() string { return "SSHFP" }
+
+// String implements RData
+func (d *SSHFP) String() string {
+	return fmt.Sprintf("%d %d %s", d.Algorithm, d.FPType, d.Fingerprint)
+}
+
+// Canonical implements RData
+func (d *SSHFP) Canonical() string {
+	return fmt.Sprintf("%d %d %s", d.Algorithm, d.FPType, strings.ToLower(d.Fingerprint))
+}
+
+// checkHex checks that text is a non-empty even-length hex string.
+func checkHex(recordType, value, text string) error {
+	if _, err := hex.DecodeString(text); err != nil || text == "" {
+		return invalid(recordType, value, fmt.Sprintf("invalid hex data %q", text))
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package rdata
+
+import (
+	"fmt"
+)
+
+// CNAME is the data of a CNAME record.
+type CNAME struct {
+	Target string
+}
+
+// ParseCNAME parses the target of an alias.
+func ParseCNAME(value string) (*CNAME, error) {
+	target, err := parseName("CNAME", value)
+	if err != nil {
+		return nil, err
+	}
+	return &CNAME{Target: target}, nil
+}
+
+// Type implements RData
+func (d *CNAME) Type() string { return "CNAME" }
+
+// String implements RData
+func (d *CNAME) String() string { return d.Target }
+
+// Canonical implements RData
+func (d *CNAME) Canonical() string { return canonicalName(d.Target) }
+
+// NS is the data of an NS record.
+type NS struct {
+	Host string
+}
+
+// ParseNS parses the name of a name server.
+func ParseNS(value string) (*NS, error) {
+	host, err := parseName("NS", value)
+	if err != nil {
+		return nil, err
+	}
+	return &NS{Host: host}, nil
+}
+
+// Type implements RData
+func (d *NS) Type() string { return "NS" }
+
+// String implements RData
+func (d *NS) String() string { return d.Host }
+
+// Canonical implements RData
+func (d *NS) Canonical() string { return canonicalName(d.Host) }
+
+// PTR is the data of a PTR record.
+type PTR struct {
+	Target string
+}
+
+// ParsePTR parses the name a reverse record points to.
+func ParsePTR(value string) (*PTR, error) {
+	target, err := parseName("PTR", value)
+	if err != nil {
+		return nil, err
+	}
+	return &PTR{Target: target}, nil
+}
+
+// Type implements RData
+func (d *PTR) Type() string { return "PTR" }
+
+// String implements RData
+func (d *PTR) String() string { return d.Target }
+
+// Canonical implements RData
+func (d *PTR) Canonical() string { return canonicalName(d.Target) }
+
+// MX is the data of an MX record, e.g. "10 mail.example.com".
+type MX struct {
+	Preference uint16
+	Exchange   string
+}
+
+// ParseMX parses a preference followed by a mail exchanger.
+func ParseMX(value string) (*MX, error) {
+	parts, err := fields("MX", value, 2, "preference and exchange")
+	if err != nil {
+		return nil, err
+	}
+
+	preference, err := parseUint("MX", value, "preference", parts[0], 16)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MX{Preference: uint16(preference), Exchange: parts[1]}, nil
+}
+
+// Type implements RData
+func (d *MX) Type() string { return "MX" }
+
+// String implements RData
+func (d *MX) String() string { return fmt.Sprintf("%d %s", d.Preference, d.Exchange) }
+
+// Canonical implements RData
+func (d *MX) Canonical() string {
+	return fmt.Sprintf("%d %s", d.Preference, canonicalName(d.Exchange))
+}
+
+// SRV is the data of an SRV record, e.g. "10 60 5060 sip.example.com".
+type SRV struct {
+	Priority uint16
+	Weight   uint16
+	Port     uint16
+	Target   string
+}
+
+// ParseSRV parses a priority, weight, port and target.
+func ParseSRV(value string) (*SRV, error) {
+	parts, err := fields("SRV", value, 4, "priority, weight, port and target")
+	if err != nil {
+		return nil, err
+	}
+
+	var numbers [3]uint16
+	for i, field := range []string{"priority", "weight", "port"} {
+		n, err := parseUint("SRV", value, field, parts[i], 16)
+		if err != nil {
+			return nil, err
+		}
+		numbers[i] = uint16(n)
+	}
+
+	return &SRV{Priority: numbers[0], Weight: numbers[1], Port: numbers[2], Target: parts[3]}, nil
+}
+
+// Type implements RData
+func (d *SRV) Type() string { return "SRV" }
+
+// String implements RData
+func (d *SRV) String() string {
+	return fmt.Sprintf("%d %d %d %s", d.Priority, d.Weight, d.Port, d.Target)
+}
+
+// Canonical implements RData
+func (d *SRV) Canonical() string {
+	return fmt.Sprintf("%d %d %d %s", d.Priority, d.Weight, d.Port, canonicalName(d.Target))
+}
@@ -0,0 +1,126 @@
+// Package rdata parses the values of DNS records into typed data.
+//
+// LWS sends and accepts record data as a single presentation string, e.g.
+// "10 mail.example.com" for an MX. Each supported type has a struct, a Parse
+// function reading that string and a String method writing it back, so that
+// Parse followed by String returns the LWS representation: names, addresses,
+// hex data and quoting as written, numbers in decimal and fields separated
+// by a single space. Canonical returns the form two records with equivalent
+// data share, which is what records should be compared on.
+package rdata
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedType is returned by Parse for record types without a parser.
+var ErrUnsupportedType = errors.New("rdata: unsupported record type")
+
+// RData is the parsed data of a record.
+type RData interface {
+	// Type returns the record type, e.g. "MX".
+	Type() string
+	// String returns the data as LWS represents it.
+	String() string
+	// Canonical returns the data with case, trailing dots, quoting and
+	// spacing normalised, so that equivalent data has the same canonical
+	// form.
+	Canonical() string
+}
+
+// parsers maps record types to their parse function.
+var parsers = map[string]func(string) (RData, error){
+	"A":     func(v string) (RData, error) { return ParseA(v) },
+	"AAAA":  func(v string) (RData, error) { return ParseAAAA(v) },
+	"CNAME": func(v string) (RData, error) { return ParseCNAME(v) },
+	"NS":    func(v string) (RData, error) { return ParseNS(v) },
+	"PTR":   func(v string) (RData, error) { return ParsePTR(v) },
+	"MX":    func(v string) (RData, error) { return ParseMX(v) },
+	"SRV":   func(v string) (RData, error) { return ParseSRV(v) },
+	"TXT":   func(v string) (RData, error) { return ParseTXT(v) },
+	"CAA":   func(v string) (RData, error) { return ParseCAA(v) },
+	"TLSA":  func(v string) (RData, error) { return ParseTLSA(v) },
+	"SSHFP": func(v string) (RData, error) { return ParseSSHFP(v) },
+}
+
+// Supported reports whether Parse handles the record type.
+func Supported(recordType string) bool {
+	_, ok := parsers[normalizeType(recordType)]
+	return ok
+}
+
+// Parse parses the value of a record of the given type.
+func Parse(recordType, value string) (RData, error) {
+	parse, ok := parsers[normalizeType(recordType)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedType, recordType)
+	}
+	return parse(value)
+}
+
+// Canonical returns the canonical form of a record value. Values that cannot
+// be parsed, including those of unsupported types, are compared as names:
+// surrounding spaces and a trailing dot are dropped and case is ignored.
+func Canonical(recordType, value string) string {
+	if data, err := Parse(recordType, value); err == nil {
+		return data.Canonical()
+	}
+	return canonicalName(value)
+}
+
+// Equal reports whether two parsed values are of the same type and have the
+// same canonical form.
+func Equal(a, b RData) bool {
+	return a.Type() == b.Type() && a.Canonical() == b.Canonical()
+}
+
+// EqualValues reports whether two values of a record type are equivalent.
+func EqualValues(recordType, a, b string) bool {
+	return Canonical(recordType, a) == Canonical(recordType, b)
+}
+
+// normalizeType returns a record type in upper case.
+func normalizeType(recordType string) string {
+	return strings.ToUpper(strings.TrimSpace(recordType))
+}
+
+// canonicalName returns a domain name without surrounding spaces or trailing
+// dot, in lower case.
+func canonicalName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(name), "."))
+}
+
+// parseName checks that a domain name is a single non-empty field.
+func parseName(recordType, value string) (string, error) {
+	name := strings.TrimSpace(value)
+	if name == "" || strings.ContainsAny(name, " \t\n\r") {
+		return "", invalid(recordType, value, "expected a single domain name")
+	}
+	return name, nil
+}
+
+// fields splits a value into exactly n space separated fields.
+func fields(recordType, value string, n int, layout string) ([]string, error) {
+	parts := strings.Fields(value)
+	if len(parts) != n {
+		return nil, invalid(recordType, value, "expected "+layout)
+	}
+	return parts, nil
+}
+
+// parseUint parses an unsigned integer field of the given bit size.
+func parseUint(recordType, value, field, text string, bits int) (uint64, error) {
+	n, err := strconv.ParseUint(text, 10, bits)
+	if err != nil {
+		return 0, invalid(recordType, value, fmt.Sprintf("invalid %s %q", field, text))
+	}
+	return n, nil
+}
+
+// invalid returns the error of a value that does not parse.
+func invalid(recordType, value, reason string) error {
+	return fmt.Errorf("invalid %s data %q: %s", recordType, value, reason)
+}
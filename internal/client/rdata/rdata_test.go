@@ -0,0 +1,188 @@
+package rdata
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestParse_RoundTrip(t *testing.T) {
+	tests := []struct {
+		recordType string
+		value      string
+		canonical  string
+	}{
+		{"A", "192.168.1.1", "192.168.1.1"},
+		{"AAAA", "2001:db8::1", "2001:db8::1"},
+		{"CNAME", "www.Example.com.", "www.example.com"},
+		{"NS", "ns1.lws.fr", "ns1.lws.fr"},
+		{"PTR", "host.example.com.", "host.example.com"},
+		{"MX", "10 Mail.example.com", "10 mail.example.com"},
+		{"SRV", "10 60 5060 sip.example.com.", "10 60 5060 sip.example.com"},
+		{"TXT", "v=spf1 include:_spf.google.com ~all", "v=spf1 include:_spf.google.com ~all"},
+		{"TXT", `"v=DKIM1; k=rsa; " "p=MIGf"`, "v=DKIM1; k=rsa; p=MIGf"},
+		{"TXT", `"say \"hi\""`, `say "hi"`},
+		{"CAA", `0 issue "letsencrypt.org"`, `0 issue "letsencrypt.org"`},
+		{"CAA", `128 IODEF mailto:security@example.com`, `128 iodef "mailto:security@example.com"`},
+		{"TLSA", "3 1 1 0D6FCE3A", "3 1 1 0d6fce3a"},
+		{"SSHFP", "4 2 ABCDEF01", "4 2 abcdef01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.recordType+"/"+tt.value, func(t *testing.T) {
+			data, err := Parse(tt.recordType, tt.value)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if data.Type() != tt.recordType {
+				t.Errorf("Expected type %s, got %s", tt.recordType, data.Type())
+			}
+			if data.String() != tt.value {
+				t.Errorf("Expected %q to round-trip, got %q", tt.value, data.String())
+			}
+			if data.Canonical() != tt.canonical {
+				t.Errorf("Expected canonical form %q, got %q", tt.canonical, data.Canonical())
+			}
+		})
+	}
+}
+
+func TestParse_NonCanonical(t *testing.T) {
+	tests := []struct {
+		recordType string
+		value      string
+		formatted  string
+		canonical  string
+	}{
+		{"A", " 192.168.1.1 ", "192.168.1.1", "192.168.1.1"},
+		{"AAAA", "2001:DB8:0:0::1", "2001:DB8:0:0::1", "2001:db8::1"},
+		{"AAAA", " ::FFFF:192.0.2.1 ", "::FFFF:192.0.2.1", "::ffff:192.0.2.1"},
+		{"CNAME", " WWW.Example.com. ", "WWW.Example.com.", "www.example.com"},
+		{"NS", "NS1.lws.fr.", "NS1.lws.fr.", "ns1.lws.fr"},
+		{"PTR", "Host.Example.com", "Host.Example.com", "host.example.com"},
+		{"MX", "010  Mail.example.com.", "10 Mail.example.com.", "10 mail.example.com"},
+		{"SRV", " 10  60 05060 SIP.example.com ", "10 60 5060 SIP.example.com", "10 60 5060 sip.example.com"},
+		{"TXT", "  v=spf1 -all  ", "v=spf1 -all", "v=spf1 -all"},
+		{"TXT", `"v=spf1 "   "-all"`, `"v=spf1 " "-all"`, "v=spf1 -all"},
+		{"CAA", `0  ISSUE   "letsencrypt.org"`, `0 ISSUE "letsencrypt.org"`, `0 issue "letsencrypt.org"`},
+		{"TLSA", "3 1 01  0D6FCE3A", "3 1 1 0D6FCE3A", "3 1 1 0d6fce3a"},
+		{"SSHFP", "04 2 ABCDEF01", "4 2 ABCDEF01", "4 2 abcdef01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.recordType+"/"+tt.value, func(t *testing.T) {
+			data, err := Parse(tt.recordType, tt.value)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if data.String() != tt.formatted {
+				t.Errorf("Expected %q to be formatted as %q, got %q", tt.value, tt.formatted, data.String())
+			}
+			if data.Canonical() != tt.canonical {
+				t.Errorf("Expected canonical form %q, got %q", tt.canonical, data.Canonical())
+			}
+
+			// The formatted value reads back the same
+			again, err := Parse(tt.recordType, data.String())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if again.String() != tt.formatted || !Equal(again, data) {
+				t.Errorf("Expected %q to round-trip, got %q", tt.formatted, again.String())
+			}
+		})
+	}
+
+	built := &AAAA{Addr: netip.MustParseAddr("2001:DB8::1")}
+	if built.String() != "2001:db8::1" {
+		t.Errorf("Expected an AAAA built from its address to be formatted as 2001:db8::1, got %q", built.String())
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		recordType string
+		value      string
+	}{
+		{"A", "2001:db8::1"},
+		{"A", "192.168.1"},
+		{"AAAA", "192.168.1.1"},
+		{"CNAME", "two names"},
+		{"CNAME", " "},
+		{"MX", "mail.example.com"},
+		{"MX", "70000 mail.example.com"},
+		{"SRV", "10 60 sip.example.com"},
+		{"TXT", ""},
+		{"CAA", `0 issue`},
+		{"CAA", `256 issue "ca.example.net"`},
+		{"CAA", `0 is-sue "ca.example.net"`},
+		{"CAA", `0 issue "ca.example.net`},
+		{"TLSA", "3 1 1 xyz"},
+		{"SSHFP", "4 2 abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.recordType+"/"+tt.value, func(t *testing.T) {
+			if data, err := Parse(tt.recordType, tt.value); err == nil {
+				t.Errorf("Expected an error, got %#v", data)
+			}
+		})
+	}
+
+	if _, err := Parse("LOC", "52 22 23.000 N 4 53 32.000 E -2.00m"); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("Expected ErrUnsupportedType, got %v", err)
+	}
+}
+
+func TestEqualValues(t *testing.T) {
+	tests := []struct {
+		recordType string
+		a, b       string
+		equal      bool
+	}{
+		{"A", "192.168.1.1", " 192.168.1.1 ", true},
+		{"AAAA", "2001:DB8:0:0::1", "2001:db8::1", true},
+		{"CNAME", "www.example.com", "WWW.EXAMPLE.COM.", true},
+		{"MX", "10 mail.example.com", "10  mail.example.com.", true},
+		{"MX", "10 mail.example.com", "20 mail.example.com", false},
+		{"SRV", "10 60 5060 sip.example.com", "10 60 5061 sip.example.com", false},
+		{"TXT", "v=spf1 -all", `"v=spf1 -all"`, true},
+		{"TXT", `"v=spf1 " "-all"`, `"v=spf1 -all"`, true},
+		{"TXT", "token=ABC", "token=abc", false},
+		{"CAA", `0 issue "letsencrypt.org"`, `0 ISSUE letsencrypt.org`, true},
+		{"TLSA", "3 1 1 ABCD", "3 1 1 abcd", true},
+		{"loc", "Some Value.", "some value", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.recordType+"/"+tt.a, func(t *testing.T) {
+			if got := EqualValues(tt.recordType, tt.a, tt.b); got != tt.equal {
+				t.Errorf("EqualValues(%q, %q, %q) = %t, want %t", tt.recordType, tt.a, tt.b, got, tt.equal)
+			}
+		})
+	}
+}
+
+func TestEqual(t *testing.T) {
+	cname, _ := ParseCNAME("www.example.com.")
+	ns, _ := ParseNS("www.example.com")
+	other, _ := ParseCNAME("WWW.example.com")
+
+	if !Equal(cname, other) {
+		t.Errorf("Expected %s and %s to be equal", cname, other)
+	}
+	if Equal(cname, ns) {
+		t.Errorf("Expected records of different types to differ")
+	}
+}
+
+func TestSupported(t *testing.T) {
+	for _, recordType := range []string{"A", "aaaa", "CNAME", "MX", "TXT", "SRV", "CAA", "NS", "TLSA", "SSHFP", "PTR"} {
+		if !Supported(recordType) {
+			t.Errorf("Expected %s to be supported", recordType)
+		}
+	}
+	if Supported("SOA") {
+		t.Errorf("Expected SOA to be unsupported")
+	}
+}
@@ -0,0 +1,160 @@
+package rdata
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TXT is the data of a TXT record. LWS takes the text as is; quoted
+// character strings, e.g. `"v=spf1 " "-all"`, are accepted too and keep
+// their quoting when formatted.
+type TXT struct {
+	// Strings holds the character strings, without quotes or escapes.
+	Strings []string
+	// Quoted is set when the value was written as quoted strings.
+	Quoted bool
+}
+
+// ParseTXT parses the text of a TXT record. A value that starts with a quote
+// but is not a valid sequence of quoted strings is taken as plain text.
+func ParseTXT(value string) (*TXT, error) {
+	text := strings.TrimSpace(value)
+	if text == "" {
+		return nil, invalid("TXT", value, "expected text")
+	}
+
+	if strings.HasPrefix(text, `"`) {
+		if strs, ok := splitQuoted(text); ok {
+			return &TXT{Strings: strs, Quoted: true}, nil
+		}
+	}
+
+	return &TXT{Strings: []string{text}}, nil
+}
+
+// Type implements RData
+func (d *TXT) Type() string { return "TXT" }
+
+// String implements RData
+func (d *TXT) String() string {
+	if !d.Quoted {
+		return strings.Join(d.Strings, "")
+	}
+
+	quoted := make([]string, len(d.Strings))
+	for i, s := range d.Strings {
+		quoted[i] = quote(s)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// Canonical implements RData. Text is case sensitive, only the quoting and
+// the split into character strings are dropped.
+func (d *TXT) Canonical() string { return strings.Join(d.Strings, "") }
+
+// CAA is the data of a CAA record, e.g. `0 issue "letsencrypt.org"`.
+type CAA struct {
+	Flags uint8
+	Tag   string
+	Value string
+	// Quoted is set when the value was written between quotes.
+	Quoted bool
+}
+
+// ParseCAA parses flags, a property tag and its value.
+func ParseCAA(value string) (*CAA, error) {
+	text := strings.TrimSpace(value)
+	parts := strings.Fields(text)
+	if len(parts) < 3 {
+		return nil, invalid("CAA", value, "expected flags, tag and value")
+	}
+
+	flags, err := parseUint("CAA", value, "flags", parts[0], 8)
+	if err != nil {
+		return nil, err
+	}
+
+	tag := parts[1]
+	if tag == "" || strings.IndexFunc(tag, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+	}) >= 0 {
+		return nil, invalid("CAA", value, fmt.Sprintf("invalid tag %q", tag))
+	}
+
+	rest := strings.TrimSpace(strings.TrimPrefix(text, parts[0]))
+	rest = strings.TrimSpace(strings.TrimPrefix(rest, tag))
+
+	data := &CAA{Flags: uint8(flags), Tag: tag, Value: rest}
+	if strings.HasPrefix(data.Value, `"`) {
+		strs, ok := splitQuoted(data.Value)
+		if !ok || len(strs) != 1 {
+			return nil, invalid("CAA", value, "expected a single quoted value")
+		}
+		data.Value, data.Quoted = strs[0], true
+	}
+
+	return data, nil
+}
+
+// Type implements RData
+func (d *CAA) Type() string { return "CAA" }
+
+// String implements RData
+func (d *CAA) String() string {
+	if d.Quoted {
+		return fmt.Sprintf("%d %s %s", d.Flags, d.Tag, quote(d.Value))
+	}
+	return fmt.Sprintf("%d %s %s", d.Flags, d.Tag, d.Value)
+}
+
+// Canonical implements RData. Tags are case insensitive, values are not.
+func (d *CAA) Canonical() string {
+	return fmt.Sprintf("%d %s %s", d.Flags, strings.ToLower(d.Tag), quote(d.Value))
+}
+
+// splitQuoted splits a sequence of space separated quoted strings, handling
+// backslash escapes. It reports false when text is not such a sequence.
+func splitQuoted(text string) ([]string, bool) {
+	var strs []string
+	for {
+		text = strings.TrimLeft(text, " \t")
+		if text == "" {
+			return strs, len(strs) > 0
+		}
+		if text[0] != '"' {
+			return nil, false
+		}
+
+		var b strings.Builder
+		closed := false
+		i := 1
+		for ; i < len(text); i++ {
+			switch c := text[i]; {
+			case c == '\\' && i+1 < len(text):
+				i++
+				b.WriteByte(text[i])
+			case c == '"':
+				closed = true
+			default:
+				b.WriteByte(c)
+			}
+			if closed {
+				break
+			}
+		}
+		if !closed {
+			return nil, false
+		}
+
+		strs = append(strs, b.String())
+		text = text[i+1:]
+		if text != "" && text[0] != ' ' && text[0] != '\t' {
+			return nil, false
+		}
+	}
+}
+
+// quote returns s between double quotes, escaping quotes and backslashes.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// RequestIDHeader carries the identifier of the logical operation a request
+// belongs to, so a failing call can be pointed out to LWS support.
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// NewRequestID returns a random UUID (version 4).
+func NewRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// WithRequestID returns a context whose API calls, retries included, are
+// all sent with the given request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx, if any.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// withOperation prepares the context of a client operation: it gets a
+// request ID unless the caller already set one, and the tflog subsystem.
+func withOperation(ctx context.Context) context.Context {
+	if RequestIDFromContext(ctx) == "" {
+		ctx = WithRequestID(ctx, NewRequestID())
+	}
+	return withLogSubsystem(ctx)
+}
+
+type requestIDErrorKey struct{}
+
+// withRequestIDError marks ctx as belonging to an API call whose error gets
+// the request ID, and reports whether ctx was not marked already. Calls
+// made within another one, such as the lookups of a retry guard, leave it
+// to the outer call, so the ID shows once in its error.
+func withRequestIDError(ctx context.Context) (context.Context, bool) {
+	if ctx.Value(requestIDErrorKey{}) != nil {
+		return ctx, false
+	}
+	return context.WithValue(ctx, requestIDErrorKey{}, true), true
+}
+
+// requestIDError adds the request ID to the message of errors that don't
+// carry it themselves.
+type requestIDError struct {
+	err       error
+	requestID string
+}
+
+func (e *requestIDError) Error() string {
+	return fmt.Sprintf("%s (request ID: %s)", e.err, e.requestID)
+}
+
+func (e *requestIDError) Unwrap() error {
+	return e.err
+}
+
+// As finds the APIError wrapped by e with the request ID of the operation,
+// without changing the wrapped one.
+func (e *requestIDError) As(target interface{}) bool {
+	ptr, ok := target.(**APIError)
+	if !ok {
+		return false
+	}
+	var apiErr *APIError
+	if !errors.As(e.err, &apiErr) {
+		return false
+	}
+	*ptr = apiErr.withRequestID(e.requestID)
+	return true
+}
+
+// withRequestID attaches the request ID to an error returned by an API call.
+// The error is never changed, since it may be shared with other operations,
+// e.g. as the cause of every error of an open circuit breaker.
+func withRequestID(err error, requestID string) error {
+	if err == nil || requestID == "" {
+		return err
+	}
+
+	if apiErr, ok := err.(*APIError); ok {
+		return apiErr.withRequestID(requestID)
+	}
+	return &requestIDError{err: err, requestID: requestID}
+}
+
+// withRequestID returns a copy of e carrying the request ID, or e when it
+// already carries one.
+func (e *APIError) withRequestID(requestID string) *APIError {
+	if e.RequestID != "" {
+		return e
+	}
+	copied := *e
+	copied.RequestID = requestID
+	return &copied
+}
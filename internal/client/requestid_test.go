@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNewRequestID(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	first, second := NewRequestID(), NewRequestID()
+	if !uuid.MatchString(first) {
+		t.Errorf("Expected a UUID v4, got %q", first)
+	}
+	if first == second {
+		t.Errorf("Expected distinct request IDs, got %q twice", first)
+	}
+}
+
+func TestLWSClient_RequestIDAcrossRetries(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Header.Get(RequestIDHeader))
+		mu.Unlock()
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = w.Write([]byte(`{"code": 502, "info": "Bad gateway", "data": null}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server.URL,
+		WithRetryPolicy(NewDefaultRetryPolicy(2, 0, 1)),
+		WithCircuitBreaker(0, 0),
+	)
+
+	_, err := client.GetDNSZone(context.Background(), testDomainName)
+	if err == nil {
+		t.Fatalf("Expected an error")
+	}
+
+	if len(seen) != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", len(seen))
+	}
+	for _, id := range seen[1:] {
+		if id != seen[0] || id == "" {
+			t.Errorf("Expected every attempt to carry the same request ID, got %v", seen)
+		}
+	}
+
+	if !strings.Contains(err.Error(), "request ID: "+seen[0]) {
+		t.Errorf("Expected the error to contain the request ID, got %q", err.Error())
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.RequestID != seen[0] {
+		t.Errorf("Expected an APIError carrying the request ID, got %v", err)
+	}
+}
+
+func TestLWSClient_RequestIDFromContext(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(RequestIDHeader)
+		_, _ = w.Write([]byte(`{"code": 200, "info": "Fetched DNS Zone", "data": []}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server.URL)
+	ctx := WithRequestID(context.Background(), "terraform-apply-42")
+	if _, err := client.GetDNSZone(ctx, testDomainName); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if received != "terraform-apply-42" {
+		t.Errorf("Expected the caller's request ID, got %q", received)
+	}
+}
+
+func TestWithRequestID_TransportError(t *testing.T) {
+	err := withRequestID(errors.New("connection refused"), "abc")
+	if err.Error() != "connection refused (request ID: abc)" {
+		t.Errorf("Unexpected message %q", err.Error())
+	}
+}
+
+func TestLWSClient_RequestIDOnceInGuardedErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The create fails, then so does the listing checking whether it
+		// landed
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = w.Write([]byte(`{"code": 502, "info": "Bad gateway", "data": null}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server.URL,
+		WithRetryPolicy(NewDefaultRetryPolicy(1, 0, 1)),
+		WithCircuitBreaker(0, 0),
+	)
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	_, err := client.CreateDNSRecord(ctx, &DNSRecord{Name: "www", Type: "A", Value: testIP4Address, Zone: testDomainName, TTL: 3600})
+	if err == nil || !strings.Contains(err.Error(), "unable to check whether POST") {
+		t.Fatalf("Expected the guard to fail, got %v", err)
+	}
+	if count := strings.Count(err.Error(), "request ID: req-1"); count != 1 {
+		t.Errorf("Expected the request ID once, got %d times in %q", count, err.Error())
+	}
+}
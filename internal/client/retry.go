@@ -0,0 +1,172 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy decides whether and when a failed API call is attempted again.
+type RetryPolicy interface {
+	// MaxAttempts returns the total number of attempts, including the first one.
+	MaxAttempts() int
+	// ShouldRetry reports whether a call made with the given HTTP method that
+	// failed with err may be sent again.
+	ShouldRetry(method string, err error) bool
+	// Backoff returns how long to wait before the given retry (1 for the
+	// first retry). err is the error of the attempt that just failed.
+	Backoff(retry int, err error) time.Duration
+}
+
+// DefaultRetryPolicy retries transient failures with a jittered exponential
+// backoff, honouring Retry-After when the API sends one.
+//
+// Idempotent methods (GET, PUT, DELETE) are retried on transport errors,
+// throttling, Cloudflare challenges and 5xx answers. POST is only retried
+// when the API clearly refused the request before processing it (429, 503
+// or a challenge page), since a timed-out create may already have landed.
+// The client retries creates like idempotent calls anyway, once it has
+// checked that the zone does not hold the record yet.
+type DefaultRetryPolicy struct {
+	// Retries is the number of retries after the first attempt.
+	Retries int
+	// BaseDelay is the wait before the first retry.
+	BaseDelay time.Duration
+	// Multiplier is applied to the delay after each retry. Values below 1
+	// are treated as 1.
+	Multiplier float64
+	// MaxDelay caps the computed delay, Retry-After included. Zero means no cap.
+	MaxDelay time.Duration
+	// Jitter is the fraction (0 to 1) of the delay that is randomised, so
+	// parallel resources don't retry in lockstep.
+	Jitter float64
+}
+
+// NewDefaultRetryPolicy returns a DefaultRetryPolicy with 20% jitter and a
+// five minute cap on a single wait.
+func NewDefaultRetryPolicy(retries int, baseDelay time.Duration, multiplier float64) *DefaultRetryPolicy {
+	return &DefaultRetryPolicy{
+		Retries:    retries,
+		BaseDelay:  baseDelay,
+		Multiplier: multiplier,
+		MaxDelay:   5 * time.Minute,
+		Jitter:     0.2,
+	}
+}
+
+// MaxAttempts implements RetryPolicy
+func (p *DefaultRetryPolicy) MaxAttempts() int {
+	if p.Retries < 0 {
+		return 1
+	}
+	return p.Retries + 1
+}
+
+// ShouldRetry implements RetryPolicy
+func (p *DefaultRetryPolicy) ShouldRetry(method string, err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || IsNotRecorded(err) {
+		return false
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		// Transport error: the request may or may not have reached LWS.
+		return isIdempotent(method)
+	}
+
+	if apiErr.Challenge || IsRateLimited(err) || apiErr.hasStatus(http.StatusServiceUnavailable) {
+		return true
+	}
+
+	if !isIdempotent(method) {
+		return false
+	}
+
+	return apiErr.hasStatus(http.StatusRequestTimeout) ||
+		apiErr.StatusCode >= 500 || apiErr.Code >= 500
+}
+
+// Backoff implements RetryPolicy
+func (p *DefaultRetryPolicy) Backoff(retry int, err error) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(p.BaseDelay) * math.Pow(multiplier, float64(retry-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+
+	if p.Jitter > 0 {
+		jitter := math.Min(p.Jitter, 1)
+		delay -= delay * jitter * rand.Float64()
+	}
+
+	wait := time.Duration(delay)
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > wait {
+		wait = apiErr.RetryAfter
+	}
+
+	if p.MaxDelay > 0 && wait > p.MaxDelay {
+		wait = p.MaxDelay
+	}
+
+	return wait
+}
+
+// isIdempotent reports whether sending the same request twice has the same
+// effect as sending it once.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter reads a Retry-After header, either in seconds or as an
+// HTTP date. It returns zero when the header is missing or invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := date.Sub(now); wait > 0 {
+			return wait
+		}
+	}
+
+	return 0
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,355 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDefaultRetryPolicy_ShouldRetry(t *testing.T) {
+	policy := NewDefaultRetryPolicy(3, time.Millisecond, 2)
+
+	tests := []struct {
+		name   string
+		method string
+		err    error
+		want   bool
+	}{
+		{"transport error on GET", http.MethodGet, errors.New("connection reset"), true},
+		{"transport error on POST", http.MethodPost, errors.New("connection reset"), false},
+		{"cancelled context", http.MethodGet, fmt.Errorf("wrapped: %w", context.Canceled), false},
+		{"unauthorized", http.MethodGet, &APIError{StatusCode: http.StatusUnauthorized, Code: 401}, false},
+		{"not found", http.MethodDelete, &APIError{StatusCode: http.StatusNotFound, Code: 404}, false},
+		{"bad request", http.MethodPut, &APIError{StatusCode: http.StatusBadRequest, Code: 400}, false},
+		{"server error on GET", http.MethodGet, &APIError{StatusCode: http.StatusBadGateway}, true},
+		{"server error on POST", http.MethodPost, &APIError{StatusCode: http.StatusInternalServerError}, false},
+		{"unavailable on POST", http.MethodPost, &APIError{StatusCode: http.StatusServiceUnavailable}, true},
+		{"rate limited on POST", http.MethodPost, &APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{"challenge on POST", http.MethodPost, &APIError{StatusCode: http.StatusForbidden, Challenge: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.ShouldRetry(tt.method, tt.err); got != tt.want {
+				t.Errorf("ShouldRetry(%s, %v) = %v, want %v", tt.method, tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultRetryPolicy_Backoff(t *testing.T) {
+	policy := &DefaultRetryPolicy{
+		Retries:    5,
+		BaseDelay:  100 * time.Millisecond,
+		Multiplier: 2,
+		MaxDelay:   time.Second,
+		Jitter:     0.5,
+	}
+
+	for retry := 1; retry <= 5; retry++ {
+		nominal := 100 * time.Millisecond << (retry - 1)
+		if nominal > time.Second {
+			nominal = time.Second
+		}
+
+		for i := 0; i < 20; i++ {
+			wait := policy.Backoff(retry, errors.New("boom"))
+			if wait > nominal || wait < nominal/2 {
+				t.Fatalf("retry %d: wait %s outside jitter range [%s, %s]", retry, wait, nominal/2, nominal)
+			}
+		}
+	}
+
+	// Retry-After wins over a shorter computed delay but stays capped
+	wait := policy.Backoff(1, &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 700 * time.Millisecond})
+	if wait != 700*time.Millisecond {
+		t.Errorf("Expected Retry-After of 700ms to be honoured, got %s", wait)
+	}
+
+	wait = policy.Backoff(1, &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Hour})
+	if wait != time.Second {
+		t.Errorf("Expected Retry-After to be capped to 1s, got %s", wait)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if got := parseRetryAfter("", now); got != 0 {
+		t.Errorf("Expected 0 for empty header, got %s", got)
+	}
+	if got := parseRetryAfter("7", now); got != 7*time.Second {
+		t.Errorf("Expected 7s, got %s", got)
+	}
+	if got := parseRetryAfter("Mon, 01 Jan 2024 12:00:30 GMT", now); got != 30*time.Second {
+		t.Errorf("Expected 30s, got %s", got)
+	}
+	if got := parseRetryAfter("garbage", now); got != 0 {
+		t.Errorf("Expected 0 for invalid header, got %s", got)
+	}
+}
+
+func TestLWSClient_RetryDoesNotRetryAuthErrors(t *testing.T) {
+	var requestCount int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requestCount, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"code": 401, "info": "Unauthorized", "data": null}`))
+	}))
+	defer server.Close()
+
+	// A long delay would make the test hang if the 401 were retried
+	client := NewLWSClient("testlogin", "wrongkey", server.URL, true, 30, 3, 15, 2)
+
+	start := time.Now()
+	_, err := client.GetDNSZone(context.Background(), testDomainName)
+	if !IsAuth(err) {
+		t.Fatalf("Expected auth error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Auth error took %s, expected immediate failure", elapsed)
+	}
+	if requestCount != 1 {
+		t.Errorf("Expected 1 request, got %d", requestCount)
+	}
+}
+
+func TestLWSClient_RetryResendsBody(t *testing.T) {
+	var requestCount int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requestCount, 1)
+
+		body, _ := io.ReadAll(r.Body)
+		if len(body) == 0 {
+			t.Errorf("Attempt %d was sent without a body", n)
+		}
+
+		if n == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte(`{"code": 502, "info": "Bad gateway", "data": null}`))
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"code": 200, "info": "Record updated", "data": {"id": 12345, "name": "www", "type": "A", "value": "192.168.1.2", "ttl": 3600}}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server.URL, WithRetryPolicy(NewDefaultRetryPolicy(2, time.Millisecond, 1)))
+
+	record := &DNSRecord{ID: 12345, Name: "www", Type: "A", Value: "192.168.1.2", Zone: testDomainName, TTL: 3600}
+	if _, err := client.UpdateDNSRecord(context.Background(), record); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if requestCount != 2 {
+		t.Errorf("Expected 2 attempts, got %d", requestCount)
+	}
+}
+
+func TestLWSClient_RetryHonoursContextCancellation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"code": 503, "info": "Unavailable", "data": null}`))
+	}))
+	defer server.Close()
+
+	client := NewLWSClient("testlogin", "testkey", server.URL, true, 30, 5, 60, 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.GetDNSZone(ctx, testDomainName)
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Cancellation took %s, expected the backoff to be interrupted", elapsed)
+	}
+}
+
+// flakyZoneServer is an LWS zone whose create endpoint misbehaves on the
+// first POST: it may commit the record and then lose the answer.
+type flakyZoneServer struct {
+	mu      sync.Mutex
+	records []DNSRecord
+	posts   int
+	// commitFirst stores the record of the first POST before failing it
+	commitFirst bool
+	// fail breaks the first POST once it has been handled
+	fail func(w http.ResponseWriter)
+	// zoneStatus, when set, is returned for every listing after the first POST
+	zoneStatus int
+}
+
+func (s *flakyZoneServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+
+	switch r.Method {
+	case http.MethodGet:
+		if s.zoneStatus != 0 && s.posts > 0 {
+			s.mu.Unlock()
+			w.WriteHeader(s.zoneStatus)
+			_, _ = w.Write([]byte(`{"code": 500, "info": "Internal error", "data": null}`))
+			return
+		}
+		data, _ := json.Marshal(s.records)
+		s.mu.Unlock()
+		_, _ = fmt.Fprintf(w, `{"code": 200, "info": "Fetched DNS Zone", "data": %s}`, data)
+
+	case http.MethodPost:
+		s.posts++
+		first := s.posts == 1
+
+		var req CreateDNSRecordRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if !first || s.commitFirst {
+			s.records = append(s.records, DNSRecord{ID: 100 + len(s.records), Name: req.Name, Type: req.Type, Value: req.Value, TTL: req.TTL})
+		}
+		s.mu.Unlock()
+
+		if first {
+			s.fail(w)
+			return
+		}
+		_, _ = w.Write([]byte(`{"code": 200, "info": "Added a new line in the DNS Zone", "data": {}}`))
+	}
+}
+
+func dropConnection(w http.ResponseWriter) {
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err == nil {
+		_ = conn.Close()
+	}
+}
+
+func TestLWSClient_CreateDNSRecordSurvivesLostResponses(t *testing.T) {
+	tests := []struct {
+		name          string
+		server        *flakyZoneServer
+		expectedPosts int
+		expectError   bool
+	}{
+		{
+			name:          "connection dropped after commit",
+			server:        &flakyZoneServer{commitFirst: true, fail: dropConnection},
+			expectedPosts: 1,
+		},
+		{
+			name: "timeout after commit",
+			server: &flakyZoneServer{commitFirst: true, fail: func(w http.ResponseWriter) {
+				time.Sleep(500 * time.Millisecond)
+			}},
+			expectedPosts: 1,
+		},
+		{
+			name: "server error after commit",
+			server: &flakyZoneServer{commitFirst: true, fail: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusBadGateway)
+				_, _ = w.Write([]byte(`{"code": 502, "info": "Bad gateway", "data": null}`))
+			}},
+			expectedPosts: 1,
+		},
+		{
+			name:          "connection dropped before commit",
+			server:        &flakyZoneServer{fail: dropConnection},
+			expectedPosts: 2,
+		},
+		{
+			name:          "zone unreadable after a dropped response",
+			server:        &flakyZoneServer{commitFirst: true, fail: dropConnection, zoneStatus: http.StatusInternalServerError},
+			expectedPosts: 1,
+			expectError:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.server)
+			defer server.Close()
+
+			client := newTestClient(t, server.URL,
+				WithTimeout(200*time.Millisecond),
+				WithRetryPolicy(NewDefaultRetryPolicy(2, time.Millisecond, 1)),
+			)
+
+			record, err := client.CreateDNSRecord(context.Background(), &DNSRecord{Name: "www", Type: "A", Value: "192.168.1.1", Zone: testDomainName, TTL: 3600})
+
+			tt.server.mu.Lock()
+			defer tt.server.mu.Unlock()
+
+			if tt.server.posts != tt.expectedPosts {
+				t.Errorf("Expected %d POST, got %d", tt.expectedPosts, tt.server.posts)
+			}
+			if len(tt.server.records) != 1 {
+				t.Errorf("Expected exactly one record in the zone, got %d", len(tt.server.records))
+			}
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected an error when the zone cannot be checked, got record %+v", record)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if record.ID != tt.server.records[0].ID || record.Zone != testDomainName {
+				t.Errorf("Expected record %d of %s, got %+v", tt.server.records[0].ID, testDomainName, record)
+			}
+		})
+	}
+}
+
+func TestLWSClient_CreateDNSRecordFindsIDByValue(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			_, _ = w.Write([]byte(`{"code": 200, "info": "Added a new line in the DNS Zone", "data": {}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"code": 200, "info": "Fetched DNS Zone", "data": [
+			{"id": 1, "name": "www", "type": "A", "value": "192.168.1.1", "ttl": 3600},
+			{"id": 2, "name": "www", "type": "A", "value": "192.168.1.2", "ttl": 3600}
+		]}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server.URL)
+	record, err := client.CreateDNSRecord(context.Background(), &DNSRecord{Name: "www", Type: "A", Value: testIP4Address, Zone: testDomainName, TTL: 3600})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if record.ID != 2 {
+		t.Errorf("Expected the ID of the record with the created value, got %d", record.ID)
+	}
+}
+
+func TestSameRecord(t *testing.T) {
+	tests := []struct {
+		a, b DNSRecord
+		want bool
+	}{
+		{DNSRecord{Name: "www", Type: "A", Value: "192.168.1.1"}, DNSRecord{Name: "WWW", Type: "a", Value: " 192.168.1.1 "}, true},
+		{DNSRecord{Name: "www", Type: "A", Value: "192.168.1.1"}, DNSRecord{Name: "www", Type: "A", Value: "192.168.1.2"}, false},
+		{DNSRecord{Name: "www", Type: "A", Value: "192.168.1.1"}, DNSRecord{Name: "www", Type: "AAAA", Value: "192.168.1.1"}, false},
+		{DNSRecord{Name: "mail", Type: "CNAME", Value: "Mx.Example.com."}, DNSRecord{Name: "mail.", Type: "CNAME", Value: "mx.example.com"}, true},
+		{DNSRecord{Name: "@", Type: "TXT", Value: `"v=spf1 -all"`}, DNSRecord{Name: "@", Type: "TXT", Value: "v=spf1 -all"}, true},
+		{DNSRecord{Name: "@", Type: "TXT", Value: "Token"}, DNSRecord{Name: "@", Type: "TXT", Value: "token"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := sameRecord(tt.a, tt.b); got != tt.want {
+			t.Errorf("sameRecord(%+v, %+v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
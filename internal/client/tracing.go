@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// TracerName is the instrumentation scope of the client spans.
+const TracerName = "github.com/M4XGO/terraform-provider-lws/internal/client"
+
+// newTracer returns the tracer of a client, nil when tracing is off.
+func newTracer(provider trace.TracerProvider) trace.Tracer {
+	if provider == nil {
+		return nil
+	}
+	return provider.Tracer(TracerName)
+}
+
+// startAttempt opens the span of an attempt of an API call, a child of the
+// operation span in ctx. Without a tracer it returns ctx unchanged and a
+// span that does nothing, so untraced clients pay nothing for it.
+func (c *LWSClient) startAttempt(ctx context.Context, method, endpoint string, attempt, maxAttempts int) (context.Context, trace.Span) {
+	if c.tracer == nil {
+		return ctx, noop.Span{}
+	}
+
+	template := metricsEndpoint(endpoint)
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(method),
+		semconv.URLTemplate(template),
+		attribute.String("lws.endpoint", endpoint),
+		attribute.Int("lws.attempt", attempt),
+		attribute.Int("lws.max_attempts", maxAttempts),
+	}
+	if attempt > 1 {
+		attrs = append(attrs, semconv.HTTPRequestResendCount(attempt-1))
+	}
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		attrs = append(attrs, attribute.String("lws.request_id", requestID))
+	}
+	return c.tracer.Start(ctx, method+" "+template,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// traceExchange records on the attempt span in ctx which API address
+// answered and how. With failover, an attempt may reach several addresses:
+// each one adds an event and the last one sets the span attributes.
+func traceExchange(ctx context.Context, rawURL string, status int, err error) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+
+	attrs := []attribute.KeyValue{semconv.URLFull(rawURL)}
+	if u, parseErr := url.Parse(rawURL); parseErr == nil {
+		attrs = append(attrs, semconv.ServerAddress(u.Hostname()))
+	}
+	if status != 0 {
+		attrs = append(attrs, semconv.HTTPResponseStatusCode(status))
+	}
+	span.SetAttributes(attrs...)
+
+	event := append([]attribute.KeyValue{}, attrs...)
+	if err != nil {
+		event = append(event, attribute.String("error", err.Error()))
+	}
+	span.AddEvent("lws.exchange", trace.WithAttributes(event...))
+}
+
+// endAttempt closes the span of an attempt. retry tells whether the call
+// is attempted again after wait.
+func endAttempt(span trace.Span, err error, retry bool, wait time.Duration) {
+	if !span.IsRecording() {
+		return
+	}
+
+	span.SetAttributes(attribute.Bool("lws.retry", retry))
+	if retry {
+		span.SetAttributes(attribute.Int64("lws.retry_wait_ms", wait.Milliseconds()))
+	}
+	if err != nil {
+		span.SetAttributes(semconv.ErrorTypeKey.String(errorType(err)))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// errorType classifies an attempt error for the error.type attribute: the
+// status code of API errors, the kind of failure otherwise.
+func errorType(err error) string {
+	var apiErr *APIError
+	switch {
+	case IsCircuitOpen(err):
+		return "circuit_open"
+	case IsChallenge(err):
+		return "challenge"
+	case errors.As(err, &apiErr):
+		return strconv.Itoa(apiErr.StatusCode)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "cancelled"
+	default:
+		return "transport"
+	}
+}
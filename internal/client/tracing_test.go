@@ -0,0 +1,107 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// spanAttributes indexes the attributes of a span by key.
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestLWSClient_Tracing(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		call := calls
+		mu.Unlock()
+
+		if call == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte(`{"code": 502, "info": "Bad gateway", "data": null}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"code": 200, "info": "Fetched DNS Zone", "data": []}`))
+	}))
+	defer server.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	client := newTestClient(t, server.URL,
+		WithRetryPolicy(&DefaultRetryPolicy{Retries: 1, Multiplier: 1}),
+		WithTracerProvider(provider),
+	)
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "lws_dns_zone.Read")
+	if _, err := client.GetDNSZone(WithRequestID(ctx, "req-1"), testDomainName); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 2 attempt spans and the parent span, got %d", len(spans))
+	}
+	for i, span := range spans[:2] {
+		if span.Name() != "GET domain/{zone}/zdns" || span.SpanKind() != trace.SpanKindClient {
+			t.Errorf("Unexpected span %q of kind %s", span.Name(), span.SpanKind())
+		}
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("Expected attempt %d to be a child of the operation span", i+1)
+		}
+
+		attrs := spanAttributes(span)
+		if attrs["lws.attempt"].AsInt64() != int64(i+1) || attrs["lws.max_attempts"].AsInt64() != 2 {
+			t.Errorf("Unexpected attempt attributes %v", attrs)
+		}
+		if attrs["lws.request_id"].AsString() != "req-1" || attrs["lws.endpoint"].AsString() != "domain/"+testDomainName+"/zdns" {
+			t.Errorf("Unexpected request attributes %v", attrs)
+		}
+		if attrs["url.full"].AsString() != server.URL+"/domain/"+testDomainName+"/zdns" {
+			t.Errorf("Unexpected url.full %v", attrs["url.full"])
+		}
+	}
+
+	first, second := spanAttributes(spans[0]), spanAttributes(spans[1])
+	if first["http.response.status_code"].AsInt64() != 502 || !first["lws.retry"].AsBool() || first["error.type"].AsString() != "502" {
+		t.Errorf("Expected the first attempt to fail with a 502 and be retried, got %v", first)
+	}
+	if spans[0].Status().Code != codes.Error {
+		t.Errorf("Expected the first attempt to be marked failed, got %v", spans[0].Status())
+	}
+	if second["http.response.status_code"].AsInt64() != 200 || second["lws.retry"].AsBool() || second["http.request.resend_count"].AsInt64() != 1 {
+		t.Errorf("Expected the second attempt to succeed, got %v", second)
+	}
+	if spans[1].Status().Code == codes.Error {
+		t.Errorf("Expected the second attempt not to be marked failed")
+	}
+}
+
+func TestLWSClient_NoTracing(t *testing.T) {
+	client := newTestClient(t, "http://127.0.0.1")
+
+	ctx := context.Background()
+	attemptCtx, span := client.startAttempt(ctx, http.MethodGet, "domain/"+testDomainName+"/zdns", 1, 1)
+	if attemptCtx != ctx || span.IsRecording() {
+		t.Errorf("Expected an untraced client not to create spans")
+	}
+	endAttempt(span, nil, false, 0)
+}
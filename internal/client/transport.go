@@ -0,0 +1,200 @@
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// TransportOptions customises how the client reaches the API, for networks
+// with an egress proxy, TLS interception or mutual TLS.
+type TransportOptions struct {
+	// ProxyURL is the proxy used for every request. When empty, the
+	// HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables apply.
+	ProxyURL string
+	// CACertFile and CACertPEM add certificate authorities to the system
+	// pool, e.g. the CA of an intercepting proxy.
+	CACertFile string
+	CACertPEM  string
+	// ClientCert and ClientKey are a PEM encoded certificate and key, or
+	// paths to files holding them, presented for mutual TLS.
+	ClientCert string
+	ClientKey  string
+	// InsecureSkipVerify disables server certificate verification.
+	InsecureSkipVerify bool
+	// PinnedPublicKeys are base64 SHA-256 hashes of the subject public key
+	// info, optionally prefixed with "sha256/". When set, connections to
+	// PinnedHost must present a certificate matching one of them.
+	PinnedPublicKeys []string
+	PinnedHost       string
+}
+
+// IsZero reports whether no option is set, in which case the default
+// transport is used.
+func (o TransportOptions) IsZero() bool {
+	return o.ProxyURL == "" && o.CACertFile == "" && o.CACertPEM == "" &&
+		o.ClientCert == "" && o.ClientKey == "" && !o.InsecureSkipVerify &&
+		len(o.PinnedPublicKeys) == 0
+}
+
+// NewTransport builds an http.Transport from the options, starting from the
+// settings of http.DefaultTransport. Invalid options are reported as
+// ConfigErrors.
+func NewTransport(opts TransportOptions) (*http.Transport, error) {
+	var errs ConfigErrors
+	add := func(field, summary, detail string) {
+		errs = append(errs, &ConfigError{Field: field, Summary: summary, Detail: detail})
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if opts.ProxyURL != "" {
+		proxyURL, err := url.Parse(opts.ProxyURL)
+		if err != nil || proxyURL.Host == "" || !isProxyScheme(proxyURL.Scheme) {
+			add("proxy_url", "Invalid proxy_url value",
+				fmt.Sprintf("The proxy_url value %q must be an http, https or socks5 URL.", opts.ProxyURL))
+		} else {
+			transport.Proxy = http.ProxyURL(proxyURL)
+		}
+	}
+
+	if opts.CACertFile != "" || opts.CACertPEM != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		if opts.CACertFile != "" {
+			pem, err := os.ReadFile(opts.CACertFile)
+			if err != nil {
+				add("ca_cert_file", "Invalid ca_cert_file value", fmt.Sprintf("Unable to read the CA bundle: %s", err))
+			} else if !pool.AppendCertsFromPEM(pem) {
+				add("ca_cert_file", "Invalid ca_cert_file value", fmt.Sprintf("No PEM certificate found in %s.", opts.CACertFile))
+			}
+		}
+
+		if opts.CACertPEM != "" && !pool.AppendCertsFromPEM([]byte(opts.CACertPEM)) {
+			add("ca_cert_pem", "Invalid ca_cert_pem value", "No PEM certificate found in ca_cert_pem.")
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	switch {
+	case opts.ClientCert != "" && opts.ClientKey != "":
+		cert, err := loadClientCertificate(opts.ClientCert, opts.ClientKey)
+		if err != nil {
+			add("client_cert", "Invalid client certificate", err.Error())
+		} else {
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+	case opts.ClientCert != "":
+		add("client_key", "Missing client_key value", "A client_key is required when client_cert is set.")
+	case opts.ClientKey != "":
+		add("client_cert", "Missing client_cert value", "A client_cert is required when client_key is set.")
+	}
+
+	if opts.InsecureSkipVerify {
+		// #nosec G402 -- explicitly requested, the provider warns about it
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	if len(opts.PinnedPublicKeys) > 0 {
+		pins, err := parsePins(opts.PinnedPublicKeys)
+		if err != nil {
+			add("pinned_public_keys", "Invalid pinned_public_keys value", err.Error())
+		} else {
+			tlsConfig.VerifyConnection = verifyPins(opts.PinnedHost, pins)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+func isProxyScheme(scheme string) bool {
+	return scheme == "http" || scheme == "https" || scheme == "socks5"
+}
+
+// loadClientCertificate accepts PEM contents or file paths for both parts.
+func loadClientCertificate(cert, key string) (tls.Certificate, error) {
+	certPEM, err := pemOrFile(cert)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("unable to read client_cert: %w", err)
+	}
+	keyPEM, err := pemOrFile(key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("unable to read client_key: %w", err)
+	}
+
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("unable to load the client certificate: %w", err)
+	}
+	return pair, nil
+}
+
+func pemOrFile(value string) ([]byte, error) {
+	if strings.Contains(value, "-----BEGIN") {
+		return []byte(value), nil
+	}
+	return os.ReadFile(value)
+}
+
+// parsePins decodes "sha256/<base64>" or bare base64 SPKI hashes.
+func parsePins(values []string) ([][]byte, error) {
+	pins := make([][]byte, 0, len(values))
+	for _, value := range values {
+		pin, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(strings.TrimSpace(value), "sha256/"))
+		if err != nil || len(pin) != sha256.Size {
+			return nil, fmt.Errorf("%q is not a base64 encoded SHA-256 public key hash", value)
+		}
+		pins = append(pins, pin)
+	}
+	return pins, nil
+}
+
+// PublicKeyPin returns the pin of a certificate in the format accepted by
+// TransportOptions.PinnedPublicKeys.
+func PublicKeyPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return "sha256/" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// errPinMismatch is returned by the TLS handshake when no certificate of the
+// chain matches a pin.
+var errPinMismatch = errors.New("certificate of the LWS API does not match any pinned public key")
+
+// verifyPins checks that a certificate presented by host matches one of the
+// pins. Connections to other hosts, such as an HTTPS proxy, are not pinned.
+// The server name is empty when the host is an IP address.
+func verifyPins(host string, pins [][]byte) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		if host != "" && cs.ServerName != "" && !strings.EqualFold(cs.ServerName, host) {
+			return nil
+		}
+
+		for _, cert := range cs.PeerCertificates {
+			sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+			for _, pin := range pins {
+				if bytes.Equal(sum[:], pin) {
+					return nil
+				}
+			}
+		}
+		return errPinMismatch
+	}
+}
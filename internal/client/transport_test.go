@@ -0,0 +1,237 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const transportTestZoneResponse = `{"code": 200, "info": "Fetched DNS Zone", "data": []}`
+
+// testCA is a throwaway certificate authority issuing client certificates.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  string
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Unable to generate CA key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "lws test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Unable to create CA certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pem:  string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+	}
+}
+
+// issueClientCert returns a PEM certificate and key signed by the CA.
+func (ca *testCA) issueClientCert(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Unable to generate client key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "terraform"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("Unable to create client certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Unable to encode client key: %v", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+func serverCAPEM(server *httptest.Server) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+}
+
+func zoneHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(transportTestZoneResponse))
+	}
+}
+
+func TestLWSClient_TransportOptions(t *testing.T) {
+	server := httptest.NewTLSServer(zoneHandler())
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, []byte(serverCAPEM(server)), 0o600); err != nil {
+		t.Fatalf("Unable to write CA file: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		opts      TransportOptions
+		expectErr bool
+	}{
+		{
+			name:      "untrusted_server",
+			opts:      TransportOptions{},
+			expectErr: true,
+		},
+		{
+			name: "ca_cert_pem",
+			opts: TransportOptions{CACertPEM: serverCAPEM(server)},
+		},
+		{
+			name: "ca_cert_file",
+			opts: TransportOptions{CACertFile: caFile},
+		},
+		{
+			name: "insecure_skip_verify",
+			opts: TransportOptions{InsecureSkipVerify: true},
+		},
+		{
+			name: "matching_pin",
+			opts: TransportOptions{CACertPEM: serverCAPEM(server), PinnedPublicKeys: []string{PublicKeyPin(server.Certificate())}},
+		},
+		{
+			name:      "mismatched_pin",
+			opts:      TransportOptions{CACertPEM: serverCAPEM(server), PinnedPublicKeys: []string{"sha256/" + strings.Repeat("A", 43) + "="}},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, server.URL, WithTransportOptions(tt.opts))
+			_, err := client.GetDNSZone(context.Background(), testDomainName)
+			if tt.expectErr && err == nil {
+				t.Errorf("Expected a TLS error, got none")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tt.name == "mismatched_pin" && err != nil && !errors.Is(err, errPinMismatch) {
+				t.Errorf("Expected a pin mismatch, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLWSClient_ClientCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	clientCert, clientKey := ca.issueClientCert(t)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	server := httptest.NewUnstartedServer(zoneHandler())
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	withoutCert := newTestClient(t, server.URL, WithTransportOptions(TransportOptions{CACertPEM: serverCAPEM(server)}))
+	if _, err := withoutCert.GetDNSZone(context.Background(), testDomainName); err == nil {
+		t.Errorf("Expected the server to reject a client without certificate")
+	}
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+	_ = os.WriteFile(certFile, []byte(clientCert), 0o600)
+	_ = os.WriteFile(keyFile, []byte(clientKey), 0o600)
+
+	for name, opts := range map[string]TransportOptions{
+		"pem":   {CACertPEM: serverCAPEM(server), ClientCert: clientCert, ClientKey: clientKey},
+		"files": {CACertPEM: serverCAPEM(server), ClientCert: certFile, ClientKey: keyFile},
+	} {
+		client := newTestClient(t, server.URL, WithTransportOptions(opts))
+		if _, err := client.GetDNSZone(context.Background(), testDomainName); err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		}
+	}
+}
+
+func TestLWSClient_Proxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		_, _ = w.Write([]byte(transportTestZoneResponse))
+	}))
+	defer proxy.Close()
+
+	client := newTestClient(t, "http://api.lws.invalid/v1", WithTransportOptions(TransportOptions{ProxyURL: proxy.URL}))
+	if _, err := client.GetDNSZone(context.Background(), testDomainName); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "http://api.lws.invalid/v1/domain/" + testDomainName + "/zdns"
+	if proxied != expected {
+		t.Errorf("Expected the proxy to receive %s, got %q", expected, proxied)
+	}
+}
+
+func TestNewTransport_InvalidOptions(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  TransportOptions
+		field string
+	}{
+		{name: "proxy_url", opts: TransportOptions{ProxyURL: "ftp://proxy"}, field: "proxy_url"},
+		{name: "ca_cert_file", opts: TransportOptions{CACertFile: "/nonexistent/ca.pem"}, field: "ca_cert_file"},
+		{name: "ca_cert_pem", opts: TransportOptions{CACertPEM: "not a certificate"}, field: "ca_cert_pem"},
+		{name: "client_key_missing", opts: TransportOptions{ClientCert: "-----BEGIN CERTIFICATE-----"}, field: "client_key"},
+		{name: "pin", opts: TransportOptions{PinnedPublicKeys: []string{"sha256/short"}}, field: "pinned_public_keys"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(WithCredentials("testlogin", "testkey"), WithTransportOptions(tt.opts))
+
+			var cfgErrs ConfigErrors
+			if !errors.As(err, &cfgErrs) || len(cfgErrs) != 1 || cfgErrs[0].Field != tt.field {
+				t.Errorf("Expected one error on %s, got %v", tt.field, err)
+			}
+		})
+	}
+}
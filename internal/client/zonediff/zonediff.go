@@ -0,0 +1,393 @@
+// Package zonediff compares the records a zone should hold with the records
+// it holds, and computes the changes that make the zone match.
+//
+// Values are compared on their canonical form (see rdata.Canonical), so
+// "10 Mail.example.com." and "10 mail.example.com" are the same MX. The plan
+// is minimal: records already present are left alone, and a record whose
+// value changes is updated in place rather than deleted and created again.
+package zonediff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/M4XGO/terraform-provider-lws/internal/client"
+	"github.com/M4XGO/terraform-provider-lws/internal/client/rdata"
+)
+
+// Options tunes a diff.
+type Options struct {
+	// Ignore lists patterns of records left alone, such as the TXT records
+	// of ACME challenges. A pattern is a record name glob in path.Match
+	// syntax, matched without regard to case, optionally preceded by a type
+	// and a space: "_acme-challenge.*" or "TXT _acme-challenge*". Apex
+	// records are named "@".
+	Ignore []string
+}
+
+// Change is one change of a plan.
+type Change struct {
+	Kind client.ChangeKind `json:"action"`
+	// Before is the record held by the zone, for updates and deletes.
+	Before *client.DNSRecord `json:"before,omitempty"`
+	// After is the record the zone should hold, for creates and updates.
+	// Updates carry the ID of Before.
+	After *client.DNSRecord `json:"after,omitempty"`
+}
+
+// Plan is the ordered list of changes making a zone hold the desired
+// records: deletes first, then updates, then creates, each sorted by name,
+// type and value.
+type Plan struct {
+	Zone    string
+	Changes []Change
+	// Ignored counts the records of the zone matched by Options.Ignore.
+	Ignored int
+}
+
+// Diff computes the plan making the actual records of a zone match the
+// desired ones.
+//
+// A desired record with a zero TTL keeps the TTL of the record it matches.
+// Desired records must have a name, a type and a value valid for the type;
+// the same record may not be desired twice, nor match an ignore pattern.
+func Diff(zoneName string, desired, actual []client.DNSRecord, opts Options) (*Plan, error) {
+	ignore, err := parseIgnore(opts.Ignore)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{Zone: zoneName}
+	groups := make(map[groupKey]*group)
+	groupOf := func(record client.DNSRecord) *group {
+		identity := client.IdentityOf(record)
+		key := groupKey{name: identity.Name, recordType: identity.Type}
+		if groups[key] == nil {
+			groups[key] = &group{}
+		}
+		return groups[key]
+	}
+
+	var errs []error
+	seen := make(map[client.RecordIdentity]bool)
+	for i, record := range desired {
+		if err := client.ValidateRecord(record); err != nil {
+			errs = append(errs, fmt.Errorf("desired record %d: %w", i, err))
+			continue
+		}
+		identity := client.IdentityOf(record)
+		if ignore.matches(identity) {
+			errs = append(errs, fmt.Errorf("desired record %d: %s record '%s' matches an ignore pattern", i, record.Type, record.Name))
+			continue
+		}
+		identity.Zone = ""
+		if seen[identity] {
+			errs = append(errs, fmt.Errorf("desired record %d: %s record '%s' with value %q is desired twice", i, record.Type, record.Name, record.Value))
+			continue
+		}
+		seen[identity] = true
+
+		g := groupOf(record)
+		g.desired = append(g.desired, record)
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid desired records for zone '%s': %w", zoneName, errors.Join(errs...))
+	}
+
+	for _, record := range actual {
+		if ignore.matches(client.IdentityOf(record)) {
+			plan.Ignored++
+			continue
+		}
+		g := groupOf(record)
+		g.actual = append(g.actual, record)
+	}
+
+	for _, g := range groups {
+		plan.Changes = append(plan.Changes, g.diff(zoneName)...)
+	}
+	sort.SliceStable(plan.Changes, func(i, j int) bool {
+		return plan.Changes[i].less(plan.Changes[j])
+	})
+
+	return plan, nil
+}
+
+// groupKey identifies the records sharing a name and a type.
+type groupKey struct {
+	name       string
+	recordType string
+}
+
+// group holds the desired and actual records sharing a name and a type.
+type group struct {
+	desired []client.DNSRecord
+	actual  []client.DNSRecord
+}
+
+// diff returns the changes of a group. Records with equivalent values are
+// paired first, and get an update only if their TTL differs. The remaining
+// ones are paired in canonical value order and updated, and what is left
+// over is created or deleted.
+func (g *group) diff(zoneName string) []Change {
+	var changes []Change
+
+	byValue := make(map[string][]client.DNSRecord)
+	for _, record := range g.actual {
+		value := canonical(record)
+		byValue[value] = append(byValue[value], record)
+	}
+
+	var unmatched []client.DNSRecord
+	for _, want := range g.desired {
+		value := canonical(want)
+		candidates := byValue[value]
+		if len(candidates) == 0 {
+			unmatched = append(unmatched, want)
+			continue
+		}
+		have := candidates[0]
+		byValue[value] = candidates[1:]
+
+		if want.TTL != 0 && want.TTL != have.TTL {
+			changes = append(changes, update(zoneName, have, want))
+		}
+	}
+
+	// Records held twice with the same value are left over here too
+	var leftover []client.DNSRecord
+	for _, records := range byValue {
+		leftover = append(leftover, records...)
+	}
+
+	sortByValue(unmatched)
+	sortByValue(leftover)
+	for len(unmatched) > 0 && len(leftover) > 0 {
+		changes = append(changes, update(zoneName, leftover[0], unmatched[0]))
+		unmatched, leftover = unmatched[1:], leftover[1:]
+	}
+
+	for _, want := range unmatched {
+		after := want
+		after.ID = 0
+		after.Zone = zoneName
+		changes = append(changes, Change{Kind: client.ChangeCreate, After: &after})
+	}
+	for _, have := range leftover {
+		before := have
+		before.Zone = zoneName
+		changes = append(changes, Change{Kind: client.ChangeDelete, Before: &before})
+	}
+
+	return changes
+}
+
+// update returns the change turning have into want.
+func update(zoneName string, have, want client.DNSRecord) Change {
+	before, after := have, want
+	before.Zone, after.Zone = zoneName, zoneName
+	after.ID = have.ID
+	if after.TTL == 0 {
+		after.TTL = have.TTL
+	}
+	return Change{Kind: client.ChangeUpdate, Before: &before, After: &after}
+}
+
+// kindOrder is the position of each kind of change in a plan.
+var kindOrder = map[client.ChangeKind]int{
+	client.ChangeDelete: 0,
+	client.ChangeUpdate: 1,
+	client.ChangeCreate: 2,
+}
+
+// less orders changes by kind, then by the name, type and value of their
+// record.
+func (c Change) less(other Change) bool {
+	if kindOrder[c.Kind] != kindOrder[other.Kind] {
+		return kindOrder[c.Kind] < kindOrder[other.Kind]
+	}
+	a, b := client.IdentityOf(c.record()), client.IdentityOf(other.record())
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	if a.Type != b.Type {
+		return a.Type < b.Type
+	}
+	return canonical(c.record()) < canonical(other.record())
+}
+
+// record returns the record a change is about: the one it leaves in the
+// zone, or the one it deletes.
+func (c Change) record() client.DNSRecord {
+	if c.After != nil {
+		return *c.After
+	}
+	return *c.Before
+}
+
+// Empty reports whether the zone already holds the desired records.
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// Counts returns the number of records the plan creates, updates and
+// deletes.
+func (p *Plan) Counts() (creates, updates, deletes int) {
+	for _, change := range p.Changes {
+		switch change.Kind {
+		case client.ChangeCreate:
+			creates++
+		case client.ChangeUpdate:
+			updates++
+		case client.ChangeDelete:
+			deletes++
+		}
+	}
+	return creates, updates, deletes
+}
+
+// ChangeSet returns a change set applying the plan.
+func (p *Plan) ChangeSet() *client.ChangeSet {
+	cs := client.NewChangeSet(p.Zone)
+	for _, change := range p.Changes {
+		switch change.Kind {
+		case client.ChangeCreate:
+			cs.Create(*change.After)
+		case client.ChangeUpdate:
+			cs.Update(*change.After)
+		case client.ChangeDelete:
+			cs.Delete(change.Before.ID)
+		}
+	}
+	return cs
+}
+
+// String renders the plan for humans, one change per line:
+//
+//	example.com: 1 to create, 1 to update, 1 to delete
+//	- old CNAME www.example.com (TTL 3600)
+//	~ @ MX 10 mx1.example.com -> 20 mx1.example.com (TTL 3600)
+//	+ new CNAME www.example.com (TTL 3600)
+func (p *Plan) String() string {
+	var b strings.Builder
+
+	creates, updates, deletes := p.Counts()
+	if p.Empty() {
+		fmt.Fprintf(&b, "%s: no changes", p.Zone)
+	} else {
+		fmt.Fprintf(&b, "%s: %d to create, %d to update, %d to delete", p.Zone, creates, updates, deletes)
+	}
+	if p.Ignored > 0 {
+		fmt.Fprintf(&b, " (%d ignored)", p.Ignored)
+	}
+
+	for _, change := range p.Changes {
+		b.WriteString("\n")
+		switch change.Kind {
+		case client.ChangeCreate:
+			fmt.Fprintf(&b, "+ %s %s %s (TTL %d)", change.After.Name, change.After.Type, change.After.Value, change.After.TTL)
+		case client.ChangeDelete:
+			fmt.Fprintf(&b, "- %s %s %s (TTL %d)", change.Before.Name, change.Before.Type, change.Before.Value, change.Before.TTL)
+		case client.ChangeUpdate:
+			before, after := change.Before, change.After
+			fmt.Fprintf(&b, "~ %s %s ", after.Name, after.Type)
+			if rdata.EqualValues(after.Type, before.Value, after.Value) {
+				b.WriteString(after.Value)
+			} else {
+				fmt.Fprintf(&b, "%s -> %s", before.Value, after.Value)
+			}
+			if before.TTL != after.TTL {
+				fmt.Fprintf(&b, " (TTL %d -> %d)", before.TTL, after.TTL)
+			} else {
+				fmt.Fprintf(&b, " (TTL %d)", after.TTL)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// planJSON is the JSON rendering of a plan.
+type planJSON struct {
+	Zone    string `json:"zone"`
+	Summary struct {
+		Create  int `json:"create"`
+		Update  int `json:"update"`
+		Delete  int `json:"delete"`
+		Ignored int `json:"ignored"`
+	} `json:"summary"`
+	Changes []Change `json:"changes"`
+}
+
+// MarshalJSON renders the plan with a summary of its changes.
+func (p *Plan) MarshalJSON() ([]byte, error) {
+	out := planJSON{Zone: p.Zone, Changes: p.Changes}
+	out.Summary.Create, out.Summary.Update, out.Summary.Delete = p.Counts()
+	out.Summary.Ignored = p.Ignored
+	if out.Changes == nil {
+		out.Changes = []Change{}
+	}
+	return json.Marshal(out)
+}
+
+// canonical returns the canonical value of a record.
+func canonical(record client.DNSRecord) string {
+	return rdata.Canonical(record.Type, record.Value)
+}
+
+// sortByValue sorts records of a group by canonical value, then by ID.
+func sortByValue(records []client.DNSRecord) {
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := canonical(records[i]), canonical(records[j])
+		if a != b {
+			return a < b
+		}
+		return records[i].ID < records[j].ID
+	})
+}
+
+// ignorePattern is a parsed Options.Ignore entry.
+type ignorePattern struct {
+	recordType string
+	name       string
+}
+
+type ignoreList []ignorePattern
+
+// parseIgnore parses and checks the ignore patterns.
+func parseIgnore(patterns []string) (ignoreList, error) {
+	var list ignoreList
+	for _, pattern := range patterns {
+		var p ignorePattern
+		switch parts := strings.Fields(pattern); len(parts) {
+		case 1:
+			p.name = strings.ToLower(parts[0])
+		case 2:
+			p.recordType, p.name = strings.ToUpper(parts[0]), strings.ToLower(parts[1])
+		default:
+			return nil, fmt.Errorf("invalid ignore pattern %q: expected a name glob, optionally preceded by a type", pattern)
+		}
+		if _, err := path.Match(p.name, ""); err != nil {
+			return nil, fmt.Errorf("invalid ignore pattern %q: %w", pattern, err)
+		}
+		list = append(list, p)
+	}
+	return list, nil
+}
+
+// matches reports whether a record identity matches one of the patterns.
+func (l ignoreList) matches(identity client.RecordIdentity) bool {
+	for _, p := range l {
+		if p.recordType != "" && p.recordType != identity.Type {
+			continue
+		}
+		if ok, _ := path.Match(p.name, identity.Name); ok {
+			return true
+		}
+	}
+	return false
+}
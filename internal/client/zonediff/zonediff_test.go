@@ -0,0 +1,266 @@
+package zonediff
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/M4XGO/terraform-provider-lws/internal/client"
+)
+
+const testZone = "example.com"
+
+// liveRecords is the zone the tests diff against.
+var liveRecords = []client.DNSRecord{
+	{ID: 1, Name: "@", Type: "MX", Value: "10 mx1.example.com", TTL: 3600},
+	{ID: 2, Name: "@", Type: "MX", Value: "20 mx2.example.com", TTL: 3600},
+	{ID: 3, Name: "www", Type: "A", Value: "192.168.1.1", TTL: 3600},
+	{ID: 4, Name: "old", Type: "CNAME", Value: "www.example.com", TTL: 3600},
+	{ID: 5, Name: "_acme-challenge.www", Type: "TXT", Value: "token", TTL: 60},
+}
+
+// line renders a change as "kind name type value ttl", with the ID of the
+// record it changes.
+func line(change Change) string {
+	record := change.record()
+	id := 0
+	if change.Before != nil {
+		id = change.Before.ID
+	}
+	return strings.Join([]string{
+		string(change.Kind), record.Name, record.Type, record.Value,
+		strconv.Itoa(record.TTL), "#" + strconv.Itoa(id),
+	}, " ")
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		desired []client.DNSRecord
+		ignore  []string
+		want    []string
+	}{
+		{
+			name: "in_sync",
+			desired: []client.DNSRecord{
+				{Name: "@", Type: "MX", Value: "10 MX1.example.com.", TTL: 3600},
+				{Name: "@", Type: "MX", Value: "20 mx2.example.com"},
+				{Name: "WWW", Type: "a", Value: "192.168.1.1", TTL: 3600},
+				{Name: "old.", Type: "CNAME", Value: "www.example.com"},
+			},
+			ignore: []string{"_acme-challenge.*"},
+		},
+		{
+			name: "value_and_ttl_changes",
+			desired: []client.DNSRecord{
+				{Name: "@", Type: "MX", Value: "10 mx1.example.com", TTL: 300},
+				{Name: "@", Type: "MX", Value: "30 mx3.example.com", TTL: 3600},
+				{Name: "www", Type: "A", Value: "192.168.1.1", TTL: 3600},
+				{Name: "new", Type: "CNAME", Value: "www.example.com", TTL: 3600},
+			},
+			ignore: []string{"TXT _acme-challenge*"},
+			want: []string{
+				"delete old CNAME www.example.com 3600 #4",
+				"update @ MX 10 mx1.example.com 300 #1",
+				"update @ MX 30 mx3.example.com 3600 #2",
+				"create new CNAME www.example.com 3600 #0",
+			},
+		},
+		{
+			name: "unmanaged_records_are_deleted",
+			desired: []client.DNSRecord{
+				{Name: "www", Type: "A", Value: "192.168.1.2"},
+				{Name: "www", Type: "A", Value: "192.168.1.3", TTL: 300},
+			},
+			want: []string{
+				"delete @ MX 10 mx1.example.com 3600 #1",
+				"delete @ MX 20 mx2.example.com 3600 #2",
+				"delete _acme-challenge.www TXT token 60 #5",
+				"delete old CNAME www.example.com 3600 #4",
+				"update www A 192.168.1.2 3600 #3",
+				"create www A 192.168.1.3 300 #0",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := Diff(testZone, tt.desired, liveRecords, Options{Ignore: tt.ignore})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, change := range plan.Changes {
+				got = append(got, line(change))
+				if change.Kind != client.ChangeDelete && change.After.Zone != testZone {
+					t.Errorf("Expected the zone to be set on %+v", change.After)
+				}
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Expected changes:\n%s\ngot:\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+			if plan.Empty() != (len(tt.want) == 0) {
+				t.Errorf("Expected Empty() to be %t", len(tt.want) == 0)
+			}
+		})
+	}
+}
+
+func TestDiff_Duplicates(t *testing.T) {
+	actual := []client.DNSRecord{
+		{ID: 7, Name: "@", Type: "TXT", Value: "v=spf1 -all", TTL: 300},
+		{ID: 8, Name: "@", Type: "TXT", Value: `"v=spf1 -all"`, TTL: 300},
+	}
+	desired := []client.DNSRecord{{Name: "@", Type: "TXT", Value: "v=spf1 -all", TTL: 300}}
+
+	plan, err := Diff(testZone, desired, actual, Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(plan.Changes) != 1 || line(plan.Changes[0]) != `delete @ TXT "v=spf1 -all" 300 #8` {
+		t.Errorf("Expected the duplicate record to be deleted, got %+v", plan.Changes)
+	}
+}
+
+func TestDiff_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		desired []client.DNSRecord
+		ignore  []string
+		want    string
+	}{
+		{
+			name:    "invalid_value",
+			desired: []client.DNSRecord{{Name: "@", Type: "MX", Value: "mx1.example.com"}},
+			want:    "invalid MX data",
+		},
+		{
+			name:    "missing_name",
+			desired: []client.DNSRecord{{Type: "A", Value: "192.168.1.1"}},
+			want:    "name and type are required",
+		},
+		{
+			name: "desired_twice",
+			desired: []client.DNSRecord{
+				{Name: "www", Type: "CNAME", Value: "example.com"},
+				{Name: "www", Type: "CNAME", Value: "Example.com."},
+			},
+			want: "desired twice",
+		},
+		{
+			name:    "desired_and_ignored",
+			desired: []client.DNSRecord{{Name: "_acme-challenge.www", Type: "TXT", Value: "token"}},
+			ignore:  []string{"_ACME-CHALLENGE.*"},
+			want:    "matches an ignore pattern",
+		},
+		{
+			name:   "bad_pattern",
+			ignore: []string{"[_acme"},
+			want:   "invalid ignore pattern",
+		},
+		{
+			name:   "too_many_fields",
+			ignore: []string{"TXT @ extra"},
+			want:   "invalid ignore pattern",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Diff(testZone, tt.desired, liveRecords, Options{Ignore: tt.ignore})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestPlan_Render(t *testing.T) {
+	desired := []client.DNSRecord{
+		{Name: "@", Type: "MX", Value: "20 mx1.example.com", TTL: 3600},
+		{Name: "@", Type: "MX", Value: "20 mx2.example.com"},
+		{Name: "www", Type: "A", Value: "192.168.1.1", TTL: 300},
+		{Name: "new", Type: "CNAME", Value: "www.example.com", TTL: 3600},
+	}
+	plan, err := Diff(testZone, desired, liveRecords, Options{Ignore: []string{"_acme-challenge.*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := strings.Join([]string{
+		"example.com: 1 to create, 2 to update, 1 to delete (1 ignored)",
+		"- old CNAME www.example.com (TTL 3600)",
+		"~ @ MX 10 mx1.example.com -> 20 mx1.example.com (TTL 3600)",
+		"~ www A 192.168.1.1 (TTL 3600 -> 300)",
+		"+ new CNAME www.example.com (TTL 3600)",
+	}, "\n")
+	if got := plan.String(); got != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, got)
+	}
+
+	out, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var decoded struct {
+		Zone    string
+		Summary map[string]int
+		Changes []struct {
+			Action string
+			Before *client.DNSRecord
+			After  *client.DNSRecord
+		}
+	}
+	if err := json.Unmarshal(out, &decoded); err != nil {
+		t.Fatalf("Unexpected error decoding %s: %v", out, err)
+	}
+	if decoded.Zone != testZone || decoded.Summary["create"] != 1 || decoded.Summary["update"] != 2 ||
+		decoded.Summary["delete"] != 1 || decoded.Summary["ignored"] != 1 {
+		t.Errorf("Unexpected summary in %s", out)
+	}
+	if len(decoded.Changes) != 4 || decoded.Changes[0].Action != "delete" || decoded.Changes[0].After != nil ||
+		decoded.Changes[1].Before.ID != 1 || decoded.Changes[1].After.Value != "20 mx1.example.com" {
+		t.Errorf("Unexpected changes in %s", out)
+	}
+
+	empty, err := Diff(testZone, nil, nil, Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := empty.String(); got != "example.com: no changes" {
+		t.Errorf("Expected no changes, got %q", got)
+	}
+	if out, _ := json.Marshal(empty); !strings.Contains(string(out), `"changes":[]`) {
+		t.Errorf("Expected an empty change list, got %s", out)
+	}
+}
+
+func TestPlan_ChangeSet(t *testing.T) {
+	desired := []client.DNSRecord{
+		{Name: "@", Type: "MX", Value: "10 mx1.example.com", TTL: 3600},
+		{Name: "@", Type: "MX", Value: "20 mx2.example.com", TTL: 3600},
+		{Name: "www", Type: "A", Value: "192.168.1.2", TTL: 3600},
+		{Name: "new", Type: "CNAME", Value: "www.example.com", TTL: 3600},
+	}
+	plan, err := Diff(testZone, desired, liveRecords, Options{Ignore: []string{"_acme-challenge.*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cs := plan.ChangeSet()
+	if cs.Zone != testZone || len(cs.Changes) != 3 {
+		t.Fatalf("Expected 3 changes to %s, got %+v", testZone, cs)
+	}
+	want := []client.Change{
+		{Kind: client.ChangeDelete, Record: client.DNSRecord{ID: 4}},
+		{Kind: client.ChangeUpdate, Record: client.DNSRecord{ID: 3, Name: "www", Type: "A", Value: "192.168.1.2", TTL: 3600, Zone: testZone}},
+		{Kind: client.ChangeCreate, Record: client.DNSRecord{Name: "new", Type: "CNAME", Value: "www.example.com", TTL: 3600, Zone: testZone}},
+	}
+	for i := range want {
+		if cs.Changes[i] != want[i] {
+			t.Errorf("Expected change %d to be %+v, got %+v", i, want[i], cs.Changes[i])
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ZoneLocker is implemented by DNSAPI backends that serialise the changes
+// made to a zone. Callers hold the lock around a read, check and write
+// sequence, such as looking for an existing record before creating one, so
+// that no other change to the zone lands in between.
+type ZoneLocker interface {
+	// LockZone waits until no other change to the zone is in progress. The
+	// returned context carries the lock: changes to the zone made with it
+	// don't wait for it again. unlock must be called once done.
+	LockZone(ctx context.Context, zoneName string) (lockedCtx context.Context, unlock func(), err error)
+}
+
+// Ensure LWSClient satisfies the ZoneLocker interface.
+var _ ZoneLocker = &LWSClient{}
+
+// zoneLocks serialises the mutations of each zone. Reads don't take them,
+// and mutations of different zones run in parallel.
+type zoneLocks struct {
+	mu    sync.Mutex
+	zones map[string]chan struct{}
+}
+
+// heldZoneKey marks, in a context, a zone lock held by the operation.
+type heldZoneKey struct {
+	locks *zoneLocks
+	zone  string
+}
+
+func newZoneLocks() *zoneLocks {
+	return &zoneLocks{zones: make(map[string]chan struct{})}
+}
+
+// lock waits for the lock of a zone, or returns ctx unchanged when it
+// already carries it. It fails only when ctx is done first.
+func (l *zoneLocks) lock(ctx context.Context, zoneName string) (context.Context, func(), error) {
+	key := heldZoneKey{locks: l, zone: zoneCacheKey(zoneName)}
+	if ctx.Value(key) != nil {
+		return ctx, func() {}, nil
+	}
+
+	l.mu.Lock()
+	sem, ok := l.zones[key.zone]
+	if !ok {
+		sem = make(chan struct{}, 1)
+		l.zones[key.zone] = sem
+	}
+	l.mu.Unlock()
+
+	select {
+	case sem <- struct{}{}:
+	default:
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx, nil, ctx.Err()
+		}
+	}
+
+	var once sync.Once
+	unlock := func() {
+		once.Do(func() { <-sem })
+	}
+	return context.WithValue(ctx, key, true), unlock, nil
+}
+
+// LockZone implements ZoneLocker
+func (c *LWSClient) LockZone(ctx context.Context, zoneName string) (context.Context, func(), error) {
+	start := c.clock.Now()
+	lockedCtx, unlock, err := c.locks.lock(ctx, zoneName)
+	if err != nil {
+		return ctx, nil, err
+	}
+
+	if waited := c.clock.Now().Sub(start); waited > 10*time.Millisecond {
+		c.log(ctx, LogDebug, "Waited for other changes to the zone", map[string]interface{}{
+			"zone":   zoneName,
+			"waited": waited.String(),
+		})
+	}
+	return lockedCtx, unlock, nil
+}
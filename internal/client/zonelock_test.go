@@ -0,0 +1,132 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestZoneLocks(t *testing.T) {
+	locks := newZoneLocks()
+	ctx := context.Background()
+
+	lockedCtx, unlock, err := locks.lock(ctx, testDomainName)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The operation holding the lock can change the zone again
+	if _, again, err := locks.lock(lockedCtx, testDomainName); err != nil {
+		t.Fatalf("Expected the held lock to be reentrant, got %v", err)
+	} else {
+		again()
+	}
+
+	// Other spellings of the zone share its lock
+	waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := locks.lock(waitCtx, "Example.COM."); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected to wait for the zone lock until the deadline, got %v", err)
+	}
+
+	// Other zones are not blocked
+	_, unlockOther, err := locks.lock(ctx, "example.org")
+	if err != nil {
+		t.Fatalf("Expected another zone to be lockable, got %v", err)
+	}
+	unlockOther()
+
+	unlock()
+	unlock()
+	_, unlock, err = locks.lock(ctx, testDomainName)
+	if err != nil {
+		t.Fatalf("Expected the released lock to be available, got %v", err)
+	}
+	unlock()
+}
+
+func TestLWSClient_LockZoneRace(t *testing.T) {
+	server := &laggingZoneServer{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	client := newTestClient(t, ts.URL, WithRateLimit(0, 0, 8))
+	want := DNSRecord{Name: "_dmarc", Type: "TXT", Value: "v=DMARC1; p=none", Zone: testDomainName, TTL: 300}
+
+	// Every goroutine checks for the record and creates it when missing, as
+	// parallel resources with the same configuration do
+	const workers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, unlock, err := client.LockZone(context.Background(), testDomainName)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer unlock()
+
+			zone, err := client.GetDNSZone(ctx, testDomainName)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if FindRecord(zone.Records, IdentityOf(want)) != nil {
+				return
+			}
+
+			record := want
+			if _, err := client.CreateDNSRecord(ctx, &record); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	server.mu.Lock()
+	created := len(server.records)
+	server.mu.Unlock()
+	if created != 1 {
+		t.Errorf("Expected a single record, got %d", created)
+	}
+}
+
+func TestLWSClient_LockZoneAllowsReads(t *testing.T) {
+	server := &laggingZoneServer{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	client := newTestClient(t, ts.URL, WithRateLimit(0, 0, 4))
+
+	_, unlock, err := client.LockZone(context.Background(), testDomainName)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := client.GetDNSZone(ctx, testDomainName); err != nil {
+		t.Errorf("Expected reads to proceed while the zone is locked, got %v", err)
+	}
+
+	// A change made without the lock waits for it
+	shortCtx, cancelShort := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelShort()
+	err = client.DeleteDNSRecord(shortCtx, 1, testDomainName)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the delete to wait for the zone lock, got %v", err)
+	}
+}
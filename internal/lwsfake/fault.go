@@ -0,0 +1,173 @@
+package lwsfake
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChallengePage is the body of the Cloudflare challenges the server answers
+// with.
+const ChallengePage = `<!DOCTYPE html><html lang="en-US"><head><title>Just a moment...</title></head>` +
+	`<body><div id="challenge-body-text">Checking if the site connection is secure. ` +
+	`Enable JavaScript and cookies to continue</div><script src="/cdn-cgi/challenge-platform/h/b/orchestrate/chl_page/v1"></script></body></html>`
+
+// Fault is a failure of the API, applied to the requests it matches.
+type Fault struct {
+	// Method and Zone restrict the fault to the requests with that method
+	// or for that zone. Empty values match every request.
+	Method string
+	Zone   string
+	// Times is the number of matching requests the fault applies to, zero
+	// applying it to every one.
+	Times int
+
+	// Latency delays the answer.
+	Latency time.Duration
+	// Status answers with that HTTP status and the LWS error envelope,
+	// without handling the request. RetryAfter sets the Retry-After header
+	// of the answer.
+	Status     int
+	RetryAfter time.Duration
+	// Challenge answers with a Cloudflare challenge page, without handling
+	// the request.
+	Challenge bool
+	// Drop handles the request, then closes the connection without
+	// answering, like a response lost on the way back.
+	Drop bool
+	// Renumber gives new IDs to the records of the zone after a change.
+	Renumber bool
+	// Lag keeps serving the previous listing of the zone for that number of
+	// listings after a change, overriding WithConsistencyLag.
+	Lag int
+}
+
+// scriptedFault is a fault with the number of requests it still applies to,
+// negative when it applies to every request.
+type scriptedFault struct {
+	Fault
+	left int
+}
+
+// Inject scripts faults. Each request is affected by the first fault
+// matching it that has requests left, if any.
+func (s *Server) Inject(faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, fault := range faults {
+		left := fault.Times
+		if left == 0 {
+			left = -1
+		}
+		s.faults = append(s.faults, &scriptedFault{Fault: fault, left: left})
+	}
+}
+
+// ClearFaults removes the scripted faults.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// takeFault returns the fault applying to a request, counting it.
+func (s *Server) takeFault(method, zoneName string) *scriptedFault {
+	for _, fault := range s.faults {
+		if fault.left == 0 ||
+			(fault.Method != "" && !strings.EqualFold(fault.Method, method)) ||
+			(fault.Zone != "" && zoneKey(fault.Zone) != zoneKey(zoneName)) {
+			continue
+		}
+		if fault.left > 0 {
+			fault.left--
+		}
+		return fault
+	}
+	return nil
+}
+
+// delay waits for the latency of the fault. It returns false when the
+// client gave up in the meantime.
+func (f *scriptedFault) delay(ctx context.Context) bool {
+	if f.Latency <= 0 {
+		return true
+	}
+	timer := time.NewTimer(f.Latency)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// writeStatus answers with the status of the fault.
+func (f *scriptedFault) writeStatus(w http.ResponseWriter) {
+	if f.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(f.RetryAfter.Round(time.Second)/time.Second)))
+	}
+	writeEnvelope(w, f.Status, http.StatusText(f.Status), nil)
+}
+
+// writeChallenge answers with a Cloudflare challenge page.
+func writeChallenge(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.Header().Set("Cf-Mitigated", "challenge")
+	w.WriteHeader(http.StatusForbidden)
+	_, _ = w.Write([]byte(ChallengePage))
+}
+
+// ParseFault parses a fault written as comma separated settings, e.g.
+// "method=POST,status=502,times=2" or "zone=example.com,drop,times=1".
+// The settings are method, zone, times, latency, status, retry_after,
+// challenge, drop, renumber and lag; durations use the time.ParseDuration
+// syntax.
+func ParseFault(spec string) (Fault, error) {
+	var fault Fault
+	for _, setting := range strings.Split(spec, ",") {
+		key, value, hasValue := strings.Cut(strings.TrimSpace(setting), "=")
+		var err error
+		switch key {
+		case "method":
+			fault.Method = strings.ToUpper(value)
+		case "zone":
+			fault.Zone = value
+		case "times":
+			fault.Times, err = strconv.Atoi(value)
+		case "latency":
+			fault.Latency, err = time.ParseDuration(value)
+		case "status":
+			fault.Status, err = strconv.Atoi(value)
+		case "retry_after":
+			fault.RetryAfter, err = time.ParseDuration(value)
+		case "challenge":
+			fault.Challenge, err = parseFlag(value, hasValue)
+		case "drop":
+			fault.Drop, err = parseFlag(value, hasValue)
+		case "renumber":
+			fault.Renumber, err = parseFlag(value, hasValue)
+		case "lag":
+			fault.Lag, err = strconv.Atoi(value)
+		case "":
+			continue
+		default:
+			return Fault{}, fmt.Errorf("invalid fault %q: unknown setting %q", spec, key)
+		}
+		if err != nil {
+			return Fault{}, fmt.Errorf("invalid fault %q: invalid %s: %w", spec, key, err)
+		}
+	}
+	return fault, nil
+}
+
+// parseFlag parses a boolean setting, true when written without a value.
+func parseFlag(value string, hasValue bool) (bool, error) {
+	if !hasValue {
+		return true, nil
+	}
+	return strconv.ParseBool(value)
+}
@@ -0,0 +1,409 @@
+// Package lwsfake is an in-memory fake of the zone endpoints of the LWS API,
+// for tests and local Terraform runs.
+//
+// A Server keeps a set of zones and serves GET, POST, PUT and DELETE on
+// domain/{zone}/zdns the way LWS does: answers use the LWS JSON envelope,
+// record IDs come from a counter shared by every zone and are never reused,
+// a create answers without the ID of the new record, a duplicate create is
+// rejected with a 400, and requests sent with X-Test-Mode are validated
+// without changing the zone. Faults scripted with Inject reproduce the
+// failures the client has to cope with: latency, server errors, Cloudflare
+// challenge pages, dropped responses, records renumbered by LWS and zone
+// listings lagging behind changes.
+//
+// The package does not depend on the client, so the tests of every package
+// can use it.
+package lwsfake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/M4XGO/terraform-provider-lws/internal/client/rdata"
+)
+
+// DefaultTTL is the TTL LWS gives records created without one.
+const DefaultTTL = 3600
+
+// zonePath matches the zone endpoint, after any base path such as "/v1".
+var zonePath = regexp.MustCompile(`/domain/([^/]+)/zdns$`)
+
+// Record is a DNS record as listed by the API.
+type Record struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	TTL   int    `json:"ttl"`
+}
+
+// zone is the state of a zone. listed is the listing served while pending
+// listings still lag behind the last change.
+type zone struct {
+	records []Record
+	listed  []Record
+	pending int
+}
+
+// Server is a fake LWS API. It implements http.Handler, to be served with
+// httptest.NewServer or http.ListenAndServe.
+type Server struct {
+	login  string
+	apiKey string
+	lag    int
+
+	mu       sync.Mutex
+	zones    map[string]*zone
+	nextID   int
+	faults   []*scriptedFault
+	requests []string
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithCredentials only accepts requests authenticated with login and
+// apiKey. Without it, any non-empty credentials are accepted.
+func WithCredentials(login, apiKey string) Option {
+	return func(s *Server) {
+		s.login, s.apiKey = login, apiKey
+	}
+}
+
+// WithZone adds a zone holding records. Records without an ID get one.
+func WithZone(name string, records ...Record) Option {
+	return func(s *Server) {
+		s.addZone(name, records)
+	}
+}
+
+// WithFirstID sets the ID given to the first record created.
+func WithFirstID(id int) Option {
+	return func(s *Server) {
+		s.nextID = id
+	}
+}
+
+// WithConsistencyLag keeps serving the previous listing of a zone for the
+// given number of listings after each change, like a slowly indexed zone.
+func WithConsistencyLag(listings int) Option {
+	return func(s *Server) {
+		s.lag = listings
+	}
+}
+
+// WithFaults scripts faults, see Inject.
+func WithFaults(faults ...Fault) Option {
+	return func(s *Server) {
+		s.Inject(faults...)
+	}
+}
+
+// New returns a fake API without zones unless options add some.
+func New(opts ...Option) *Server {
+	s := &Server{zones: make(map[string]*zone), nextID: 1}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// AddZone adds a zone holding records, replacing any zone of that name.
+// Records without an ID get one.
+func (s *Server) AddZone(name string, records ...Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addZone(name, records)
+}
+
+func (s *Server) addZone(name string, records []Record) {
+	z := &zone{}
+	for _, record := range records {
+		if record.ID == 0 {
+			record.ID = s.newID()
+		}
+		s.nextID = max(s.nextID, record.ID+1)
+		z.records = append(z.records, record)
+	}
+	s.zones[zoneKey(name)] = z
+}
+
+func (s *Server) newID() int {
+	id := s.nextID
+	s.nextID++
+	return id
+}
+
+// Records returns the records of a zone as they are, whatever the listing
+// currently served, or nil when the zone does not exist.
+func (s *Server) Records(name string) []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	z, ok := s.zones[zoneKey(name)]
+	if !ok {
+		return nil
+	}
+	return append([]Record{}, z.records...)
+}
+
+// Renumber gives new IDs to every record of a zone, as LWS sometimes does
+// when it rewrites a zone.
+func (s *Server) Renumber(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if z, ok := s.zones[zoneKey(name)]; ok {
+		s.renumber(z)
+	}
+}
+
+func (s *Server) renumber(z *zone) {
+	for i := range z.records {
+		z.records[i].ID = s.newID()
+	}
+}
+
+// Requests returns the requests received so far, as "METHOD /path".
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	match := zonePath.FindStringSubmatch(r.URL.Path)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	var zoneName string
+	if match != nil {
+		zoneName = match[1]
+	}
+	fault := s.takeFault(r.Method, zoneName)
+	s.mu.Unlock()
+
+	if fault != nil {
+		if !fault.delay(r.Context()) {
+			return
+		}
+		if fault.Challenge {
+			writeChallenge(w)
+			return
+		}
+		if fault.Status != 0 {
+			fault.writeStatus(w)
+			return
+		}
+	}
+
+	if match == nil {
+		writeEnvelope(w, http.StatusNotFound, "Not found", nil)
+		return
+	}
+	if !s.authenticated(r) {
+		writeEnvelope(w, http.StatusUnauthorized, "Authentication failed", nil)
+		return
+	}
+
+	status, info, data := s.handle(r, zoneName, fault)
+	if fault != nil && fault.Drop {
+		// The change is applied, but the client never learns about it
+		panic(http.ErrAbortHandler)
+	}
+	writeEnvelope(w, status, info, data)
+}
+
+// authenticated checks the credentials headers of r.
+func (s *Server) authenticated(r *http.Request) bool {
+	login, apiKey := r.Header.Get("X-Auth-Login"), r.Header.Get("X-Auth-Pass")
+	if s.login == "" && s.apiKey == "" {
+		return login != "" && apiKey != ""
+	}
+	return login == s.login && apiKey == s.apiKey
+}
+
+// handle serves a zone request and returns the status, info and data of
+// the answer.
+func (s *Server) handle(r *http.Request, zoneName string, fault *scriptedFault) (int, string, interface{}) {
+	testMode := r.Header.Get("X-Test-Mode") == "true"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	z, ok := s.zones[zoneKey(zoneName)]
+	if !ok {
+		return http.StatusNotFound, "Domain not found", nil
+	}
+
+	lag := s.lag
+	if fault != nil && fault.Lag > 0 {
+		lag = fault.Lag
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		records := z.records
+		if z.pending > 0 {
+			z.pending--
+			records = z.listed
+		}
+		return http.StatusOK, "Fetched DNS Zone", append([]Record{}, records...)
+
+	case http.MethodPost:
+		var req Record
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return http.StatusBadRequest, "Invalid request body", nil
+		}
+		if msg := validate(req); msg != "" {
+			return http.StatusBadRequest, msg, nil
+		}
+		if req.TTL == 0 {
+			req.TTL = DefaultTTL
+		}
+		for _, record := range z.records {
+			if sameRecord(record, req) {
+				return http.StatusBadRequest, "This DNS record already exists", nil
+			}
+		}
+		if !testMode {
+			s.snapshot(z, lag)
+			req.ID = s.newID()
+			z.records = append(z.records, req)
+			s.afterChange(z, fault)
+		}
+		// LWS echoes the record without saying which ID it gave it
+		return http.StatusOK, "Added a new line in the DNS Zone", struct {
+			Type  string `json:"type"`
+			Name  string `json:"name"`
+			Value string `json:"value"`
+			TTL   int    `json:"ttl"`
+		}{req.Type, req.Name, req.Value, req.TTL}
+
+	case http.MethodPut:
+		var req Record
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return http.StatusBadRequest, "Invalid request body", nil
+		}
+		i := z.index(req.ID)
+		if i < 0 {
+			return http.StatusNotFound, fmt.Sprintf("Record %d not found", req.ID), nil
+		}
+		if msg := validate(req); msg != "" {
+			return http.StatusBadRequest, msg, nil
+		}
+		if req.TTL == 0 {
+			req.TTL = DefaultTTL
+		}
+		if !testMode {
+			s.snapshot(z, lag)
+			z.records[i] = req
+			s.afterChange(z, fault)
+		}
+		return http.StatusOK, "Record updated", req
+
+	case http.MethodDelete:
+		var req struct {
+			ID int `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return http.StatusBadRequest, "Invalid request body", nil
+		}
+		i := z.index(req.ID)
+		if i < 0 {
+			return http.StatusNotFound, fmt.Sprintf("Record %d not found", req.ID), nil
+		}
+		if !testMode {
+			s.snapshot(z, lag)
+			z.records = append(z.records[:i], z.records[i+1:]...)
+			s.afterChange(z, fault)
+		}
+		return http.StatusOK, "Record deleted", nil
+	}
+
+	return http.StatusMethodNotAllowed, "Method not allowed", nil
+}
+
+// snapshot keeps the current listing of z to serve for the next lag
+// listings. Changes made while listings lag extend the lag, but the listing
+// served stays the one from before the first of them.
+func (s *Server) snapshot(z *zone, lag int) {
+	if lag <= 0 {
+		return
+	}
+	if z.pending == 0 {
+		z.listed = append([]Record{}, z.records...)
+	}
+	z.pending = lag
+}
+
+// afterChange applies the faults that follow a change of z.
+func (s *Server) afterChange(z *zone, fault *scriptedFault) {
+	if fault != nil && fault.Renumber {
+		s.renumber(z)
+	}
+}
+
+// index returns the position of the record with the given ID, or -1.
+func (z *zone) index(id int) int {
+	for i, record := range z.records {
+		if record.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// validate returns why LWS would reject a record, or "".
+func validate(record Record) string {
+	switch {
+	case strings.TrimSpace(record.Type) == "":
+		return "The type field is required"
+	case strings.TrimSpace(record.Value) == "":
+		return "The value field is required"
+	case record.TTL < 0:
+		return "The ttl field must be positive"
+	}
+	if rdata.Supported(record.Type) {
+		if _, err := rdata.Parse(record.Type, record.Value); err != nil {
+			return "Invalid value: " + err.Error()
+		}
+	}
+	return ""
+}
+
+// sameRecord reports whether two records have the same name, type and
+// equivalent values.
+func sameRecord(a, b Record) bool {
+	return strings.EqualFold(normalizeName(a.Name), normalizeName(b.Name)) &&
+		strings.EqualFold(a.Type, b.Type) &&
+		rdata.EqualValues(strings.ToUpper(a.Type), a.Value, b.Value)
+}
+
+func normalizeName(name string) string {
+	return strings.TrimSuffix(strings.TrimSpace(name), ".")
+}
+
+func zoneKey(name string) string {
+	return strings.ToLower(normalizeName(name))
+}
+
+// writeEnvelope answers with the LWS JSON envelope.
+func writeEnvelope(w http.ResponseWriter, status int, info string, data interface{}) {
+	body, err := json.Marshal(struct {
+		Code int         `json:"code"`
+		Info string      `json:"info"`
+		Data interface{} `json:"data"`
+	}{status, info, data})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
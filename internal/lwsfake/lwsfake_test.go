@@ -0,0 +1,293 @@
+package lwsfake_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/M4XGO/terraform-provider-lws/internal/client"
+	"github.com/M4XGO/terraform-provider-lws/internal/lwsfake"
+)
+
+const testZone = "example.com"
+
+// newClient returns a client of the fake, retrying retries times without
+// delay and waiting for changes to be listed.
+func newClient(t *testing.T, baseURL string, retries int, opts ...client.Option) *client.LWSClient {
+	t.Helper()
+
+	opts = append([]client.Option{
+		client.WithCredentials("testlogin", "testkey"),
+		client.WithBaseURL(baseURL + "/v1"),
+		client.WithRetryPolicy(&client.DefaultRetryPolicy{Retries: retries, Multiplier: 1}),
+		client.WithConsistencyWait(time.Millisecond, time.Second, 1),
+		client.WithZoneCacheTTL(0),
+	}, opts...)
+	c, err := client.New(opts...)
+	if err != nil {
+		t.Fatalf("Unexpected configuration error: %v", err)
+	}
+	return c
+}
+
+// newServer starts a fake holding an MX record in example.com.
+func newServer(t *testing.T, opts ...lwsfake.Option) (*lwsfake.Server, *httptest.Server) {
+	t.Helper()
+
+	opts = append([]lwsfake.Option{
+		lwsfake.WithCredentials("testlogin", "testkey"),
+		lwsfake.WithFirstID(12000),
+		lwsfake.WithZone(testZone, lwsfake.Record{Name: "@", Type: "MX", Value: "10 mail.example.com", TTL: 3600}),
+	}, opts...)
+	fake := lwsfake.New(opts...)
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func TestServer_Lifecycle(t *testing.T) {
+	fake, server := newServer(t)
+	c := newClient(t, server.URL, 0)
+	ctx := context.Background()
+
+	created, err := c.CreateDNSRecord(ctx, &client.DNSRecord{Name: "www", Type: "A", Value: "192.0.2.10", Zone: testZone})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if created.ID != 12001 || created.Name != "www" || created.Value != "192.0.2.10" {
+		t.Errorf("Expected the record with the next ID of the server, got %+v", created)
+	}
+	want := []lwsfake.Record{
+		{ID: 12000, Name: "@", Type: "MX", Value: "10 mail.example.com", TTL: 3600},
+		{ID: 12001, Name: "www", Type: "A", Value: "192.0.2.10", TTL: lwsfake.DefaultTTL},
+	}
+	if got := fake.Records(testZone); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	updated := &client.DNSRecord{ID: created.ID, Name: "www", Type: "A", Value: "192.0.2.20", TTL: 300, Zone: testZone}
+	if _, err := c.UpdateDNSRecord(ctx, updated); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := fake.Records(testZone)[1]; got != (lwsfake.Record{ID: 12001, Name: "www", Type: "A", Value: "192.0.2.20", TTL: 300}) {
+		t.Errorf("Expected the record to be updated in place, got %+v", got)
+	}
+
+	if err := c.DeleteDNSRecord(ctx, created.ID, testZone); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := fake.Records(testZone); len(got) != 1 {
+		t.Errorf("Expected the record to be deleted, got %+v", got)
+	}
+
+	// IDs are never reused
+	again, err := c.CreateDNSRecord(ctx, &client.DNSRecord{Name: "www", Type: "A", Value: "192.0.2.10", Zone: testZone})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if again.ID != 12002 {
+		t.Errorf("Expected a new ID, got %d", again.ID)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		login    string
+		testMode bool
+		status   int
+		info     string
+	}{
+		{name: "wrong_credentials", method: http.MethodGet, path: "/v1/domain/example.com/zdns", login: "other", status: 401, info: "Authentication failed"},
+		{name: "unknown_zone", method: http.MethodGet, path: "/v1/domain/example.org/zdns", status: 404, info: "Domain not found"},
+		{name: "unknown_endpoint", method: http.MethodGet, path: "/v1/domain/example.com", status: 404, info: "Not found"},
+		{name: "duplicate", method: http.MethodPost, path: "/domain/example.com/zdns", body: `{"type": "mx", "name": "@.", "value": "10 MAIL.example.com.", "ttl": 60}`, status: 400, info: "This DNS record already exists"},
+		{name: "invalid_value", method: http.MethodPost, path: "/domain/example.com/zdns", body: `{"type": "A", "name": "www", "value": "2001:db8::1", "ttl": 60}`, status: 400},
+		{name: "missing_value", method: http.MethodPost, path: "/domain/example.com/zdns", body: `{"type": "A", "name": "www", "ttl": 60}`, status: 400, info: "The value field is required"},
+		{name: "unknown_record", method: http.MethodPut, path: "/domain/example.com/zdns", body: `{"id": 42, "type": "A", "name": "www", "value": "192.0.2.1", "ttl": 60}`, status: 404, info: "Record 42 not found"},
+		{name: "unknown_delete", method: http.MethodDelete, path: "/domain/example.com/zdns", body: `{"id": 42}`, status: 404, info: "Record 42 not found"},
+		{name: "test_mode_create", method: http.MethodPost, path: "/domain/example.com/zdns", body: `{"type": "A", "name": "www", "value": "192.0.2.1", "ttl": 60}`, testMode: true, status: 200, info: "Added a new line in the DNS Zone"},
+		{name: "test_mode_delete", method: http.MethodDelete, path: "/domain/example.com/zdns", body: `{"id": 12000}`, testMode: true, status: 200, info: "Record deleted"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, server := newServer(t)
+			before := fake.Records(testZone)
+
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, bytes.NewBufferString(tt.body))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			login := tt.login
+			if login == "" {
+				login = "testlogin"
+			}
+			req.Header.Set("X-Auth-Login", login)
+			req.Header.Set("X-Auth-Pass", "testkey")
+			if tt.testMode {
+				req.Header.Set("X-Test-Mode", "true")
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			defer resp.Body.Close()
+
+			var envelope client.LWSAPIResponse
+			if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+				t.Fatalf("Expected a JSON envelope: %v", err)
+			}
+			if resp.StatusCode != tt.status || envelope.Code != tt.status {
+				t.Errorf("Expected status %d, got HTTP %d, code %d", tt.status, resp.StatusCode, envelope.Code)
+			}
+			if tt.info != "" && envelope.GetInfoMessage() != tt.info {
+				t.Errorf("Expected info %q, got %q", tt.info, envelope.GetInfoMessage())
+			}
+			if after := fake.Records(testZone); !reflect.DeepEqual(before, after) {
+				t.Errorf("Expected the zone to be unchanged, got %+v", after)
+			}
+		})
+	}
+}
+
+func TestServer_Faults(t *testing.T) {
+	www := func() *client.DNSRecord {
+		return &client.DNSRecord{Name: "www", Type: "A", Value: "192.0.2.10", TTL: 300, Zone: testZone}
+	}
+
+	tests := []struct {
+		name    string
+		opts    []lwsfake.Option
+		faults  []lwsfake.Fault
+		retries int
+		check   func(t *testing.T, fake *lwsfake.Server, c *client.LWSClient)
+	}{
+		{
+			name:    "server_error_retried",
+			faults:  []lwsfake.Fault{{Method: http.MethodGet, Status: http.StatusBadGateway, Times: 2}},
+			retries: 2,
+			check: func(t *testing.T, fake *lwsfake.Server, c *client.LWSClient) {
+				if _, err := c.GetDNSZone(context.Background(), testZone); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if got := len(fake.Requests()); got != 3 {
+					t.Errorf("Expected 3 attempts, got %d", got)
+				}
+			},
+		},
+		{
+			name:   "challenge",
+			faults: []lwsfake.Fault{{Challenge: true}},
+			check: func(t *testing.T, fake *lwsfake.Server, c *client.LWSClient) {
+				if _, err := c.GetDNSZone(context.Background(), testZone); !client.IsChallenge(err) {
+					t.Errorf("Expected a challenge error, got %v", err)
+				}
+			},
+		},
+		{
+			name:    "dropped_create",
+			faults:  []lwsfake.Fault{{Method: http.MethodPost, Drop: true, Times: 1}},
+			retries: 1,
+			check: func(t *testing.T, fake *lwsfake.Server, c *client.LWSClient) {
+				created, err := c.CreateDNSRecord(context.Background(), www())
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if records := fake.Records(testZone); len(records) != 2 || records[1].ID != created.ID {
+					t.Errorf("Expected the dropped create to be found instead of sent again, got %+v", records)
+				}
+			},
+		},
+		{
+			name:   "renumbered",
+			faults: []lwsfake.Fault{{Method: http.MethodPost, Renumber: true}},
+			check: func(t *testing.T, fake *lwsfake.Server, c *client.LWSClient) {
+				created, err := c.CreateDNSRecord(context.Background(), www())
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if records := fake.Records(testZone); records[0].ID != 12002 || records[1].ID != 12003 || created.ID != 12003 {
+					t.Errorf("Expected every record to be renumbered, got %+v and %+v", records, created)
+				}
+			},
+		},
+		{
+			name: "lagging_listing",
+			opts: []lwsfake.Option{lwsfake.WithConsistencyLag(3)},
+			check: func(t *testing.T, fake *lwsfake.Server, c *client.LWSClient) {
+				if _, err := c.CreateDNSRecord(context.Background(), www()); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				gets := 0
+				for _, request := range fake.Requests() {
+					if strings.HasPrefix(request, http.MethodGet) {
+						gets++
+					}
+				}
+				if gets != 4 {
+					t.Errorf("Expected the client to list the zone until the record shows up, got %d listings", gets)
+				}
+			},
+		},
+		{
+			name:   "latency",
+			faults: []lwsfake.Fault{{Latency: time.Second}},
+			check: func(t *testing.T, fake *lwsfake.Server, c *client.LWSClient) {
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				defer cancel()
+				if _, err := c.GetDNSZone(ctx, testZone); err == nil {
+					t.Errorf("Expected the slow answer to time out")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, server := newServer(t, append(tt.opts, lwsfake.WithFaults(tt.faults...))...)
+			tt.check(t, fake, newClient(t, server.URL, tt.retries))
+		})
+	}
+}
+
+func TestParseFault(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    lwsfake.Fault
+		wantErr string
+	}{
+		{spec: "method=post,status=502,times=2,retry_after=3s", want: lwsfake.Fault{Method: "POST", Status: 502, Times: 2, RetryAfter: 3 * time.Second}},
+		{spec: "zone=example.com, drop, times=1", want: lwsfake.Fault{Zone: "example.com", Drop: true, Times: 1}},
+		{spec: "challenge,renumber=false,latency=250ms,lag=2", want: lwsfake.Fault{Challenge: true, Latency: 250 * time.Millisecond, Lag: 2}},
+		{spec: "status=bad", wantErr: "invalid status"},
+		{spec: "explode", wantErr: `unknown setting "explode"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := lwsfake.ParseFault(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
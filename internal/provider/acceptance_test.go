@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"fmt"
+	"log"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/M4XGO/terraform-provider-lws/internal/lwsfake"
+	"github.com/hashicorp/terraform-plugin-testing/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// testAccRecordPrefix starts the names of the records created by acceptance
+// tests, which the sweepers delete.
+const testAccRecordPrefix = "tf-acc-"
+
+// testAccZonesEnv lists the zones acceptance tests may create records in,
+// separated by commas. Tests use the first one.
+const testAccZonesEnv = "LWS_ACC_ZONES"
+
+func TestMain(m *testing.M) {
+	resource.TestMain(testAccRunner{m})
+}
+
+// testAccRunner runs the tests of the package. When acceptance tests are
+// enabled without LWS_BASE_URL, it points the provider at a fake LWS API
+// serving the acceptance zones, so that they run without touching LWS.
+// Sweepers do not run the tests and always target the configured API.
+type testAccRunner struct {
+	m *testing.M
+}
+
+func (r testAccRunner) Run() int {
+	if os.Getenv(resource.EnvTfAcc) == "" || os.Getenv("LWS_BASE_URL") != "" {
+		return r.m.Run()
+	}
+
+	for _, key := range []string{"LWS_LOGIN", "LWS_API_KEY"} {
+		if os.Getenv(key) == "" {
+			_ = os.Setenv(key, "tf-acc")
+		}
+	}
+	// The fake honours test mode by leaving zones unchanged, and has no
+	// real records to protect.
+	if os.Getenv("LWS_TEST_MODE") != "" {
+		log.Printf("[INFO] Ignoring LWS_TEST_MODE for the fake LWS API")
+		_ = os.Unsetenv("LWS_TEST_MODE")
+	}
+	opts := []lwsfake.Option{lwsfake.WithCredentials(os.Getenv("LWS_LOGIN"), os.Getenv("LWS_API_KEY"))}
+	for _, zone := range testAccZones() {
+		opts = append(opts, lwsfake.WithZone(zone))
+	}
+	server := httptest.NewServer(lwsfake.New(opts...))
+	defer server.Close()
+
+	_ = os.Setenv("LWS_BASE_URL", server.URL)
+	log.Printf("[INFO] LWS_BASE_URL is not set, running acceptance tests against a fake LWS API at %s", server.URL)
+	return r.m.Run()
+}
+
+// testAccZones returns the zones of LWS_ACC_ZONES, example.com by default.
+func testAccZones() []string {
+	if zones := splitList(os.Getenv(testAccZonesEnv)); len(zones) > 0 {
+		return zones
+	}
+	return []string{"example.com"}
+}
+
+// testAccZone returns the zone acceptance tests create records in.
+func testAccZone() string {
+	return testAccZones()[0]
+}
+
+// testAccRecordName returns a unique record name starting with the
+// acceptance prefix, so that failed runs are cleaned up by the sweepers.
+func testAccRecordName(kind string) string {
+	return fmt.Sprintf("%s%s-%s", testAccRecordPrefix, kind, acctest.RandString(8))
+}
+
+// isTestAccRecord reports whether a record name has a label starting with
+// the acceptance prefix, e.g. "tf-acc-web-x1" or "_acme.tf-acc-web-x1".
+func isTestAccRecord(name string) bool {
+	for _, label := range strings.Split(strings.ToLower(name), ".") {
+		if strings.HasPrefix(label, testAccRecordPrefix) {
+			return true
+		}
+	}
+	return false
+}
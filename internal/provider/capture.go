@@ -0,0 +1,61 @@
+package provider
+
+import (
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/M4XGO/terraform-provider-lws/internal/client"
+)
+
+// HTTPCaptureEnv names the HAR file WriteHTTPCapture adds the HTTP
+// exchanges of the provider process to. HTTPCaptureRedactValuesEnv, when
+// true, masks the DNS record values in the captured bodies.
+const (
+	HTTPCaptureEnv             = "LWS_HTTP_CAPTURE"
+	HTTPCaptureRedactValuesEnv = "LWS_HTTP_CAPTURE_REDACT_VALUES"
+)
+
+var (
+	httpCaptureMu sync.Mutex
+	httpCapture   *client.HARCapture
+)
+
+// httpCaptureFor returns the capture shared by the clients of the process,
+// nil when LWS_HTTP_CAPTURE is not set.
+func httpCaptureFor(version string) *client.HARCapture {
+	if os.Getenv(HTTPCaptureEnv) == "" {
+		return nil
+	}
+
+	httpCaptureMu.Lock()
+	defer httpCaptureMu.Unlock()
+	if httpCapture == nil {
+		value := os.Getenv(HTTPCaptureRedactValuesEnv)
+		redactValues, err := strconv.ParseBool(value)
+		if err != nil {
+			// Err on the side of masking when the value is not understood
+			redactValues = value != ""
+		}
+		httpCapture = client.NewHARCapture(client.HARCaptureOptions{Version: version, RedactValues: redactValues})
+	}
+	return httpCapture
+}
+
+// WriteHTTPCapture adds the HTTP exchanges captured since the provider
+// started to the LWS_HTTP_CAPTURE file. Terraform starts several provider
+// processes per run, so each one merges its exchanges into the archive,
+// replacing only the exchanges of a previous run. It is called once the
+// provider server has stopped and does nothing when no exchange was
+// captured.
+func WriteHTTPCapture() error {
+	httpCaptureMu.Lock()
+	capture := httpCapture
+	httpCaptureMu.Unlock()
+
+	path := os.Getenv(HTTPCaptureEnv)
+	if capture == nil || path == "" || len(capture.HAR().Log.Entries) == 0 {
+		return nil
+	}
+	return capture.MergeFile(path, runID())
+}
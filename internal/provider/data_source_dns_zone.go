@@ -10,6 +10,7 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
@@ -21,7 +22,7 @@ func NewDNSZoneDataSource() datasource.DataSource {
 
 // DNSZoneDataSource defines the data source implementation.
 type DNSZoneDataSource struct {
-	client *client.LWSClient
+	client client.DNSAPI
 }
 
 // DNSZoneDataSourceModel describes the data source data model.
@@ -90,21 +91,24 @@ func (d *DNSZoneDataSource) Configure(ctx context.Context, req datasource.Config
 		return
 	}
 
-	lwsClient, ok := req.ProviderData.(*client.LWSClient)
+	dnsAPI, ok := req.ProviderData.(client.DNSAPI)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Data Source Configure Type",
-			fmt.Sprintf("Expected *client.LWSClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected client.DNSAPI, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 
 		return
 	}
 
-	d.client = lwsClient
+	d.client = dnsAPI
 }
 
 func (d *DNSZoneDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	ctx, span := startOperation(ctx, "lws_dns_zone.Read")
+	defer func() { endOperation(span, resp.Diagnostics) }()
+
 	var data DNSZoneDataSourceModel
 
 	// Read Terraform configuration data into the model
@@ -115,11 +119,12 @@ func (d *DNSZoneDataSource) Read(ctx context.Context, req datasource.ReadRequest
 	}
 
 	zoneName := data.Name.ValueString()
+	span.SetAttributes(attribute.String("lws.zone", zoneName))
 	tflog.Info(ctx, "Reading DNS zone", map[string]interface{}{
 		"zone_name": zoneName,
-		"base_url":  d.client.BaseURL,
-		"login":     d.client.Login,
-		"test_mode": d.client.TestMode,
+		"base_url":  d.client.Describe().BaseURL,
+		"login":     d.client.Describe().Login,
+		"test_mode": d.client.Describe().TestMode,
 	})
 
 	// Get DNS zone information from LWS API
@@ -128,18 +133,13 @@ func (d *DNSZoneDataSource) Read(ctx context.Context, req datasource.ReadRequest
 		tflog.Error(ctx, "Failed to read DNS zone", map[string]interface{}{
 			"zone_name": zoneName,
 			"error":     err.Error(),
-			"base_url":  d.client.BaseURL,
-			"login":     d.client.Login,
+			"base_url":  d.client.Describe().BaseURL,
+			"login":     d.client.Describe().Login,
 		})
 
 		// Provide more helpful error message
 		errorMsg := fmt.Sprintf("Unable to read DNS zone '%s', got error: %s", zoneName, err)
-		if d.client.TestMode {
-			errorMsg += "\n\nNote: You're in test mode. Make sure your test server is configured correctly."
-		} else {
-			errorMsg += fmt.Sprintf("\n\nAPI Details:\n- Base URL: %s\n- Login: %s\n- Expected endpoint: %s/domain/%s/zdns",
-				d.client.BaseURL, d.client.Login, d.client.BaseURL, zoneName)
-		}
+		errorMsg += clientErrorDetails(d.client.Describe(), zoneName)
 
 		resp.Diagnostics.AddError("Client Error", errorMsg)
 		return
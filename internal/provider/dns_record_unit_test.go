@@ -1,14 +1,27 @@
 package provider
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/M4XGO/terraform-provider-lws/internal/client"
+	"github.com/M4XGO/terraform-provider-lws/internal/lwsfake"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
 )
 
 func TestDNSRecordResource_Metadata(t *testing.T) {
@@ -436,73 +449,72 @@ func TestDNSRecord_ExistingRecordDetection(t *testing.T) {
 func TestDNSRecord_APIErrorDetection(t *testing.T) {
 	tests := []struct {
 		name                   string
-		errorMessage           string
+		err                    error
 		shouldIndicateExisting bool
 		description            string
 	}{
 		{
-			name:                   "cannot_add_record",
-			errorMessage:           "Cannot add record to the DNS Zone. Record invalid.",
+			name: "rejected_create",
+			err: &client.APIError{StatusCode: http.StatusBadRequest, Code: 400, Method: http.MethodPost,
+				Info: "Cannot add record to the DNS Zone. Record invalid."},
 			shouldIndicateExisting: true,
-			description:            "LWS API 'cannot add record' error should indicate existing record",
+			description:            "LWS rejecting a create should look for an existing record",
 		},
 		{
-			name:                   "record_invalid",
-			errorMessage:           "Record invalid",
+			name: "reworded_rejected_create",
+			err: &client.APIError{StatusCode: http.StatusBadRequest, Code: 400, Method: http.MethodPost,
+				Info: map[string]interface{}{"name": "Entry already present"}},
 			shouldIndicateExisting: true,
-			description:            "Generic 'record invalid' should indicate existing record",
+			description:            "Detection must not depend on the wording of the LWS message",
 		},
 		{
-			name:                   "already_exists",
-			errorMessage:           "Record already exists",
+			name:                   "explicit_conflict",
+			err:                    &client.APIError{StatusCode: http.StatusConflict, Method: http.MethodPost},
 			shouldIndicateExisting: true,
-			description:            "Explicit 'already exists' should indicate existing record",
+			description:            "HTTP 409 should indicate existing record",
 		},
 		{
-			name:                   "duplicate_record",
-			errorMessage:           "Duplicate record found",
+			name:                   "wrapped_conflict",
+			err:                    fmt.Errorf("create failed: %w", &client.APIError{StatusCode: http.StatusConflict, Method: http.MethodPost}),
 			shouldIndicateExisting: true,
-			description:            "Duplicate record error should indicate existing record",
-		},
-		{
-			name:                   "case_insensitive_cannot_add",
-			errorMessage:           "CANNOT ADD RECORD TO THE DNS ZONE. RECORD INVALID.",
-			shouldIndicateExisting: true,
-			description:            "Case insensitive matching should work for error detection",
+			description:            "Wrapped API errors should still be classified",
 		},
 		{
 			name:                   "network_error",
-			errorMessage:           "Connection timeout",
+			err:                    errors.New("Connection timeout"),
 			shouldIndicateExisting: false,
 			description:            "Network errors should not indicate existing record",
 		},
 		{
 			name:                   "permission_error",
-			errorMessage:           "Access denied",
+			err:                    &client.APIError{StatusCode: http.StatusForbidden, Code: 403, Method: http.MethodPost, Info: "Access denied"},
 			shouldIndicateExisting: false,
 			description:            "Permission errors should not indicate existing record",
 		},
 		{
 			name:                   "zone_not_found",
-			errorMessage:           "Zone not found",
+			err:                    &client.APIError{StatusCode: http.StatusNotFound, Code: 404, Method: http.MethodPost, Info: "Zone not found"},
 			shouldIndicateExisting: false,
 			description:            "Zone not found should not indicate existing record",
 		},
+		{
+			name:                   "challenge",
+			err:                    &client.APIError{StatusCode: http.StatusForbidden, Method: http.MethodPost, Challenge: true},
+			shouldIndicateExisting: false,
+			description:            "Cloudflare challenges should not indicate existing record",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Simulate the error detection logic from the actual code
-			errorMsg := strings.ToLower(tt.errorMessage)
-			actualIndicatesExisting := strings.Contains(errorMsg, "cannot add record") ||
-				strings.Contains(errorMsg, "record invalid") ||
-				strings.Contains(errorMsg, "already exists") ||
-				strings.Contains(errorMsg, "duplicate")
+			// A rejected create may be a duplicate as well as an invalid
+			// record, the resource looks at the zone to tell
+			actualIndicatesExisting := client.IsConflict(tt.err) || client.IsRejected(tt.err)
 
 			if actualIndicatesExisting != tt.shouldIndicateExisting {
 				t.Errorf("%s: expected indicates_existing=%v, got indicates_existing=%v",
 					tt.description, tt.shouldIndicateExisting, actualIndicatesExisting)
-				t.Errorf("  Error message: '%s'", tt.errorMessage)
+				t.Errorf("  Error: '%v'", tt.err)
 			}
 		})
 	}
@@ -739,128 +751,81 @@ func TestDNSRecord_ZonePreservation(t *testing.T) {
 // Tests for idempotent deletion logic
 func TestDNSRecord_IdempotentDeletion(t *testing.T) {
 	tests := []struct {
-		name                 string
-		apiError             string
-		shouldSucceed        bool
-		shouldHaveWarning    bool
-		expectedWarningTitle string
-		description          string
+		name          string
+		err           error
+		recordInZone  bool
+		shouldSucceed bool
+		description   string
 	}{
 		{
-			name:                 "not_found_error",
-			apiError:             "Record not found",
-			shouldSucceed:        true,
-			shouldHaveWarning:    true,
-			expectedWarningTitle: "DNS Record Already Deleted",
-			description:          "Should succeed when record is not found",
-		},
-		{
-			name:                 "does_not_exist_error",
-			apiError:             "Record does not exist",
-			shouldSucceed:        true,
-			shouldHaveWarning:    true,
-			expectedWarningTitle: "DNS Record Already Deleted",
-			description:          "Should succeed when record does not exist",
-		},
-		{
-			name:                 "record_with_id_error",
-			apiError:             "Record with ID 12345 not found",
-			shouldSucceed:        true,
-			shouldHaveWarning:    true,
-			expectedWarningTitle: "DNS Record Already Deleted",
-			description:          "Should succeed when specific record ID not found",
+			name:          "not_found_error",
+			err:           &client.APIError{StatusCode: http.StatusNotFound, Code: 404, Method: http.MethodDelete, Info: "Record not found"},
+			recordInZone:  true,
+			shouldSucceed: true,
+			description:   "Should succeed when the API reports the record as not found",
 		},
 		{
-			name:                 "no_record_found_error",
-			apiError:             "No record found with the specified criteria",
-			shouldSucceed:        true,
-			shouldHaveWarning:    true,
-			expectedWarningTitle: "DNS Record Already Deleted",
-			description:          "Should succeed when no record found",
+			name:          "not_found_code_in_envelope",
+			err:           &client.APIError{StatusCode: http.StatusOK, Code: 404, Method: http.MethodDelete, Info: "Whatever wording"},
+			recordInZone:  true,
+			shouldSucceed: true,
+			description:   "Should succeed when only the envelope code says not found",
 		},
 		{
-			name:                 "invalid_record_id_error",
-			apiError:             "Invalid record ID provided",
-			shouldSucceed:        true,
-			shouldHaveWarning:    true,
-			expectedWarningTitle: "DNS Record Already Deleted",
-			description:          "Should succeed when record ID is invalid (likely deleted)",
+			name:          "rejected_and_gone",
+			err:           &client.APIError{StatusCode: http.StatusBadRequest, Code: 400, Method: http.MethodDelete, Info: "Invalid record ID provided"},
+			recordInZone:  false,
+			shouldSucceed: true,
+			description:   "Should succeed when the delete is rejected and the record is absent from the zone",
 		},
 		{
-			name:                 "record_id_not_found_error",
-			apiError:             "Record ID not found in zone",
-			shouldSucceed:        true,
-			shouldHaveWarning:    true,
-			expectedWarningTitle: "DNS Record Already Deleted",
-			description:          "Should succeed when record ID not found in zone",
+			name:          "rejected_but_present",
+			err:           &client.APIError{StatusCode: http.StatusBadRequest, Code: 400, Method: http.MethodDelete, Info: "Invalid record ID provided"},
+			recordInZone:  true,
+			shouldSucceed: false,
+			description:   "Should fail when the delete is rejected but the record still exists",
 		},
 		{
-			name:                 "case_insensitive_not_found",
-			apiError:             "RECORD NOT FOUND",
-			shouldSucceed:        true,
-			shouldHaveWarning:    true,
-			expectedWarningTitle: "DNS Record Already Deleted",
-			description:          "Should handle case-insensitive error messages",
+			name:          "network_error",
+			err:           errors.New("Connection timeout"),
+			recordInZone:  false,
+			shouldSucceed: false,
+			description:   "Should fail for network errors",
 		},
 		{
-			name:                 "network_error",
-			apiError:             "Connection timeout",
-			shouldSucceed:        false,
-			shouldHaveWarning:    false,
-			expectedWarningTitle: "",
-			description:          "Should fail for network errors",
+			name:          "permission_error",
+			err:           &client.APIError{StatusCode: http.StatusForbidden, Code: 403, Method: http.MethodDelete, Info: "Access denied"},
+			recordInZone:  false,
+			shouldSucceed: false,
+			description:   "Should fail for permission errors",
 		},
 		{
-			name:                 "permission_error",
-			apiError:             "Access denied",
-			shouldSucceed:        false,
-			shouldHaveWarning:    false,
-			expectedWarningTitle: "",
-			description:          "Should fail for permission errors",
-		},
-		{
-			name:                 "api_limit_error",
-			apiError:             "Rate limit exceeded",
-			shouldSucceed:        false,
-			shouldHaveWarning:    false,
-			expectedWarningTitle: "",
-			description:          "Should fail for API limit errors",
-		},
-		{
-			name:                 "generic_error",
-			apiError:             "Internal server error",
-			shouldSucceed:        false,
-			shouldHaveWarning:    false,
-			expectedWarningTitle: "",
-			description:          "Should fail for generic server errors",
+			name:          "api_limit_error",
+			err:           &client.APIError{StatusCode: http.StatusTooManyRequests, Method: http.MethodDelete, Info: "Rate limit exceeded"},
+			recordInZone:  false,
+			shouldSucceed: false,
+			description:   "Should fail for API limit errors",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Test the error detection logic by simulating different API error messages
-			errorMsg := strings.ToLower(tt.apiError)
-
-			isNotFoundError := strings.Contains(errorMsg, "not found") ||
-				strings.Contains(errorMsg, "does not exist") ||
-				strings.Contains(errorMsg, "record with id") ||
-				strings.Contains(errorMsg, "no record found") ||
-				strings.Contains(errorMsg, "record not found") ||
-				strings.Contains(errorMsg, "invalid record id") ||
-				strings.Contains(errorMsg, "record id not found")
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				records := "[]"
+				if tt.recordInZone {
+					records = `[{"id": 12345, "name": "www", "type": "A", "value": "192.168.1.1", "ttl": 3600}]`
+				}
+				_, _ = w.Write([]byte(`{"code": 200, "info": "Fetched DNS Zone", "data": ` + records + `}`))
+			}))
+			defer server.Close()
 
-			if tt.shouldSucceed && !isNotFoundError {
-				t.Errorf("Test case '%s': Expected error '%s' to be detected as 'not found' but it wasn't. %s",
-					tt.name, tt.apiError, tt.description)
-			}
+			r := &DNSRecordResource{client: client.NewLWSClient("testlogin", "testkey", server.URL, true, 30, 0, 0, 0)}
 
-			if !tt.shouldSucceed && isNotFoundError {
-				t.Errorf("Test case '%s': Expected error '%s' to NOT be detected as 'not found' but it was. %s",
-					tt.name, tt.apiError, tt.description)
+			actual := r.isAlreadyDeleted(context.Background(), tt.err, "example.com", "12345")
+			if actual != tt.shouldSucceed {
+				t.Errorf("Test case '%s': expected shouldSucceed=%v, got %v. %s",
+					tt.name, tt.shouldSucceed, actual, tt.description)
 			}
-
-			t.Logf("✅ Test case '%s': Error '%s' correctly identified as shouldSucceed=%v (%s)",
-				tt.name, tt.apiError, tt.shouldSucceed, tt.description)
 		})
 	}
 }
@@ -928,3 +893,755 @@ func TestDNSRecord_DeletionWarningMessage(t *testing.T) {
 		})
 	}
 }
+
+// fakeDNSAPI is an in-memory client.DNSAPI used to exercise resources
+// without an HTTP server.
+type fakeDNSAPI struct {
+	records map[string][]client.DNSRecord
+	// readErr is returned by every read when set.
+	readErr error
+}
+
+func (f *fakeDNSAPI) GetDNSZone(ctx context.Context, zoneName string) (*client.DNSZone, error) {
+	if f.readErr != nil {
+		return nil, f.readErr
+	}
+	return &client.DNSZone{Name: zoneName, Records: f.records[zoneName]}, nil
+}
+
+func (f *fakeDNSAPI) GetDNSRecord(ctx context.Context, zoneName, recordID string) (*client.DNSRecord, error) {
+	if f.readErr != nil {
+		return nil, f.readErr
+	}
+	for _, record := range f.records[zoneName] {
+		if fmt.Sprint(record.ID) == recordID {
+			found := record
+			return &found, nil
+		}
+	}
+	return nil, &client.APIError{StatusCode: http.StatusNotFound, Code: 404, Method: http.MethodGet}
+}
+
+func (f *fakeDNSAPI) CreateDNSRecord(ctx context.Context, record *client.DNSRecord) (*client.DNSRecord, error) {
+	created := *record
+	created.ID = 0
+	for _, existing := range f.records[record.Zone] {
+		created.ID = max(created.ID, existing.ID)
+	}
+	created.ID++
+	f.records[record.Zone] = append(f.records[record.Zone], created)
+	return &created, nil
+}
+
+func (f *fakeDNSAPI) UpdateDNSRecord(ctx context.Context, record *client.DNSRecord) (*client.DNSRecord, error) {
+	updated := *record
+	for i, existing := range f.records[record.Zone] {
+		if existing.ID == record.ID {
+			f.records[record.Zone][i] = updated
+			return &updated, nil
+		}
+	}
+	return nil, &client.APIError{StatusCode: http.StatusNotFound, Code: 404, Method: http.MethodPut}
+}
+
+func (f *fakeDNSAPI) DeleteDNSRecord(ctx context.Context, recordID int, zoneName string) error {
+	for i, existing := range f.records[zoneName] {
+		if existing.ID == recordID {
+			f.records[zoneName] = append(f.records[zoneName][:i:i], f.records[zoneName][i+1:]...)
+			return nil
+		}
+	}
+	return &client.APIError{StatusCode: http.StatusNotFound, Code: 404, Method: http.MethodDelete}
+}
+
+func (f *fakeDNSAPI) Describe() client.Description {
+	return client.Description{Backend: "fake"}
+}
+
+func TestDNSRecordResource_ConfigureWithDNSAPI(t *testing.T) {
+	fake := &fakeDNSAPI{records: map[string][]client.DNSRecord{
+		"example.com": {{ID: 1, Name: "www", Type: "A", Value: "192.168.1.1", TTL: 3600}},
+	}}
+
+	r := &DNSRecordResource{}
+	resp := &resource.ConfigureResponse{}
+	r.Configure(context.Background(), resource.ConfigureRequest{ProviderData: fake}, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Expected no configure errors, got %v", resp.Diagnostics)
+	}
+
+	rejected := &client.APIError{StatusCode: http.StatusBadRequest, Code: 400, Method: http.MethodDelete}
+	if r.isAlreadyDeleted(context.Background(), rejected, "example.com", "1") {
+		t.Errorf("Expected record 1 to still exist in the fake zone")
+	}
+	if !r.isAlreadyDeleted(context.Background(), rejected, "example.com", "2") {
+		t.Errorf("Expected record 2 to be reported as already deleted")
+	}
+
+	resp = &resource.ConfigureResponse{}
+	r.Configure(context.Background(), resource.ConfigureRequest{ProviderData: "not a client"}, resp)
+	if !resp.Diagnostics.HasError() {
+		t.Errorf("Expected an error for provider data that does not implement client.DNSAPI")
+	}
+}
+
+func TestClientErrorDetails(t *testing.T) {
+	tests := []struct {
+		name     string
+		desc     client.Description
+		expected string
+	}{
+		{
+			name:     "test_mode",
+			desc:     client.Description{Backend: "lws", BaseURL: "https://api.lws.net/v1", TestMode: true},
+			expected: "You're in test mode",
+		},
+		{
+			name:     "lws",
+			desc:     client.Description{Backend: "lws", BaseURL: "https://api.lws.net/v1", Login: "user"},
+			expected: "Expected endpoint: https://api.lws.net/v1/domain/example.com/zdns",
+		},
+		{
+			name:     "no_base_url",
+			desc:     client.Description{Backend: "fake"},
+			expected: "Backend: fake",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details := clientErrorDetails(tt.desc, "example.com")
+			if !strings.Contains(details, tt.expected) {
+				t.Errorf("Expected details to contain %q, got %q", tt.expected, details)
+			}
+		})
+	}
+}
+
+// recordSchema returns the lws_dns_record schema.
+func recordSchema(t *testing.T) schema.Schema {
+	t.Helper()
+
+	resp := &resource.SchemaResponse{}
+	(&DNSRecordResource{}).Schema(context.Background(), resource.SchemaRequest{}, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Unexpected schema errors: %v", resp.Diagnostics)
+	}
+	return resp.Schema
+}
+
+// recordState returns a state holding model.
+func recordState(t *testing.T, model DNSRecordResourceModel) tfsdk.State {
+	t.Helper()
+
+	state := tfsdk.State{Schema: recordSchema(t)}
+	if diags := state.Set(context.Background(), &model); diags.HasError() {
+		t.Fatalf("Unexpected state errors: %v", diags)
+	}
+	return state
+}
+
+// recordPlan returns a plan holding model.
+func recordPlan(t *testing.T, model DNSRecordResourceModel) tfsdk.Plan {
+	t.Helper()
+
+	state := recordState(t, model)
+	return tfsdk.Plan{Schema: state.Schema, Raw: state.Raw}
+}
+
+// recordModel returns the model of a record of example.com with the given ID.
+func recordModel(id, name, recordType, value string) DNSRecordResourceModel {
+	return DNSRecordResourceModel{
+		ID:    types.StringValue(id),
+		Name:  types.StringValue(name),
+		Type:  types.StringValue(recordType),
+		Value: types.StringValue(value),
+		TTL:   types.Int64Value(3600),
+		Zone:  types.StringValue("example.com"),
+	}
+}
+
+// testRecordID returns the state ID of a record of example.com.
+func testRecordID(name, recordType, value string) string {
+	return recordStateID("example.com", client.DNSRecord{Name: name, Type: recordType, Value: value})
+}
+
+// twoApexTXT returns a fake zone holding two TXT records on the apex.
+func twoApexTXT() *fakeDNSAPI {
+	return &fakeDNSAPI{records: map[string][]client.DNSRecord{
+		"example.com": {
+			{ID: 1, Name: "@", Type: "TXT", Value: "v=spf1 -all", TTL: 3600},
+			{ID: 2, Name: "@", Type: "TXT", Value: "google-site-verification=abc", TTL: 3600},
+		},
+	}}
+}
+
+func TestDNSRecordResource_ReadFollowsIdentity(t *testing.T) {
+	verification := testRecordID("@", "TXT", "google-site-verification=abc")
+
+	tests := []struct {
+		name     string
+		state    DNSRecordResourceModel
+		renumber bool
+		removed  bool
+	}{
+		{
+			name:  "composite_id",
+			state: recordModel(verification, "@", "TXT", "google-site-verification=abc"),
+		},
+		{
+			name:     "ids_swapped_by_lws",
+			state:    recordModel(verification, "@", "TXT", "google-site-verification=abc"),
+			renumber: true,
+		},
+		{
+			name:     "legacy_numeric_id_now_on_sibling",
+			state:    recordModel("2", "@", "TXT", "google-site-verification=abc"),
+			renumber: true,
+		},
+		{
+			name:    "deleted_outside_terraform",
+			state:   recordModel(testRecordID("@", "TXT", "gone"), "@", "TXT", "gone"),
+			removed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := twoApexTXT()
+			if tt.renumber {
+				records := fake.records["example.com"]
+				records[0].ID, records[1].ID = records[1].ID, records[0].ID
+			}
+			r := &DNSRecordResource{client: fake}
+
+			state := recordState(t, tt.state)
+			resp := &resource.ReadResponse{State: state}
+			r.Read(context.Background(), resource.ReadRequest{State: state}, resp)
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("Unexpected errors: %v", resp.Diagnostics)
+			}
+
+			if tt.removed {
+				if !resp.State.Raw.IsNull() {
+					t.Errorf("Expected the resource to be removed from state")
+				}
+				return
+			}
+
+			var got DNSRecordResourceModel
+			resp.Diagnostics.Append(resp.State.Get(context.Background(), &got)...)
+			if got.ID.ValueString() != verification {
+				t.Errorf("Expected ID %q, got %q", verification, got.ID.ValueString())
+			}
+			if got.Value.ValueString() != "google-site-verification=abc" {
+				t.Errorf("Expected the verification record, got value %q", got.Value.ValueString())
+			}
+		})
+	}
+}
+
+func TestDNSRecordResource_ReadValueChangedOutsideTerraform(t *testing.T) {
+	tests := []struct {
+		name      string
+		state     DNSRecordResourceModel
+		wantValue string
+	}{
+		{
+			name:      "single_valued_type_is_followed",
+			state:     recordModel(testRecordID("blog", "CNAME", "old.example.net"), "blog", "CNAME", "old.example.net"),
+			wantValue: "new.example.net",
+		},
+		{
+			// A sibling may hold the value, the record is created again
+			name:  "multi_valued_type_leaves_state",
+			state: recordModel(testRecordID("@", "TXT", "v=spf1 ~all"), "@", "TXT", "v=spf1 ~all"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := twoApexTXT()
+			fake.records["example.com"] = append(fake.records["example.com"],
+				client.DNSRecord{ID: 3, Name: "blog", Type: "CNAME", Value: "new.example.net", TTL: 3600})
+			r := &DNSRecordResource{client: fake}
+
+			state := recordState(t, tt.state)
+			resp := &resource.ReadResponse{State: state}
+			r.Read(context.Background(), resource.ReadRequest{State: state}, resp)
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("Unexpected errors: %v", resp.Diagnostics)
+			}
+
+			if tt.wantValue == "" {
+				if !resp.State.Raw.IsNull() {
+					t.Errorf("Expected the resource to be removed from state")
+				}
+				return
+			}
+
+			var got DNSRecordResourceModel
+			resp.Diagnostics.Append(resp.State.Get(context.Background(), &got)...)
+			want := testRecordID(got.Name.ValueString(), got.Type.ValueString(), tt.wantValue)
+			if got.Value.ValueString() != tt.wantValue || got.ID.ValueString() != want {
+				t.Errorf("Expected value %q with ID %q, got %q with %q", tt.wantValue, want, got.Value.ValueString(), got.ID.ValueString())
+			}
+		})
+	}
+}
+
+func TestDNSRecordResource_ReadKeepsStateOnErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{
+			name: "circuit_open",
+			err:  &client.CircuitOpenError{Failures: 5, Cause: &client.APIError{StatusCode: http.StatusForbidden, Challenge: true}},
+		},
+		{
+			name: "unauthorized",
+			err:  &client.APIError{StatusCode: http.StatusUnauthorized, Code: 401, Method: http.MethodGet},
+		},
+		{
+			name: "transport",
+			err:  errors.New("dial tcp 192.0.2.1:443: connect: connection refused"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := twoApexTXT()
+			fake.readErr = tt.err
+			r := &DNSRecordResource{client: fake}
+
+			for _, id := range []string{testRecordID("@", "TXT", "v=spf1 -all"), "1"} {
+				state := recordState(t, recordModel(id, "@", "TXT", "v=spf1 -all"))
+				resp := &resource.ReadResponse{State: state}
+				r.Read(context.Background(), resource.ReadRequest{State: state}, resp)
+
+				if len(resp.Diagnostics.Errors()) != 1 {
+					t.Errorf("Expected a single error for state ID %q, got %v", id, resp.Diagnostics)
+				}
+				if resp.State.Raw.IsNull() {
+					t.Errorf("Expected the resource with state ID %q to stay in state", id)
+				}
+			}
+		})
+	}
+}
+
+func TestDNSRecordResource_CreateKeepsSiblings(t *testing.T) {
+	tests := []struct {
+		name      string
+		plan      DNSRecordResourceModel
+		fake      *fakeDNSAPI
+		wantCount int
+		warning   string
+	}{
+		{
+			name:      "new_txt_value",
+			plan:      recordModel("", "@", "TXT", "v=DMARC1; p=none"),
+			fake:      twoApexTXT(),
+			wantCount: 3,
+		},
+		{
+			name:      "existing_txt_value",
+			plan:      recordModel("", "@", "TXT", "\"google-site-verification=abc\""),
+			fake:      twoApexTXT(),
+			wantCount: 2,
+			warning:   "Adopted Existing DNS Record",
+		},
+		{
+			name: "cname_with_other_value",
+			plan: recordModel("", "_acme", "CNAME", "new.acm.example.net"),
+			fake: &fakeDNSAPI{records: map[string][]client.DNSRecord{
+				"example.com": {{ID: 7, Name: "_acme", Type: "CNAME", Value: "old.acm.example.net", TTL: 3600}},
+			}},
+			wantCount: 1,
+			warning:   "Updated Existing DNS Record",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &DNSRecordResource{client: tt.fake}
+			tt.plan.ID = types.StringUnknown()
+
+			plan := recordPlan(t, tt.plan)
+			resp := &resource.CreateResponse{State: tfsdk.State{Schema: plan.Schema}}
+			r.Create(context.Background(), resource.CreateRequest{Plan: plan}, resp)
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("Unexpected errors: %v", resp.Diagnostics)
+			}
+
+			records := tt.fake.records["example.com"]
+			if len(records) != tt.wantCount {
+				t.Errorf("Expected %d records in the zone, got %d: %v", tt.wantCount, len(records), records)
+			}
+
+			var warning string
+			if warnings := resp.Diagnostics.Warnings(); len(warnings) > 0 {
+				warning = warnings[0].Summary()
+			}
+			if warning != tt.warning {
+				t.Errorf("Expected warning %q, got %q", tt.warning, warning)
+			}
+
+			var got DNSRecordResourceModel
+			resp.Diagnostics.Append(resp.State.Get(context.Background(), &got)...)
+			want := testRecordID(tt.plan.Name.ValueString(), tt.plan.Type.ValueString(), tt.plan.Value.ValueString())
+			if got.ID.ValueString() != want {
+				t.Errorf("Expected ID %q, got %q", want, got.ID.ValueString())
+			}
+			if client.FindRecord(records, client.IdentityOf(client.DNSRecord{
+				Name: tt.plan.Name.ValueString(), Type: tt.plan.Type.ValueString(), Value: tt.plan.Value.ValueString(),
+			})) == nil {
+				t.Errorf("Expected the planned record in the zone, got %v", records)
+			}
+		})
+	}
+}
+
+func TestDNSRecordResource_CreateInTestMode(t *testing.T) {
+	fake := lwsfake.New(lwsfake.WithCredentials("testlogin", "testkey"), lwsfake.WithZone("example.com"))
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	lwsClient, err := client.New(
+		client.WithCredentials("testlogin", "testkey"),
+		client.WithBaseURL(server.URL),
+		client.WithTestMode(true),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	r := &DNSRecordResource{client: lwsClient}
+
+	plan := recordModel("", "www", "A", "192.0.2.10")
+	resp := &resource.CreateResponse{State: tfsdk.State{Schema: recordSchema(t)}}
+	r.Create(context.Background(), resource.CreateRequest{Plan: recordPlan(t, plan)}, resp)
+
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Unexpected errors: %v", resp.Diagnostics)
+	}
+	if warnings := resp.Diagnostics.Warnings(); len(warnings) != 1 || warnings[0].Summary() != "DNS Record Validated in Test Mode" {
+		t.Errorf("Expected a test mode warning, got %v", resp.Diagnostics)
+	}
+	var got DNSRecordResourceModel
+	resp.Diagnostics.Append(resp.State.Get(context.Background(), &got)...)
+	if want := testRecordID("www", "A", "192.0.2.10"); got.ID.ValueString() != want {
+		t.Errorf("Expected ID %q, got %q", want, got.ID.ValueString())
+	}
+	if records := fake.Records("example.com"); len(records) != 0 {
+		t.Errorf("Expected the zone to be unchanged, got %v", records)
+	}
+}
+
+func TestDNSRecordResource_CreateRejected(t *testing.T) {
+	seeded := lwsfake.Record{ID: 1, Name: "www", Type: "A", Value: "192.0.2.10", TTL: 3600}
+	fake := lwsfake.New(lwsfake.WithCredentials("testlogin", "testkey"), lwsfake.WithZone("example.com", seeded))
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	lwsClient, err := client.New(
+		client.WithCredentials("testlogin", "testkey"),
+		client.WithBaseURL(server.URL),
+		client.WithTestMode(false),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	r := &DNSRecordResource{client: lwsClient}
+
+	// No record of the zone matches an invalid value, so the validation
+	// message of the API is reported instead of a conflict
+	plan := recordModel("", "www", "A", "2001:db8::1")
+	resp := &resource.CreateResponse{State: tfsdk.State{Schema: recordSchema(t)}}
+	r.Create(context.Background(), resource.CreateRequest{Plan: recordPlan(t, plan)}, resp)
+
+	if !resp.Diagnostics.HasError() {
+		t.Fatalf("Expected an error, got %v", resp.Diagnostics)
+	}
+	if detail := resp.Diagnostics.Errors()[0].Detail(); !strings.Contains(detail, "Invalid value") {
+		t.Errorf("Expected the validation message of the API in %q", detail)
+	}
+	if records := fake.Records("example.com"); len(records) != 1 {
+		t.Errorf("Expected the zone to be unchanged, got %v", records)
+	}
+}
+
+func TestDNSRecordResource_UpdateAndDeleteAfterRenumbering(t *testing.T) {
+	fake := twoApexTXT()
+	records := fake.records["example.com"]
+	records[0].ID, records[1].ID = records[1].ID, records[0].ID
+	r := &DNSRecordResource{client: fake}
+
+	prior := recordModel(testRecordID("@", "TXT", "v=spf1 -all"), "@", "TXT", "v=spf1 -all")
+	planned := recordModel(testRecordID("@", "TXT", "v=spf1 mx -all"), "@", "TXT", "v=spf1 mx -all")
+
+	state := recordState(t, prior)
+	updateResp := &resource.UpdateResponse{State: state}
+	r.Update(context.Background(), resource.UpdateRequest{Plan: recordPlan(t, planned), State: state}, updateResp)
+	if updateResp.Diagnostics.HasError() {
+		t.Fatalf("Unexpected update errors: %v", updateResp.Diagnostics)
+	}
+
+	want := []client.DNSRecord{
+		{ID: 2, Name: "@", Type: "TXT", Value: "v=spf1 mx -all", TTL: 3600, Zone: "example.com"},
+		{ID: 1, Name: "@", Type: "TXT", Value: "google-site-verification=abc", TTL: 3600},
+	}
+	if fmt.Sprint(fake.records["example.com"]) != fmt.Sprint(want) {
+		t.Fatalf("Expected the SPF record to be updated, got %v", fake.records["example.com"])
+	}
+
+	deleteResp := &resource.DeleteResponse{State: updateResp.State}
+	r.Delete(context.Background(), resource.DeleteRequest{State: updateResp.State}, deleteResp)
+	if deleteResp.Diagnostics.HasError() || len(deleteResp.Diagnostics.Warnings()) > 0 {
+		t.Fatalf("Unexpected delete diagnostics: %v", deleteResp.Diagnostics)
+	}
+
+	remaining := fake.records["example.com"]
+	if len(remaining) != 1 || remaining[0].Value != "google-site-verification=abc" {
+		t.Errorf("Expected only the verification record to remain, got %v", remaining)
+	}
+
+	// Deleting again finds nothing and leaves the sibling alone
+	deleteResp = &resource.DeleteResponse{State: updateResp.State}
+	r.Delete(context.Background(), resource.DeleteRequest{State: updateResp.State}, deleteResp)
+	if deleteResp.Diagnostics.HasError() || len(deleteResp.Diagnostics.Warnings()) != 1 {
+		t.Errorf("Expected an already deleted warning, got %v", deleteResp.Diagnostics)
+	}
+	if len(fake.records["example.com"]) != 1 {
+		t.Errorf("Expected the verification record to remain, got %v", fake.records["example.com"])
+	}
+}
+
+func TestDNSRecordResource_LogsRedactValues(t *testing.T) {
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+
+	fake := &fakeDNSAPI{records: map[string][]client.DNSRecord{
+		"example.com": {{ID: 7, Name: "_acme", Type: "CNAME", Value: "old-secret.acm.example.net", TTL: 3600}},
+	}}
+	r := &DNSRecordResource{client: fake}
+
+	// Adopting the existing record logs both values
+	created := recordModel("", "_acme", "CNAME", "new-secret.acm.example.net")
+	created.ID = types.StringUnknown()
+	plan := recordPlan(t, created)
+	createResp := &resource.CreateResponse{State: tfsdk.State{Schema: plan.Schema}}
+	r.Create(ctx, resource.CreateRequest{Plan: plan}, createResp)
+	if createResp.Diagnostics.HasError() {
+		t.Fatalf("Unexpected create errors: %v", createResp.Diagnostics)
+	}
+
+	readResp := &resource.ReadResponse{State: createResp.State}
+	r.Read(ctx, resource.ReadRequest{State: createResp.State}, readResp)
+	if readResp.Diagnostics.HasError() {
+		t.Fatalf("Unexpected read errors: %v", readResp.Diagnostics)
+	}
+
+	planned := recordModel(testRecordID("_acme", "CNAME", "newer-secret.acm.example.net"), "_acme", "CNAME", "newer-secret.acm.example.net")
+	updateResp := &resource.UpdateResponse{State: readResp.State}
+	r.Update(ctx, resource.UpdateRequest{Plan: recordPlan(t, planned), State: readResp.State}, updateResp)
+	if updateResp.Diagnostics.HasError() {
+		t.Fatalf("Unexpected update errors: %v", updateResp.Diagnostics)
+	}
+
+	entries, err := tflogtest.MultilineJSONDecode(&output)
+	if err != nil {
+		t.Fatalf("Unable to decode log output: %v", err)
+	}
+	fields := make(map[string]bool)
+	for _, entry := range entries {
+		for field, value := range entry {
+			if strings.Contains(fmt.Sprint(value), "secret") {
+				t.Errorf("Expected the record value to be redacted from %q, got %v", field, entry)
+			}
+			fields[field] = true
+		}
+	}
+	for _, field := range []string{"value", "existing_value", "new_value", "state_value", "api_value", "final_value"} {
+		if !fields[field] {
+			t.Errorf("Expected a log entry with field %q", field)
+		}
+	}
+}
+
+func TestRecordIDPlanModifier(t *testing.T) {
+	current := testRecordID("www", "A", "192.168.1.1")
+
+	tests := []struct {
+		name  string
+		state DNSRecordResourceModel
+		plan  DNSRecordResourceModel
+		want  types.String
+	}{
+		{
+			name:  "unchanged",
+			state: recordModel(current, "www", "A", "192.168.1.1"),
+			plan:  recordModel("", "www", "A", "192.168.1.1"),
+			want:  types.StringValue(current),
+		},
+		{
+			name:  "ttl_only",
+			state: recordModel(current, "www", "A", "192.168.1.1"),
+			plan:  DNSRecordResourceModel{Name: types.StringValue("WWW."), Type: types.StringValue("a"), Value: types.StringValue("192.168.1.1"), TTL: types.Int64Value(60), Zone: types.StringValue("example.com")},
+			want:  types.StringValue(current),
+		},
+		{
+			name:  "value_changed",
+			state: recordModel(current, "www", "A", "192.168.1.1"),
+			plan:  recordModel("", "www", "A", "192.168.1.2"),
+			want:  types.StringValue(testRecordID("www", "A", "192.168.1.2")),
+		},
+		{
+			name:  "legacy_numeric_id",
+			state: recordModel("12345", "www", "A", "192.168.1.1"),
+			plan:  recordModel("", "www", "A", "192.168.1.1"),
+			want:  types.StringValue(current),
+		},
+		{
+			name:  "value_unknown",
+			state: recordModel(current, "www", "A", "192.168.1.1"),
+			plan:  DNSRecordResourceModel{Name: types.StringValue("www"), Type: types.StringValue("A"), Value: types.StringUnknown(), TTL: types.Int64Value(3600), Zone: types.StringValue("example.com")},
+			want:  types.StringUnknown(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.plan.ID = types.StringUnknown()
+			state := recordState(t, tt.state)
+
+			req := planmodifier.StringRequest{
+				Plan:       recordPlan(t, tt.plan),
+				State:      state,
+				StateValue: tt.state.ID,
+				PlanValue:  types.StringUnknown(),
+			}
+			resp := &planmodifier.StringResponse{PlanValue: req.PlanValue}
+			recordIDPlanModifier{}.PlanModifyString(context.Background(), req, resp)
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("Unexpected errors: %v", resp.Diagnostics)
+			}
+			if !resp.PlanValue.Equal(tt.want) {
+				t.Errorf("Expected planned ID %s, got %s", tt.want, resp.PlanValue)
+			}
+		})
+	}
+}
+
+func TestDNSRecordResource_ImportStateIdentity(t *testing.T) {
+	id := testRecordID("www", "A", "192.168.1.1")
+	r := &DNSRecordResource{client: twoApexTXT()}
+
+	resp := &resource.ImportStateResponse{State: tfsdk.State{Schema: recordSchema(t)}}
+	resp.State.Raw = tftypes.NewValue(resp.State.Schema.Type().TerraformType(context.Background()), nil)
+	r.ImportState(context.Background(), resource.ImportStateRequest{ID: id}, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Unexpected errors: %v", resp.Diagnostics)
+	}
+
+	var got DNSRecordResourceModel
+	resp.Diagnostics.Append(resp.State.Get(context.Background(), &got)...)
+	if got.ID.ValueString() != id || got.Zone.ValueString() != "example.com" {
+		t.Errorf("Expected ID %q in zone example.com, got %q in zone %q", id, got.ID.ValueString(), got.Zone.ValueString())
+	}
+}
+
+// lockingDNSAPI wraps a fakeDNSAPI for concurrent use and serialises the
+// changes to its zones like client.LWSClient does.
+type lockingDNSAPI struct {
+	mu    sync.Mutex
+	fake  *fakeDNSAPI
+	zones chan struct{}
+}
+
+func (l *lockingDNSAPI) LockZone(ctx context.Context, zoneName string) (context.Context, func(), error) {
+	l.zones <- struct{}{}
+	return ctx, func() { <-l.zones }, nil
+}
+
+func (l *lockingDNSAPI) GetDNSZone(ctx context.Context, zoneName string) (*client.DNSZone, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	zone, err := l.fake.GetDNSZone(ctx, zoneName)
+	if err == nil {
+		zone.Records = append([]client.DNSRecord(nil), zone.Records...)
+	}
+	return zone, err
+}
+
+func (l *lockingDNSAPI) GetDNSRecord(ctx context.Context, zoneName, recordID string) (*client.DNSRecord, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.fake.GetDNSRecord(ctx, zoneName, recordID)
+}
+
+func (l *lockingDNSAPI) CreateDNSRecord(ctx context.Context, record *client.DNSRecord) (*client.DNSRecord, error) {
+	// Leave time for other creates to run their existing-record check
+	time.Sleep(time.Millisecond)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.fake.CreateDNSRecord(ctx, record)
+}
+
+func (l *lockingDNSAPI) UpdateDNSRecord(ctx context.Context, record *client.DNSRecord) (*client.DNSRecord, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.fake.UpdateDNSRecord(ctx, record)
+}
+
+func (l *lockingDNSAPI) DeleteDNSRecord(ctx context.Context, recordID int, zoneName string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.fake.DeleteDNSRecord(ctx, recordID, zoneName)
+}
+
+func (l *lockingDNSAPI) Describe() client.Description {
+	return l.fake.Describe()
+}
+
+func TestDNSRecordResource_ParallelCreates(t *testing.T) {
+	api := &lockingDNSAPI{
+		fake:  &fakeDNSAPI{records: map[string][]client.DNSRecord{}},
+		zones: make(chan struct{}, 1),
+	}
+	r := &DNSRecordResource{client: api}
+
+	plan := recordModel("", "_dmarc", "TXT", "v=DMARC1; p=none")
+	plan.ID = types.StringUnknown()
+
+	req := resource.CreateRequest{Plan: recordPlan(t, plan)}
+
+	const workers = 10
+	var wg sync.WaitGroup
+	diags := make([]diag.Diagnostics, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp := &resource.CreateResponse{State: tfsdk.State{Schema: req.Plan.Schema}}
+			r.Create(context.Background(), req, resp)
+			diags[i] = resp.Diagnostics
+		}()
+	}
+	wg.Wait()
+
+	adopted := 0
+	for _, d := range diags {
+		if d.HasError() {
+			t.Errorf("Unexpected errors: %v", d)
+		}
+		adopted += len(d.Warnings())
+	}
+
+	if records := api.fake.records["example.com"]; len(records) != 1 {
+		t.Errorf("Expected a single record, got %v", records)
+	}
+	if adopted != workers-1 {
+		t.Errorf("Expected %d creates to adopt the record, got %d", workers-1, adopted)
+	}
+}
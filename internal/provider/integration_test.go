@@ -9,6 +9,8 @@ import (
 	"testing"
 
 	"github.com/M4XGO/terraform-provider-lws/internal/client"
+	"github.com/M4XGO/terraform-provider-lws/internal/lwsfake"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
@@ -16,6 +18,15 @@ const (
 	testRecordEndpoint = "/dns/record/12345"
 )
 
+// mustMarshal encodes the data of a mock API response.
+func mustMarshal(v interface{}) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
 func setupTestServer() *httptest.Server {
 	mux := http.NewServeMux()
 
@@ -46,13 +57,13 @@ func setupTestServer() *httptest.Server {
 			response := client.LWSAPIResponse{
 				Code: 200,
 				Info: "DNS record created",
-				Data: client.DNSRecord{
+				Data: mustMarshal(client.DNSRecord{
 					ID:    1,
 					Name:  req.Name,
 					Type:  req.Type,
 					Value: req.Value,
 					TTL:   req.TTL,
-				},
+				}),
 			}
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(response)
@@ -75,13 +86,13 @@ func setupTestServer() *httptest.Server {
 			response := client.LWSAPIResponse{
 				Code: 200,
 				Info: "DNS record updated",
-				Data: client.DNSRecord{
+				Data: mustMarshal(client.DNSRecord{
 					ID:    req.ID,
 					Name:  req.Name,
 					Type:  req.Type,
 					Value: req.Value,
 					TTL:   req.TTL,
-				},
+				}),
 			}
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(response)
@@ -100,7 +111,7 @@ func setupTestServer() *httptest.Server {
 			response := client.LWSAPIResponse{
 				Code: 200,
 				Info: "Fetched DNS Zone",
-				Data: records,
+				Data: mustMarshal(records),
 			}
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(response)
@@ -119,7 +130,6 @@ func setupTestServer() *httptest.Server {
 			response := client.LWSAPIResponse{
 				Code: 200,
 				Info: "DNS record deleted",
-				Data: nil,
 			}
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(response)
@@ -661,6 +671,59 @@ func TestProvider_IDDriftScenario(t *testing.T) {
 	t.Logf("✅ ID drift scenario test completed successfully")
 }
 
+func TestProvider_RenumberedByLWS(t *testing.T) {
+	// The response to the create is lost, then LWS renumbers the zone
+	fake := lwsfake.New(
+		lwsfake.WithZone("example.com", lwsfake.Record{Name: "@", Type: "MX", Value: "10 mail.example.com"}),
+		lwsfake.WithFaults(lwsfake.Fault{Method: http.MethodPost, Drop: true, Times: 1}),
+	)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	// Not in test mode, which the fake honours by leaving the zone unchanged
+	lwsClient, err := client.New(
+		client.WithCredentials("testlogin", "testkey"),
+		client.WithBaseURL(server.URL),
+		client.WithRetryPolicy(client.NewDefaultRetryPolicy(1, 0, 1)),
+		client.WithZoneCacheTTL(0),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected configuration error: %v", err)
+	}
+	ctx := context.Background()
+
+	created, err := lwsClient.CreateDNSRecord(ctx, &client.DNSRecord{Name: "www", Type: "A", Value: "192.0.2.10", Zone: "example.com", TTL: 3600})
+	if err != nil {
+		t.Fatalf("Failed to create DNS record: %v", err)
+	}
+	if records := fake.Records("example.com"); len(records) != 2 || records[1].ID != created.ID {
+		t.Fatalf("Expected the record to be created once, got %+v", records)
+	}
+
+	fake.Renumber("example.com")
+	if _, err := lwsClient.GetDNSRecord(ctx, "example.com", fmt.Sprintf("%d", created.ID)); !client.IsNotFound(err) {
+		t.Fatalf("Expected the old ID to be gone, got %v", err)
+	}
+
+	r := &DNSRecordResource{client: lwsClient}
+	id := testRecordID("www", "A", "192.0.2.10")
+	state := recordState(t, recordModel(id, "www", "A", "192.0.2.10"))
+	readResp := &resource.ReadResponse{State: state}
+	r.Read(ctx, resource.ReadRequest{State: state}, readResp)
+	if readResp.Diagnostics.HasError() || readResp.State.Raw.IsNull() {
+		t.Fatalf("Expected the renumbered record to be found, got %v", readResp.Diagnostics)
+	}
+
+	deleteResp := &resource.DeleteResponse{State: readResp.State}
+	r.Delete(ctx, resource.DeleteRequest{State: readResp.State}, deleteResp)
+	if deleteResp.Diagnostics.HasError() {
+		t.Fatalf("Unexpected errors: %v", deleteResp.Diagnostics)
+	}
+	if records := fake.Records("example.com"); len(records) != 1 || records[0].Type != "MX" {
+		t.Errorf("Expected only the MX record to remain, got %+v", records)
+	}
+}
+
 // setupTestServerWithIDDrift creates a test server that simulates ID drift
 func setupTestServerWithIDDrift() *httptest.Server {
 	var createdRecord *client.DNSRecord
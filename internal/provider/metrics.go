@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/M4XGO/terraform-provider-lws/internal/client"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// MetricsFileEnv names the file ReportMetrics adds the API metrics to: a
+// JSON file when it ends with ".json", an OpenMetrics text file otherwise.
+const MetricsFileEnv = "LWS_METRICS_FILE"
+
+// RunIDEnv names the identifier of the run the metrics and HTTP capture
+// files cover. Without it, a run is a Terraform command: the provider
+// processes it starts share the Terraform process as parent.
+const RunIDEnv = "LWS_RUN_ID"
+
+// apiMetrics records the API calls of every client the provider process
+// configures.
+var apiMetrics = client.NewMetrics()
+
+// ReportMetrics logs a summary of the API calls made by the provider
+// process and, when LWS_METRICS_FILE is set, adds them to that file.
+// Terraform starts several provider processes per run, so each one merges
+// its calls into the file, replacing only the metrics of a previous run.
+// It is called once the provider server has stopped, and does nothing
+// without API calls.
+func ReportMetrics(ctx context.Context) error {
+	totals := apiMetrics.Totals()
+	if totals.Requests == 0 {
+		return nil
+	}
+
+	tflog.Info(ctx, "LWS API metrics", totals.Fields())
+	for _, series := range apiMetrics.Snapshot() {
+		tflog.Debug(ctx, "LWS API metrics by endpoint", series.Fields())
+	}
+
+	path := os.Getenv(MetricsFileEnv)
+	if path == "" {
+		return nil
+	}
+	return apiMetrics.MergeFile(path, runID())
+}
+
+// runID returns the identifier of the run the provider process is part of.
+func runID() string {
+	if id := os.Getenv(RunIDEnv); id != "" {
+		return id
+	}
+	return fmt.Sprintf("terraform-%d", os.Getppid())
+}
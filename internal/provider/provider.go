@@ -2,15 +2,22 @@ package provider
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/M4XGO/terraform-provider-lws/internal/client"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 // Nom du provider
@@ -37,6 +44,30 @@ type LWSProviderModel struct {
 	Retries  types.Int64  `tfsdk:"retries"`
 	Delay    types.Int64  `tfsdk:"delay"`
 	Backoff  types.Int64  `tfsdk:"backoff"`
+
+	BaseUrls         types.List  `tfsdk:"base_urls"`
+	FailoverCooldown types.Int64 `tfsdk:"failover_cooldown"`
+
+	RequestsPerSecond     types.Float64 `tfsdk:"requests_per_second"`
+	MaxConcurrentRequests types.Int64   `tfsdk:"max_concurrent_requests"`
+	ZoneCacheTTL          types.Int64   `tfsdk:"zone_cache_ttl"`
+
+	CircuitBreakerThreshold types.Int64 `tfsdk:"circuit_breaker_threshold"`
+	CircuitBreakerCooldown  types.Int64 `tfsdk:"circuit_breaker_cooldown"`
+
+	ConsistencyPollInterval types.Int64 `tfsdk:"consistency_poll_interval"`
+	ConsistencyMaxWait      types.Int64 `tfsdk:"consistency_max_wait"`
+	ConsistencyBackoff      types.Int64 `tfsdk:"consistency_backoff"`
+
+	LogRedactPatterns types.List `tfsdk:"log_redact_patterns"`
+
+	ProxyUrl           types.String `tfsdk:"proxy_url"`
+	CACertFile         types.String `tfsdk:"ca_cert_file"`
+	CACertPEM          types.String `tfsdk:"ca_cert_pem"`
+	ClientCert         types.String `tfsdk:"client_cert"`
+	ClientKey          types.String `tfsdk:"client_key"`
+	InsecureSkipVerify types.Bool   `tfsdk:"insecure_skip_verify"`
+	PinnedPublicKeys   types.List   `tfsdk:"pinned_public_keys"`
 }
 
 func (p *LWSProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
@@ -60,6 +91,15 @@ func (p *LWSProvider) Schema(ctx context.Context, req provider.SchemaRequest, re
 				MarkdownDescription: "LWS API base URL. Defaults to https://api.lws.net/v1. Can also be set with the LWS_BASE_URL environment variable.",
 				Optional:            true,
 			},
+			"base_urls": schema.ListAttribute{
+				MarkdownDescription: "Ordered list of LWS API base URLs, the primary one first, to use instead of `base_url`. API calls fail over to the next URL when one cannot be reached, answers with a 5xx error or a Cloudflare challenge page, and return to the primary URL once `failover_cooldown` has elapsed. Can also be set with the LWS_BASE_URLS environment variable, as a comma separated list.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"failover_cooldown": schema.Int64Attribute{
+				MarkdownDescription: "Number of seconds a base URL that failed is passed over in favour of the next one in `base_urls` before it is tried first again. Defaults to 60 seconds.",
+				Optional:            true,
+			},
 			"test_mode": schema.BoolAttribute{
 				MarkdownDescription: "Enable test mode for LWS API. Defaults to false. Can also be set with the LWS_TEST_MODE environment variable.",
 				Optional:            true,
@@ -69,17 +109,84 @@ func (p *LWSProvider) Schema(ctx context.Context, req provider.SchemaRequest, re
 				Optional:            true,
 			},
 			"retries": schema.Int64Attribute{
-				MarkdownDescription: "Number of retries for API requests. Defaults to 3.",
+				MarkdownDescription: "Number of retries for transient API failures (network errors, throttling, challenge pages and 5xx responses). Authentication and validation errors are never retried. Defaults to 3.",
 				Optional:            true,
 			},
 			"delay": schema.Int64Attribute{
-				MarkdownDescription: "Delay between retries for API requests in seconds. Defaults to 15 seconds.",
+				MarkdownDescription: "Base delay between retries for API requests in seconds. A random jitter is applied and a Retry-After header sent by the API takes precedence. Defaults to 15 seconds.",
 				Optional:            true,
 			},
 			"backoff": schema.Int64Attribute{
 				MarkdownDescription: "Backoff multiplier for delay between retries. Defaults to 2.",
 				Optional:            true,
 			},
+			"requests_per_second": schema.Float64Attribute{
+				MarkdownDescription: "Maximum average number of API requests per second, shared by all resources. The rate is lowered automatically when LWS answers with HTTP 429 or a Cloudflare challenge, and restored progressively afterwards. Set to 0 to disable. Defaults to 5.",
+				Optional:            true,
+			},
+			"max_concurrent_requests": schema.Int64Attribute{
+				MarkdownDescription: "Maximum number of API requests in flight at the same time. Defaults to 4.",
+				Optional:            true,
+			},
+			"zone_cache_ttl": schema.Int64Attribute{
+				MarkdownDescription: "Number of seconds a DNS zone listing is reused by all resources before it is fetched again. Changes made by the provider are applied to the cached listing immediately. Set to 0 to disable. Defaults to 30 seconds.",
+				Optional:            true,
+			},
+			"circuit_breaker_threshold": schema.Int64Attribute{
+				MarkdownDescription: "Number of consecutive Cloudflare challenges or 5xx responses after which API calls are suspended and pending operations fail immediately. Set to 0 to disable. Defaults to 5.",
+				Optional:            true,
+			},
+			"circuit_breaker_cooldown": schema.Int64Attribute{
+				MarkdownDescription: "Number of seconds API calls stay suspended before a single probe request checks whether the API has recovered. Defaults to 30 seconds.",
+				Optional:            true,
+			},
+			"consistency_poll_interval": schema.Int64Attribute{
+				MarkdownDescription: "Number of seconds between the first two reads of the zone listing made after a change to check that it shows the change. Defaults to 1 second.",
+				Optional:            true,
+			},
+			"consistency_max_wait": schema.Int64Attribute{
+				MarkdownDescription: "Maximum number of seconds to wait after creating, updating or deleting a record until the zone listing reflects the change. Set to 0 to disable waiting. Defaults to 30 seconds.",
+				Optional:            true,
+			},
+			"consistency_backoff": schema.Int64Attribute{
+				MarkdownDescription: "Multiplier applied to the interval between two reads of the zone listing while waiting for a change. Defaults to 2.",
+				Optional:            true,
+			},
+			"log_redact_patterns": schema.ListAttribute{
+				MarkdownDescription: "Regular expressions whose matches are masked in the API logs. Authentication headers and DNS record values are always masked.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"proxy_url": schema.StringAttribute{
+				MarkdownDescription: "URL of the proxy used to reach the LWS API (http, https or socks5). Defaults to the HTTPS_PROXY and HTTP_PROXY environment variables.",
+				Optional:            true,
+			},
+			"ca_cert_file": schema.StringAttribute{
+				MarkdownDescription: "Path to a PEM bundle of certificate authorities trusted in addition to the system ones, e.g. the CA of a TLS intercepting proxy.",
+				Optional:            true,
+			},
+			"ca_cert_pem": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded certificate authorities trusted in addition to the system ones.",
+				Optional:            true,
+			},
+			"client_cert": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded client certificate, or path to it, presented for mutual TLS. Requires `client_key`.",
+				Optional:            true,
+			},
+			"client_key": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded private key of `client_cert`, or path to it.",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"insecure_skip_verify": schema.BoolAttribute{
+				MarkdownDescription: "Disable verification of the API certificate. Only use this for debugging, it exposes your API key to anyone on the network path. Defaults to false.",
+				Optional:            true,
+			},
+			"pinned_public_keys": schema.ListAttribute{
+				MarkdownDescription: "SHA-256 hashes of the public keys accepted for the LWS API host, in the `sha256/<base64>` format. When set, the connection fails unless the API presents a certificate matching one of them.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
 		},
 	}
 }
@@ -123,11 +230,21 @@ func (p *LWSProvider) Configure(ctx context.Context, req provider.ConfigureReque
 	login := os.Getenv("LWS_LOGIN")
 	apiKey := os.Getenv("LWS_API_KEY")
 	baseUrl := os.Getenv("LWS_BASE_URL")
+	baseUrls := splitList(os.Getenv("LWS_BASE_URLS"))
 	testMode := os.Getenv("LWS_TEST_MODE") == "true"
 	timeout := 30
 	retries := 3
 	delay := 15
 	backoff := 2
+	requestsPerSecond := client.DefaultRequestsPerSecond
+	maxConcurrentRequests := client.DefaultMaxConcurrentRequests
+	zoneCacheTTL := int(client.DefaultZoneCacheTTL / time.Second)
+	breakerThreshold := client.DefaultBreakerThreshold
+	breakerCooldown := int(client.DefaultBreakerCooldown / time.Second)
+	consistencyInterval := int(client.DefaultConsistencyInterval / time.Second)
+	consistencyMaxWait := int(client.DefaultConsistencyMaxWait / time.Second)
+	consistencyBackoff := client.DefaultConsistencyMultiplier
+	failoverCooldown := int(client.DefaultFailoverCooldown / time.Second)
 
 	if !data.Login.IsNull() {
 		login = data.Login.ValueString()
@@ -137,8 +254,30 @@ func (p *LWSProvider) Configure(ctx context.Context, req provider.ConfigureReque
 		apiKey = data.ApiKey.ValueString()
 	}
 
+	if !data.BaseUrl.IsNull() && !data.BaseUrls.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("base_urls"),
+			"Conflicting base_url and base_urls",
+			"Set either base_url, for a single API address, or base_urls, for an ordered list of addresses to fail over between, but not both.",
+		)
+		return
+	}
+
 	if !data.BaseUrl.IsNull() {
 		baseUrl = data.BaseUrl.ValueString()
+		baseUrls = nil
+	}
+
+	if !data.BaseUrls.IsNull() && !data.BaseUrls.IsUnknown() {
+		baseUrls = nil
+		resp.Diagnostics.Append(data.BaseUrls.ElementsAs(ctx, &baseUrls, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	if !data.FailoverCooldown.IsNull() {
+		failoverCooldown = int(data.FailoverCooldown.ValueInt64())
 	}
 
 	if !data.TestMode.IsNull() {
@@ -161,70 +300,124 @@ func (p *LWSProvider) Configure(ctx context.Context, req provider.ConfigureReque
 		backoff = int(data.Backoff.ValueInt64())
 	}
 
-	// Default base URL
-	if baseUrl == "" {
-		baseUrl = "https://api.lws.net/v1"
+	if !data.RequestsPerSecond.IsNull() {
+		requestsPerSecond = data.RequestsPerSecond.ValueFloat64()
 	}
 
-	// If any of the expected configurations are missing, return
-	// errors with provider-specific guidance.
+	if !data.MaxConcurrentRequests.IsNull() {
+		maxConcurrentRequests = int(data.MaxConcurrentRequests.ValueInt64())
+	}
 
-	if login == "" {
-		resp.Diagnostics.AddAttributeError(
-			path.Root("login"),
-			"Missing LWS API Login",
-			"The provider requires a LWS login ID. Set the login value in the configuration or use the LWS_LOGIN environment variable. "+
-				"If either is already set, ensure the value is not empty.",
-		)
+	if !data.ZoneCacheTTL.IsNull() {
+		zoneCacheTTL = int(data.ZoneCacheTTL.ValueInt64())
 	}
 
-	if apiKey == "" {
-		resp.Diagnostics.AddAttributeError(
-			path.Root("api_key"),
-			"Missing LWS API Key",
-			"The provider requires a LWS API key. Set the api_key value in the configuration or use the LWS_API_KEY environment variable. "+
-				"If either is already set, ensure the value is not empty.",
-		)
+	if !data.CircuitBreakerThreshold.IsNull() {
+		breakerThreshold = int(data.CircuitBreakerThreshold.ValueInt64())
 	}
 
-	if timeout <= 0 {
-		resp.Diagnostics.AddAttributeError(
-			path.Root("timeout"),
-			"Invalid timeout value",
-			"The timeout value must be a positive integer representing seconds.",
-		)
+	if !data.CircuitBreakerCooldown.IsNull() {
+		breakerCooldown = int(data.CircuitBreakerCooldown.ValueInt64())
 	}
 
-	if retries < 0 {
-		resp.Diagnostics.AddAttributeError(
-			path.Root("retries"),
-			"Invalid retries value",
-			"The retries value cannot be negative.",
-		)
+	if !data.ConsistencyPollInterval.IsNull() {
+		consistencyInterval = int(data.ConsistencyPollInterval.ValueInt64())
 	}
 
-	if delay < 0 {
-		resp.Diagnostics.AddAttributeError(
-			path.Root("delay"),
-			"Invalid delay value",
-			"The delay value cannot be negative.",
-		)
+	if !data.ConsistencyMaxWait.IsNull() {
+		consistencyMaxWait = int(data.ConsistencyMaxWait.ValueInt64())
 	}
 
-	if backoff < 1 {
-		resp.Diagnostics.AddAttributeError(
-			path.Root("backoff"),
-			"Invalid backoff value",
-			"The backoff value must be at least 1.",
+	if !data.ConsistencyBackoff.IsNull() {
+		consistencyBackoff = int(data.ConsistencyBackoff.ValueInt64())
+	}
+
+	var redactPatterns []string
+	if !data.LogRedactPatterns.IsNull() && !data.LogRedactPatterns.IsUnknown() {
+		resp.Diagnostics.Append(data.LogRedactPatterns.ElementsAs(ctx, &redactPatterns, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	transportOptions := client.TransportOptions{
+		ProxyURL:           data.ProxyUrl.ValueString(),
+		CACertFile:         data.CACertFile.ValueString(),
+		CACertPEM:          data.CACertPEM.ValueString(),
+		ClientCert:         data.ClientCert.ValueString(),
+		ClientKey:          data.ClientKey.ValueString(),
+		InsecureSkipVerify: data.InsecureSkipVerify.ValueBool(),
+	}
+	if !data.PinnedPublicKeys.IsNull() && !data.PinnedPublicKeys.IsUnknown() {
+		resp.Diagnostics.Append(data.PinnedPublicKeys.ElementsAs(ctx, &transportOptions.PinnedPublicKeys, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	if transportOptions.InsecureSkipVerify {
+		tflog.Warn(ctx, "TLS certificate verification of the LWS API is disabled")
+		resp.Diagnostics.AddAttributeWarning(
+			path.Root("insecure_skip_verify"),
+			"TLS certificate verification disabled",
+			"insecure_skip_verify is enabled: the provider accepts any certificate for the LWS API, so anyone on the network path "+
+				"can read your API key and alter DNS changes. Prefer ca_cert_file or ca_cert_pem to trust an intercepting proxy.",
 		)
 	}
 
-	if resp.Diagnostics.HasError() {
+	// Default base URL
+	if baseUrl == "" {
+		baseUrl = client.DefaultBaseURL
+	}
+
+	transport, err := replayTransport()
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to Load the LWS_REPLAY Cassette",
+			"The provider cannot replay the LWS API responses: "+err.Error(),
+		)
 		return
 	}
+	if transport != nil {
+		tflog.Warn(ctx, "Replaying LWS API responses from a cassette, no request is sent to the API", map[string]interface{}{
+			"cassette": os.Getenv(ReplayEnv),
+		})
+		// Cassettes hold no credentials, and replayed failures need no wait
+		if login == "" {
+			login = "replay"
+		}
+		if apiKey == "" {
+			apiKey = "replay"
+		}
+		delay = 0
+	}
 
-	// Create a new LWS client using the configuration values
-	lwsClient := client.NewLWSClient(login, apiKey, baseUrl, testMode, timeout, retries, delay, backoff)
+	// Create a new LWS client using the configuration values. The client
+	// validates every setting and reports errors per attribute.
+	lwsClient, err := client.New(
+		client.WithCredentials(login, apiKey),
+		client.WithUserAgent(userAgent(p.version, req.TerraformVersion)),
+		client.WithBaseURL(baseUrl),
+		client.WithBaseURLs(baseUrls...),
+		client.WithFailoverCooldown(time.Duration(failoverCooldown)*time.Second),
+		client.WithTestMode(testMode),
+		client.WithTimeout(time.Duration(timeout)*time.Second),
+		client.WithRetryPolicy(client.NewDefaultRetryPolicy(retries, time.Duration(delay)*time.Second, float64(backoff))),
+		client.WithRateLimit(requestsPerSecond, int(math.Ceil(requestsPerSecond)), maxConcurrentRequests),
+		client.WithZoneCacheTTL(time.Duration(zoneCacheTTL)*time.Second),
+		client.WithCircuitBreaker(breakerThreshold, time.Duration(breakerCooldown)*time.Second),
+		client.WithConsistencyWait(time.Duration(consistencyInterval)*time.Second, time.Duration(consistencyMaxWait)*time.Second, float64(consistencyBackoff)),
+		client.WithRedactPatterns(redactPatterns...),
+		client.WithTransportOptions(transportOptions),
+		client.WithTransport(transport),
+		client.WithMetrics(apiMetrics),
+		client.WithTracerProvider(tracerProvider),
+		client.WithHTTPCapture(httpCaptureFor(p.version)),
+	)
+	if err != nil {
+		addClientConfigDiagnostics(&resp.Diagnostics, err)
+		return
+	}
 
 	// Make the LWS client available during DataSource and Resource
 	// type Configure methods.
@@ -251,3 +444,55 @@ func New(version string) func() provider.Provider {
 		}
 	}
 }
+
+// configErrorHints completes client configuration errors with the provider
+// specific ways of setting the value.
+var configErrorHints = map[string]string{
+	"login": "Set the login value in the configuration or use the LWS_LOGIN environment variable. " +
+		"If either is already set, ensure the value is not empty.",
+	"api_key": "Set the api_key value in the configuration or use the LWS_API_KEY environment variable. " +
+		"If either is already set, ensure the value is not empty.",
+	"base_url":  "Set the base_url value in the configuration or use the LWS_BASE_URL environment variable.",
+	"base_urls": "Set the base_urls value in the configuration or use the LWS_BASE_URLS environment variable.",
+}
+
+// addClientConfigDiagnostics turns the error returned by client.New into
+// attribute diagnostics.
+func addClientConfigDiagnostics(diags *diag.Diagnostics, err error) {
+	var cfgErrs client.ConfigErrors
+	if !errors.As(err, &cfgErrs) {
+		diags.AddError("Unable to Create LWS API Client", err.Error())
+		return
+	}
+
+	for _, cfgErr := range cfgErrs {
+		detail := cfgErr.Detail
+		if hint, ok := configErrorHints[cfgErr.Field]; ok {
+			detail += " " + hint
+		}
+		diags.AddAttributeError(path.Root(cfgErr.Field), cfgErr.Summary, detail)
+	}
+}
+
+// splitList splits a comma separated environment variable, dropping empty
+// entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// userAgent identifies the provider and Terraform versions to the LWS API.
+func userAgent(providerVersion, terraformVersion string) string {
+	if providerVersion == "" {
+		providerVersion = "dev"
+	}
+	if terraformVersion == "" {
+		terraformVersion = "unknown"
+	}
+	return fmt.Sprintf("%s/%s terraform/%s", client.DefaultUserAgent, providerVersion, terraformVersion)
+}
@@ -2,9 +2,20 @@ package provider
 
 import (
 	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/M4XGO/terraform-provider-lws/internal/client"
+	"github.com/M4XGO/terraform-provider-lws/internal/lwsfake"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 )
 
 func TestLWSProvider(t *testing.T) {
@@ -34,3 +45,350 @@ func TestLWSProvider_Metadata(t *testing.T) {
 		t.Errorf("Expected Version to be '1.0.0', got %s", resp.Version)
 	}
 }
+
+func TestAddClientConfigDiagnostics(t *testing.T) {
+	t.Parallel()
+
+	_, err := client.New(client.WithCredentials("", "testkey"), client.WithZoneCacheTTL(-1))
+
+	var diags diag.Diagnostics
+	addClientConfigDiagnostics(&diags, err)
+
+	if diags.ErrorsCount() != 2 {
+		t.Fatalf("Expected 2 errors, got %d: %v", diags.ErrorsCount(), diags)
+	}
+
+	login, ok := diags[0].(diag.DiagnosticWithPath)
+	if !ok || !login.Path().Equal(path.Root("login")) {
+		t.Errorf("Expected the first error on login, got %v", diags[0])
+	}
+	if !strings.Contains(diags[0].Detail(), "LWS_LOGIN") {
+		t.Errorf("Expected the login error to mention LWS_LOGIN, got %q", diags[0].Detail())
+	}
+
+	ttl, ok := diags[1].(diag.DiagnosticWithPath)
+	if !ok || !ttl.Path().Equal(path.Root("zone_cache_ttl")) {
+		t.Errorf("Expected the second error on zone_cache_ttl, got %v", diags[1])
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	t.Parallel()
+
+	got := splitList(" https://api.lws.net/v1, ,https://relay.example.com/v1,")
+	want := []string{"https://api.lws.net/v1", "https://relay.example.com/v1"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got := splitList(""); got != nil {
+		t.Errorf("Expected no entries, got %v", got)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		providerVersion  string
+		terraformVersion string
+		expected         string
+	}{
+		{"1.2.0", "1.9.5", "terraform-provider-lws/1.2.0 terraform/1.9.5"},
+		{"", "", "terraform-provider-lws/dev terraform/unknown"},
+	}
+
+	for _, tt := range tests {
+		if got := userAgent(tt.providerVersion, tt.terraformVersion); got != tt.expected {
+			t.Errorf("Expected User-Agent %q, got %q", tt.expected, got)
+		}
+	}
+}
+
+func TestReportMetrics(t *testing.T) {
+	previous := apiMetrics
+	t.Cleanup(func() { apiMetrics = previous })
+	apiMetrics = client.NewMetrics()
+
+	file := filepath.Join(t.TempDir(), "metrics.json")
+	t.Setenv(MetricsFileEnv, file)
+
+	// A process that made no API call writes nothing
+	if err := ReportMetrics(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatalf("Expected no metrics file without API calls, got %v", err)
+	}
+
+	server := httptest.NewServer(lwsfake.New(lwsfake.WithZone("example.com")))
+	defer server.Close()
+	lwsClient, err := client.New(
+		client.WithCredentials("testlogin", "testkey"),
+		client.WithBaseURL(server.URL),
+		client.WithMetrics(apiMetrics),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := lwsClient.GetDNSZone(context.Background(), "example.com"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Each provider process of a run adds its calls to the file
+	for range 2 {
+		if err := ReportMetrics(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Expected the metrics file to be written: %v", err)
+	}
+	var decoded struct {
+		Totals client.EndpointMetrics `json:"totals"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unable to decode %s: %v", data, err)
+	}
+	if decoded.Totals.Requests != 2 {
+		t.Errorf("Expected the requests of both reports to add up, got %s", data)
+	}
+
+	// The next run replaces the metrics of the previous one
+	t.Setenv(RunIDEnv, "next-run")
+	if err := ReportMetrics(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err = os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var next struct {
+		Run    string                 `json:"run"`
+		Totals client.EndpointMetrics `json:"totals"`
+	}
+	if err := json.Unmarshal(data, &next); err != nil {
+		t.Fatalf("Unable to decode %s: %v", data, err)
+	}
+	if next.Run != "next-run" || next.Totals.Requests != 1 {
+		t.Errorf("Expected only the requests of the next run, got %s", data)
+	}
+
+	t.Setenv(MetricsFileEnv, filepath.Join(t.TempDir(), "missing", "metrics.json"))
+	if err := ReportMetrics(context.Background()); err == nil {
+		t.Errorf("Expected an error when the file can't be written")
+	}
+}
+
+func TestSetupTracing(t *testing.T) {
+	t.Cleanup(func() { tracerProvider, tracer = nil, nil })
+
+	file := filepath.Join(t.TempDir(), "traces.json")
+	t.Setenv(TracesFileEnv, file)
+	t.Setenv("OTEL_TRACES_EXPORTER", "none")
+
+	shutdown, err := SetupTracing(context.Background(), "1.0.0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tracerProvider == nil || tracer == nil {
+		t.Fatalf("Expected tracing to be configured")
+	}
+
+	_, span := startOperation(context.Background(), "lws_dns_record.Create")
+	setRecordAttributes(span, "example.com", "www", "A")
+	var diags diag.Diagnostics
+	diags.AddError("Client Error", "Unable to create DNS record")
+	endOperation(span, diags)
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Expected the traces file to be written: %v", err)
+	}
+	for _, want := range []string{`"Name":"lws_dns_record.Create"`, `"Value":"example.com"`, `"Description":"Client Error"`, `"Value":"terraform-provider-lws"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected %s in the traces file, got %s", want, data)
+		}
+	}
+}
+
+func TestSetupTracing_Disabled(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		err  string
+	}{
+		{name: "not_configured"},
+		{name: "none", env: map[string]string{"OTEL_TRACES_EXPORTER": "none"}},
+		{name: "sdk_disabled", env: map[string]string{"OTEL_SDK_DISABLED": "true", "OTEL_TRACES_EXPORTER": "console"}},
+		{name: "unknown_exporter", env: map[string]string{"OTEL_TRACES_EXPORTER": "jaeger"}, err: "unsupported trace exporter"},
+		{name: "grpc", env: map[string]string{"OTEL_TRACES_EXPORTER": "otlp", "OTEL_EXPORTER_OTLP_PROTOCOL": "grpc"}, err: "not supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"OTEL_SDK_DISABLED", "OTEL_TRACES_EXPORTER", "OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "OTEL_EXPORTER_OTLP_PROTOCOL", TracesFileEnv} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			shutdown, err := SetupTracing(context.Background(), "1.0.0")
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("Expected an error containing %q, got %v", tt.err, err)
+				}
+			} else if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tracerProvider != nil || tracer != nil {
+				t.Errorf("Expected tracing to stay off")
+			}
+			if err := shutdown(context.Background()); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+
+			ctx, span := startOperation(context.Background(), "lws_dns_record.Read")
+			if span.IsRecording() || client.RequestIDFromContext(ctx) == "" {
+				t.Errorf("Expected a request ID and no span")
+			}
+		})
+	}
+}
+
+func TestWriteHTTPCapture(t *testing.T) {
+	t.Cleanup(func() { httpCapture = nil })
+
+	t.Setenv(HTTPCaptureEnv, "")
+	if capture := httpCaptureFor("1.0.0"); capture != nil {
+		t.Fatalf("Expected no capture without %s", HTTPCaptureEnv)
+	}
+	if err := WriteHTTPCapture(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "run.har")
+	t.Setenv(HTTPCaptureEnv, file)
+	capture := httpCaptureFor("1.0.0")
+	if capture == nil || httpCaptureFor("1.0.0") != capture {
+		t.Fatalf("Expected the clients to share a capture")
+	}
+
+	// A process that made no API call writes nothing
+	if err := WriteHTTPCapture(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatalf("Expected no capture without exchanges, got %v", err)
+	}
+
+	server := httptest.NewServer(lwsfake.New(lwsfake.WithZone("example.com")))
+	defer server.Close()
+	lwsClient, err := client.New(
+		client.WithCredentials("testlogin", "testkey"),
+		client.WithBaseURL(server.URL),
+		client.WithHTTPCapture(capture),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := lwsClient.GetDNSZone(context.Background(), "example.com"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Each provider process of a run adds its exchanges to the archive
+	for range 2 {
+		if err := WriteHTTPCapture(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	har, err := client.LoadHAR(file)
+	if err != nil {
+		t.Fatalf("Expected the capture to be written: %v", err)
+	}
+	if har.Log.Version != "1.2" || har.Log.Creator.Version != "1.0.0" {
+		t.Errorf("Unexpected archive header %+v", har.Log)
+	}
+	if len(har.Log.Entries) != 2 {
+		t.Errorf("Expected the exchanges of both writes, got %d entries", len(har.Log.Entries))
+	}
+
+	// The next run replaces the exchanges of the previous one
+	t.Setenv(RunIDEnv, "next-run")
+	if err := WriteHTTPCapture(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	har, err = client.LoadHAR(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if har.Log.RunID != "next-run" || len(har.Log.Entries) != 1 {
+		t.Errorf("Expected only the exchanges of the next run, got %d entries of %q", len(har.Log.Entries), har.Log.RunID)
+	}
+}
+
+func TestReplayTransport(t *testing.T) {
+	t.Cleanup(func() { replayer = nil })
+
+	t.Setenv(ReplayEnv, "")
+	if transport, err := replayTransport(); transport != nil || err != nil {
+		t.Fatalf("Expected no transport without %s, got %v, %v", ReplayEnv, transport, err)
+	}
+
+	t.Setenv(ReplayEnv, filepath.Join(t.TempDir(), "missing.yaml"))
+	if _, err := replayTransport(); err == nil {
+		t.Fatalf("Expected an error for a missing cassette")
+	}
+
+	t.Setenv(ReplayEnv, filepath.Join("..", "client", "cassette", "testdata", "create_record.yaml"))
+	transport, err := replayTransport()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	c, err := client.New(client.WithCredentials("replay", "replay"), client.WithTransport(transport), client.WithRetryPolicy(client.NewDefaultRetryPolicy(0, 0, 1)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetDNSZone(context.Background(), "example.com"); err != nil {
+			t.Fatalf("Expected zone reads to be replayed again, got %v", err)
+		}
+	}
+
+	if _, err := c.GetDNSZone(context.Background(), "example.org"); !client.IsNotRecorded(err) {
+		t.Fatalf("Expected an error for a request missing from the cassette, got %v", err)
+	}
+}
+
+func TestReplayTransport_UnmatchedFailsOperation(t *testing.T) {
+	t.Cleanup(func() { replayer = nil })
+	t.Setenv(ReplayEnv, filepath.Join("..", "client", "cassette", "testdata", "create_record.yaml"))
+
+	transport, err := replayTransport()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	c, err := client.New(
+		client.WithCredentials("replay", "replay"),
+		client.WithTransport(transport),
+		client.WithRetryPolicy(client.NewDefaultRetryPolicy(2, 0, 1)),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	r := &DNSRecordResource{client: c}
+
+	// The zone listing is recorded, a create of another record is not
+	plan := recordModel("", "mail", "A", "192.0.2.20")
+	resp := &resource.CreateResponse{State: tfsdk.State{Schema: recordSchema(t)}}
+	r.Create(context.Background(), resource.CreateRequest{Plan: recordPlan(t, plan)}, resp)
+
+	if errs := resp.Diagnostics.Errors(); len(errs) != 1 || !strings.Contains(errs[0].Detail(), "no recorded interaction matches POST /v1/domain/example.com/zdns") {
+		t.Fatalf("Expected the unmatched request as an error diagnostic, got %v", resp.Diagnostics)
+	}
+	if unmatched := replayer.Unmatched(); len(unmatched) != 1 {
+		t.Errorf("Expected the unmatched request to be sent once, got %v", unmatched)
+	}
+}
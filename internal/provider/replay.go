@@ -0,0 +1,42 @@
+package provider
+
+import (
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/M4XGO/terraform-provider-lws/internal/client/cassette"
+)
+
+// ReplayEnv names a cassette, or a HAR file written with LWS_HTTP_CAPTURE,
+// the provider answers its API requests from instead of calling LWS. Plans
+// then run offline and reproducibly. A request missing from the cassette
+// fails the operation that sent it, without retries.
+const ReplayEnv = "LWS_REPLAY"
+
+var (
+	replayMu sync.Mutex
+	replayer *cassette.Replayer
+)
+
+// replayTransport returns the transport replaying the LWS_REPLAY cassette,
+// shared by the clients of the process, nil when LWS_REPLAY is not set.
+func replayTransport() (http.RoundTripper, error) {
+	path := os.Getenv(ReplayEnv)
+	if path == "" {
+		return nil, nil
+	}
+
+	replayMu.Lock()
+	defer replayMu.Unlock()
+	if replayer == nil {
+		c, err := cassette.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		// Terraform refreshes zones a varying number of times, only the
+		// mutations have to be in the cassette once each
+		replayer = cassette.NewReplayer(c, cassette.ReplayOptions{RepeatReads: true})
+	}
+	return replayer, nil
+}
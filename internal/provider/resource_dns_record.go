@@ -2,11 +2,14 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 
 	"github.com/M4XGO/terraform-provider-lws/internal/client"
+	"github.com/M4XGO/terraform-provider-lws/internal/client/rdata"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
@@ -27,7 +30,7 @@ func NewDNSRecordResource() resource.Resource {
 
 // DNSRecordResource defines the resource implementation.
 type DNSRecordResource struct {
-	client *client.LWSClient
+	client client.DNSAPI
 }
 
 // DNSRecordResourceModel describes the resource data model.
@@ -52,9 +55,9 @@ func (r *DNSRecordResource) Schema(ctx context.Context, req resource.SchemaReque
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
 				Computed:            true,
-				MarkdownDescription: "DNS record identifier",
+				MarkdownDescription: "DNS record identifier, of the form `zone/name/type/value_hash`. It does not change when LWS renumbers the record. A record whose value is changed outside Terraform is still found for the types holding one record per name (`CNAME`, `SOA`). For the other types it is no longer found, so Terraform creates the configured value again and the changed record stays in the zone: restore its value, or delete or import it, before applying.",
 				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.UseStateForUnknown(),
+					recordIDPlanModifier{},
 				},
 			},
 			"name": schema.StringAttribute{
@@ -94,21 +97,24 @@ func (r *DNSRecordResource) Configure(ctx context.Context, req resource.Configur
 		return
 	}
 
-	lwsClient, ok := req.ProviderData.(*client.LWSClient)
+	dnsAPI, ok := req.ProviderData.(client.DNSAPI)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Resource Configure Type",
-			fmt.Sprintf("Expected *client.LWSClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected client.DNSAPI, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 
 		return
 	}
 
-	r.client = lwsClient
+	r.client = dnsAPI
 }
 
 func (r *DNSRecordResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	ctx, span := startOperation(ctx, "lws_dns_record.Create")
+	defer func() { endOperation(span, resp.Diagnostics) }()
+
 	var data DNSRecordResourceModel
 
 	// Read Terraform plan data into the model
@@ -123,6 +129,7 @@ func (r *DNSRecordResource) Create(ctx context.Context, req resource.CreateReque
 	recordType := strings.TrimSpace(data.Type.ValueString())
 	recordValue := strings.TrimSpace(data.Value.ValueString())
 	zoneName := strings.TrimSpace(data.Zone.ValueString())
+	setRecordAttributes(span, zoneName, recordName, recordType)
 
 	if recordName == "" {
 		resp.Diagnostics.AddError("Validation Error", "DNS record name cannot be empty")
@@ -159,14 +166,24 @@ func (r *DNSRecordResource) Create(ctx context.Context, req resource.CreateReque
 	tflog.Info(ctx, "Processing DNS record request", map[string]interface{}{
 		"name":     record.Name,
 		"type":     record.Type,
-		"value":    record.Value,
+		"value":    client.RedactValue(record.Value),
 		"zone":     record.Zone,
 		"ttl":      record.TTL,
-		"base_url": r.client.BaseURL,
-		"login":    r.client.Login,
+		"base_url": r.client.Describe().BaseURL,
+		"login":    r.client.Describe().Login,
 	})
 
-	// First, check if a record with the same name and type already exists
+	// Hold the zone lock from the check below to the create, so that a
+	// parallel create of the same record waits and then finds this one
+	ctx, unlock, ok := r.lockZone(ctx, record.Zone, &resp.Diagnostics)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	// First, check if the zone already holds the record. Records of the same
+	// name and type with other values are left alone, unless the type allows
+	// a single record per name.
 	tflog.Debug(ctx, "Checking if DNS record already exists", map[string]interface{}{
 		"name": record.Name,
 		"type": record.Type,
@@ -174,127 +191,21 @@ func (r *DNSRecordResource) Create(ctx context.Context, req resource.CreateReque
 	})
 
 	zone, err := r.client.GetDNSZone(ctx, record.Zone)
+	if client.IsNotRecorded(err) {
+		// Replaying LWS_REPLAY: the run diverged from the recording, a
+		// create sent anyway would hide it
+		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to check whether DNS record '%s' exists in zone '%s', got error: %s", record.Name, record.Zone, err))
+		return
+	}
 	if err != nil {
 		tflog.Error(ctx, "Failed to get DNS zone for conflict check", map[string]interface{}{
 			"zone":  record.Zone,
 			"error": err.Error(),
 		})
 		// If we can't get the zone, continue with create attempt
-	} else {
-		// Look for existing record with same name and type
-		// Use case-insensitive comparison and trim whitespace for better matching
-		targetName := strings.ToLower(strings.TrimSpace(record.Name))
-		targetType := strings.ToUpper(strings.TrimSpace(record.Type))
-
-		tflog.Debug(ctx, "Searching for existing records", map[string]interface{}{
-			"target_name":   targetName,
-			"target_type":   targetType,
-			"total_records": len(zone.Records),
-		})
-
-		for _, existingRecord := range zone.Records {
-			existingName := strings.ToLower(strings.TrimSpace(existingRecord.Name))
-			existingType := strings.ToUpper(strings.TrimSpace(existingRecord.Type))
-
-			tflog.Debug(ctx, "Comparing with existing record", map[string]interface{}{
-				"existing_name": existingName,
-				"existing_type": existingType,
-				"existing_id":   existingRecord.ID,
-				"matches_name":  existingName == targetName,
-				"matches_type":  existingType == targetType,
-			})
-
-			if existingName == targetName && existingType == targetType {
-				tflog.Info(ctx, "Found existing DNS record, will update instead of create", map[string]interface{}{
-					"existing_id":    existingRecord.ID,
-					"existing_value": existingRecord.Value,
-					"new_value":      record.Value,
-					"name":           record.Name,
-					"type":           record.Type,
-					"zone":           record.Zone,
-				})
-
-				// Validate that the existing record has a valid ID
-				if existingRecord.ID <= 0 {
-					tflog.Error(ctx, "Found existing record but ID is invalid", map[string]interface{}{
-						"existing_id": existingRecord.ID,
-						"name":        record.Name,
-						"type":        record.Type,
-						"zone":        record.Zone,
-					})
-
-					errorMsg := fmt.Sprintf("Found existing DNS record '%s' of type '%s' but it has invalid ID: %d. Cannot update record with invalid ID.",
-						record.Name, record.Type, existingRecord.ID)
-					resp.Diagnostics.AddError("Invalid Record ID", errorMsg)
-					return
-				}
-
-				// Update existing record instead of creating
-				record.ID = existingRecord.ID
-				updatedRecord, err := r.client.UpdateDNSRecord(ctx, record)
-				if err != nil {
-					errorMsg := fmt.Sprintf("Unable to update existing DNS record '%s' (ID: %d) in zone '%s', got error: %s",
-						record.Name, existingRecord.ID, record.Zone, err)
-					if r.client.TestMode {
-						errorMsg += "\n\nNote: You're in test mode. Make sure your test server is configured correctly."
-					} else {
-						errorMsg += fmt.Sprintf("\n\nAPI Details:\n- Base URL: %s\n- Login: %s\n- Expected endpoint: %s/domain/%s/zdns",
-							r.client.BaseURL, r.client.Login, r.client.BaseURL, record.Zone)
-					}
-
-					tflog.Error(ctx, "Failed to update existing DNS record", map[string]interface{}{
-						"name":        record.Name,
-						"zone":        record.Zone,
-						"type":        record.Type,
-						"value":       record.Value,
-						"existing_id": existingRecord.ID,
-						"error":       err.Error(),
-					})
-
-					resp.Diagnostics.AddError("Client Error", errorMsg)
-					return
-				}
-
-				// Validate the updated record ID
-				if updatedRecord.ID <= 0 {
-					tflog.Error(ctx, "Updated record returned invalid ID", map[string]interface{}{
-						"returned_id": updatedRecord.ID,
-						"name":        record.Name,
-						"type":        record.Type,
-						"zone":        record.Zone,
-					})
-
-					errorMsg := fmt.Sprintf("Update operation returned invalid ID: %d for record '%s'. This indicates an API problem.",
-						updatedRecord.ID, record.Name)
-					resp.Diagnostics.AddError("Invalid Updated Record ID", errorMsg)
-					return
-				}
-
-				tflog.Info(ctx, "Successfully updated existing DNS record", map[string]interface{}{
-					"id":        updatedRecord.ID,
-					"name":      updatedRecord.Name,
-					"type":      updatedRecord.Type,
-					"zone":      updatedRecord.Zone,
-					"new_value": updatedRecord.Value,
-					"action":    "updated_existing",
-				})
-
-				// Inform user that we updated an existing record instead of creating
-				resp.Diagnostics.AddWarning(
-					"Updated Existing DNS Record",
-					fmt.Sprintf("Found existing DNS record '%s' of type '%s' in zone '%s' (ID: %d). Updated its value from '%s' to '%s' instead of creating a duplicate.",
-						record.Name, record.Type, record.Zone, existingRecord.ID, existingRecord.Value, record.Value),
-				)
-
-				// Save updated record data into Terraform state
-				data.ID = types.StringValue(fmt.Sprintf("%d", updatedRecord.ID))
-				data.TTL = types.Int64Value(int64(updatedRecord.TTL))
-
-				// Save data into Terraform state
-				resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
-				return
-			}
-		}
+	} else if existing := existingRecord(zone.Records, *record); existing != nil {
+		r.adoptRecord(ctx, existing, record, &data, resp)
+		return
 	}
 
 	// No existing record found, proceed with creation
@@ -306,106 +217,39 @@ func (r *DNSRecordResource) Create(ctx context.Context, req resource.CreateReque
 
 	createdRecord, err := r.client.CreateDNSRecord(ctx, record)
 	if err != nil {
-		// Check if the error indicates the record already exists
-		errorMsg := strings.ToLower(err.Error())
-		if strings.Contains(errorMsg, "cannot add record") ||
-			strings.Contains(errorMsg, "record invalid") ||
-			strings.Contains(errorMsg, "already exists") ||
-			strings.Contains(errorMsg, "duplicate") {
-
-			tflog.Warn(ctx, "Create failed, likely due to existing record, attempting to find and adopt it", map[string]interface{}{
+		// Check if the error indicates the record already exists. LWS
+		// rejects duplicates as it does invalid records, so a rejected
+		// create is only a conflict when the zone holds a matching record.
+		if client.IsConflict(err) || client.IsRejected(err) {
+
+			tflog.Warn(ctx, "Create failed, possibly due to an existing record, attempting to find and adopt it", map[string]interface{}{
 				"name":  record.Name,
 				"type":  record.Type,
 				"zone":  record.Zone,
 				"error": err.Error(),
 			})
 
-			// Try to fetch the zone again and look more thoroughly for the existing record
+			// Fetch the zone again, the record may have been added since the first check
 			zone, zoneErr := r.client.GetDNSZone(ctx, record.Zone)
 			if zoneErr != nil {
 				tflog.Error(ctx, "Failed to get DNS zone for fallback search", map[string]interface{}{
 					"zone":  record.Zone,
 					"error": zoneErr.Error(),
 				})
-			} else {
-				// More thorough search - also check with exact string matching
-				for _, existingRecord := range zone.Records {
-					// Try both normalized and exact matching
-					if (strings.ToLower(strings.TrimSpace(existingRecord.Name)) == strings.ToLower(strings.TrimSpace(record.Name)) &&
-						strings.ToUpper(strings.TrimSpace(existingRecord.Type)) == strings.ToUpper(strings.TrimSpace(record.Type))) ||
-						(existingRecord.Name == record.Name && existingRecord.Type == record.Type) {
-
-						tflog.Info(ctx, "Found existing record during fallback search, adopting it", map[string]interface{}{
-							"existing_id":    existingRecord.ID,
-							"existing_name":  existingRecord.Name,
-							"existing_type":  existingRecord.Type,
-							"existing_value": existingRecord.Value,
-							"target_name":    record.Name,
-							"target_type":    record.Type,
-							"target_value":   record.Value,
-						})
-
-						// If values are different, update the record
-						if existingRecord.Value != record.Value {
-							record.ID = existingRecord.ID
-							updatedRecord, updateErr := r.client.UpdateDNSRecord(ctx, record)
-							if updateErr != nil {
-								tflog.Error(ctx, "Failed to update adopted record", map[string]interface{}{
-									"error": updateErr.Error(),
-								})
-								// Continue with adoption even if update fails
-								updatedRecord = &existingRecord
-							} else {
-								tflog.Info(ctx, "Successfully updated adopted record", map[string]interface{}{
-									"id":        updatedRecord.ID,
-									"old_value": existingRecord.Value,
-									"new_value": updatedRecord.Value,
-								})
-							}
-							createdRecord = updatedRecord
-						} else {
-							// Values are the same, just adopt the existing record
-							createdRecord = &existingRecord
-						}
-
-						// Save adopted record data into Terraform state
-						data.ID = types.StringValue(fmt.Sprintf("%d", createdRecord.ID))
-						data.Name = types.StringValue(createdRecord.Name)
-						data.Type = types.StringValue(createdRecord.Type)
-						data.Value = types.StringValue(createdRecord.Value)
-						data.TTL = types.Int64Value(int64(createdRecord.TTL))
-						// Keep the original zone from configuration, not from API response
-						data.Zone = types.StringValue(zoneName)
-
-						// Add informational warning
-						resp.Diagnostics.AddWarning(
-							"Adopted Existing DNS Record",
-							fmt.Sprintf("Found existing DNS record '%s' of type '%s' in zone '%s' (ID: %d) that matches the desired configuration. Adopted this record instead of creating a duplicate.",
-								record.Name, record.Type, record.Zone, createdRecord.ID),
-						)
-
-						// Save data into Terraform state
-						resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
-						return
-					}
-				}
+			} else if existing := existingRecord(zone.Records, *record); existing != nil {
+				r.adoptRecord(ctx, existing, record, &data, resp)
+				return
 			}
 		}
-
 		// Original error handling if we couldn't find/adopt an existing record
 		fullErrorMsg := fmt.Sprintf("Unable to create DNS record '%s' in zone '%s', got error: %s", record.Name, record.Zone, err)
-		if r.client.TestMode {
-			fullErrorMsg += "\n\nNote: You're in test mode. Make sure your test server is configured correctly."
-		} else {
-			fullErrorMsg += fmt.Sprintf("\n\nAPI Details:\n- Base URL: %s\n- Login: %s\n- Expected endpoint: %s/domain/%s/zdns",
-				r.client.BaseURL, r.client.Login, r.client.BaseURL, record.Zone)
-		}
+		fullErrorMsg += clientErrorDetails(r.client.Describe(), record.Zone)
 
 		tflog.Error(ctx, "Failed to create DNS record", map[string]interface{}{
 			"name":  record.Name,
 			"zone":  record.Zone,
 			"type":  record.Type,
-			"value": record.Value,
+			"value": client.RedactValue(record.Value),
 			"error": err.Error(),
 		})
 
@@ -413,8 +257,18 @@ func (r *DNSRecordResource) Create(ctx context.Context, req resource.CreateReque
 		return
 	}
 
-	// Validate the created record ID
-	if createdRecord.ID <= 0 {
+	// Validate the created record ID. In test mode LWS validates the record
+	// without adding it, so it has none and the next refresh won't find it
+	if createdRecord.ID <= 0 && r.client.Describe().TestMode {
+		tflog.Warn(ctx, "DNS record validated in test mode, not created", map[string]interface{}{
+			"name": record.Name,
+			"type": record.Type,
+			"zone": record.Zone,
+		})
+		resp.Diagnostics.AddWarning("DNS Record Validated in Test Mode",
+			fmt.Sprintf("LWS validated DNS record '%s' of type %s in zone '%s' without creating it, since test_mode is enabled. "+
+				"The next refresh will not find it and Terraform will plan to create it again.", record.Name, record.Type, record.Zone))
+	} else if createdRecord.ID <= 0 {
 		tflog.Error(ctx, "Created record returned invalid ID", map[string]interface{}{
 			"returned_id": createdRecord.ID,
 			"name":        record.Name,
@@ -439,7 +293,7 @@ func (r *DNSRecordResource) Create(ctx context.Context, req resource.CreateReque
 	// Log successful creation (no warning needed for normal operation)
 
 	// Save created record data into Terraform state
-	data.ID = types.StringValue(fmt.Sprintf("%d", createdRecord.ID))
+	data.ID = types.StringValue(recordStateID(zoneName, *createdRecord))
 	data.Name = types.StringValue(createdRecord.Name)
 	data.Type = types.StringValue(createdRecord.Type)
 	data.Value = types.StringValue(createdRecord.Value)
@@ -456,6 +310,9 @@ func (r *DNSRecordResource) Create(ctx context.Context, req resource.CreateReque
 }
 
 func (r *DNSRecordResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	ctx, span := startOperation(ctx, "lws_dns_record.Read")
+	defer func() { endOperation(span, resp.Diagnostics) }()
+
 	var data DNSRecordResourceModel
 
 	// Read Terraform prior state data into the model
@@ -469,6 +326,7 @@ func (r *DNSRecordResource) Read(ctx context.Context, req resource.ReadRequest,
 	zoneName := data.Zone.ValueString()
 	recordName := data.Name.ValueString()
 	recordType := data.Type.ValueString()
+	setRecordAttributes(span, zoneName, recordName, recordType)
 
 	// DEBUG: Log the current state being read
 	tflog.Debug(ctx, "🔍 READ: Starting read operation", map[string]interface{}{
@@ -476,7 +334,7 @@ func (r *DNSRecordResource) Read(ctx context.Context, req resource.ReadRequest,
 		"state_zone":      zoneName,
 		"state_name":      recordName,
 		"state_type":      recordType,
-		"state_value":     data.Value.ValueString(),
+		"state_value":     client.RedactValue(data.Value.ValueString()),
 		"state_ttl":       data.TTL.ValueInt64(),
 		"id_is_null":      data.ID.IsNull(),
 		"id_is_unknown":   data.ID.IsUnknown(),
@@ -510,175 +368,41 @@ func (r *DNSRecordResource) Read(ctx context.Context, req resource.ReadRequest,
 		"zone":      zoneName,
 		"name":      recordName,
 		"type":      recordType,
-		"base_url":  r.client.BaseURL,
-		"login":     r.client.Login,
+		"base_url":  r.client.Describe().BaseURL,
+		"login":     r.client.Describe().Login,
 	})
 
-	// Check if ID is invalid (0 or empty)
-	recordIDInt, err := strconv.Atoi(recordID)
-	if err != nil || recordIDInt <= 0 {
-		tflog.Warn(ctx, "🟡 READ: Invalid record ID in state, attempting to find record by name/type", map[string]interface{}{
-			"invalid_id":       recordID,
-			"conversion_error": err,
-			"zone":             zoneName,
-			"name":             recordName,
-			"type":             recordType,
-		})
-
-		// Try to find the record by name and type in the zone
-		zone, err := r.client.GetDNSZone(ctx, zoneName)
-		if err != nil {
-			tflog.Error(ctx, "🚨 READ: Failed to get DNS zone to find record by name/type", map[string]interface{}{
-				"zone":  zoneName,
-				"error": err.Error(),
-			})
-
-			// If we can't get the zone, assume the record is deleted
-			tflog.Info(ctx, "🗑️ READ: Removing resource from state due to zone fetch error", map[string]interface{}{
-				"zone":   zoneName,
-				"reason": "zone_fetch_failed",
-			})
-			resp.State.RemoveResource(ctx)
-			return
-		}
-
-		// Look for the record by name and type
-		var foundRecord *client.DNSRecord
-		for _, record := range zone.Records {
-			if record.Name == recordName && record.Type == recordType {
-				foundRecord = &record
-				break
-			}
-		}
-
-		if foundRecord == nil {
-			tflog.Info(ctx, "🗑️ READ: DNS record not found in zone, marking as deleted", map[string]interface{}{
-				"zone": zoneName,
-				"name": recordName,
-				"type": recordType,
-			})
-
-			// Record doesn't exist, remove from state
-			resp.State.RemoveResource(ctx)
-			return
-		}
-
-		tflog.Info(ctx, "✅ READ: Found DNS record by name/type, updating ID in state", map[string]interface{}{
-			"zone":     zoneName,
-			"name":     recordName,
-			"type":     recordType,
-			"found_id": foundRecord.ID,
-			"old_id":   recordID,
+	// Look the record up by identity, so that LWS renumbering it or a record
+	// of the same name and type being added does not change which record the
+	// resource tracks
+	record, err := r.resolveRecord(ctx, data)
+	if client.IsNotFound(err) {
+		tflog.Info(ctx, "🗑️ READ: DNS record not found in zone, removing from state", map[string]interface{}{
+			"record_id": recordID,
+			"zone":      zoneName,
+			"name":      recordName,
+			"type":      recordType,
+			"reason":    "identity_not_found",
 		})
 
-		// Update the model with found record data
-		data.ID = types.StringValue(fmt.Sprintf("%d", foundRecord.ID))
-		data.Name = types.StringValue(foundRecord.Name)
-		data.Type = types.StringValue(foundRecord.Type)
-		data.Value = types.StringValue(foundRecord.Value)
-		data.TTL = types.Int64Value(int64(foundRecord.TTL))
-		// Keep the original zone name from state
-		data.Zone = types.StringValue(zoneName)
-
-		// Save corrected data into Terraform state
-		tflog.Debug(ctx, "💾 READ: Saving corrected state after finding by name", map[string]interface{}{
-			"corrected_id":    foundRecord.ID,
-			"corrected_name":  foundRecord.Name,
-			"corrected_type":  foundRecord.Type,
-			"corrected_value": foundRecord.Value,
-			"corrected_ttl":   foundRecord.TTL,
-		})
-		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		resp.State.RemoveResource(ctx)
 		return
 	}
-
-	// Normal flow: get record by ID
-	tflog.Debug(ctx, "🔍 READ: Normal flow - fetching record by ID", map[string]interface{}{
-		"record_id_int": recordIDInt,
-		"zone":          zoneName,
-	})
-
-	record, err := r.client.GetDNSRecord(ctx, zoneName, recordID)
 	if err != nil {
-		tflog.Error(ctx, "🚨 READ: Failed to read DNS record by ID, trying fallback search", map[string]interface{}{
+		// Only a record known to be gone leaves the state: an open breaker, a
+		// challenge or a transport error says nothing about the record
+		errorMsg := fmt.Sprintf("Unable to read DNS record %s in zone '%s', got error: %s",
+			recordID, zoneName, err)
+		errorMsg += clientErrorDetails(r.client.Describe(), zoneName)
+
+		tflog.Error(ctx, "Failed to read DNS record", map[string]interface{}{
 			"record_id": recordID,
 			"zone":      zoneName,
 			"error":     err.Error(),
-			"base_url":  r.client.BaseURL,
 		})
 
-		// Check if it's a "not found" error - try fallback search by name/type
-		errorMsg := strings.ToLower(err.Error())
-		if strings.Contains(errorMsg, "not found") || strings.Contains(errorMsg, "record with id") {
-			tflog.Warn(ctx, "🔄 READ: Record ID not found, attempting fallback search by name/type", map[string]interface{}{
-				"old_record_id": recordID,
-				"zone":          zoneName,
-				"name":          recordName,
-				"type":          recordType,
-				"reason":        "id_changed_or_invalid",
-			})
-
-			// Try to find the record by name and type in the zone
-			zone, err := r.client.GetDNSZone(ctx, zoneName)
-			if err != nil {
-				tflog.Error(ctx, "🚨 READ: Failed to get DNS zone for fallback search", map[string]interface{}{
-					"zone":  zoneName,
-					"error": err.Error(),
-				})
-
-				// If we can't get the zone, assume the record is deleted
-				tflog.Info(ctx, "🗑️ READ: Removing resource from state due to zone fetch error", map[string]interface{}{
-					"zone":   zoneName,
-					"reason": "fallback_zone_fetch_failed",
-				})
-				resp.State.RemoveResource(ctx)
-				return
-			}
-
-			// Look for the record by name and type
-			var foundRecord *client.DNSRecord
-			for _, rec := range zone.Records {
-				if rec.Name == recordName && rec.Type == recordType {
-					foundRecord = &rec
-					break
-				}
-			}
-
-			if foundRecord == nil {
-				tflog.Info(ctx, "🗑️ READ: DNS record not found by name/type either, removing from state", map[string]interface{}{
-					"zone": zoneName,
-					"name": recordName,
-					"type": recordType,
-				})
-
-				// Record doesn't exist, remove from state
-				resp.State.RemoveResource(ctx)
-				return
-			}
-
-			tflog.Info(ctx, "✅ READ: Found DNS record by name/type, updating ID in state", map[string]interface{}{
-				"zone":   zoneName,
-				"name":   recordName,
-				"type":   recordType,
-				"old_id": recordID,
-				"new_id": foundRecord.ID,
-				"reason": "id_drift_detected",
-			})
-
-			// Use the found record
-			record = foundRecord
-		} else {
-			// For other errors, still try to remove from state but log it as a warning
-			tflog.Warn(ctx, "🟡 READ: Unable to read DNS record, assuming deleted and removing from state", map[string]interface{}{
-				"record_id": recordID,
-				"zone":      zoneName,
-				"error":     err.Error(),
-				"reason":    "api_error_assuming_deleted",
-			})
-
-			resp.State.RemoveResource(ctx)
-			return
-		}
+		resp.Diagnostics.AddError("Client Error", errorMsg)
+		return
 	}
 
 	tflog.Debug(ctx, "✅ READ: Successfully read DNS record from API", map[string]interface{}{
@@ -686,13 +410,23 @@ func (r *DNSRecordResource) Read(ctx context.Context, req resource.ReadRequest,
 		"api_id":    record.ID,
 		"api_name":  record.Name,
 		"api_type":  record.Type,
-		"api_value": record.Value,
+		"api_value": client.RedactValue(record.Value),
 		"api_zone":  record.Zone,
 		"api_ttl":   record.TTL,
 	})
 
+	stateID := recordStateID(zoneName, *record)
+	if stateID != recordID {
+		tflog.Info(ctx, "✅ READ: Updating record ID in state", map[string]interface{}{
+			"old_id": recordID,
+			"new_id": stateID,
+			"lws_id": record.ID,
+			"reason": "legacy_or_imported_id",
+		})
+	}
+
 	// Update the model with refreshed data
-	data.ID = types.StringValue(fmt.Sprintf("%d", record.ID))
+	data.ID = types.StringValue(stateID)
 	data.Name = types.StringValue(record.Name)
 	data.Type = types.StringValue(record.Type)
 	data.Value = types.StringValue(record.Value)
@@ -702,10 +436,10 @@ func (r *DNSRecordResource) Read(ctx context.Context, req resource.ReadRequest,
 
 	// DEBUG: Log what we're saving to state
 	tflog.Debug(ctx, "💾 READ: Saving updated state", map[string]interface{}{
-		"final_id":    fmt.Sprintf("%d", record.ID),
+		"final_id":    stateID,
 		"final_name":  record.Name,
 		"final_type":  record.Type,
-		"final_value": record.Value,
+		"final_value": client.RedactValue(record.Value),
 		"final_ttl":   record.TTL,
 		"final_zone":  zoneName,
 	})
@@ -726,27 +460,26 @@ func (r *DNSRecordResource) Read(ctx context.Context, req resource.ReadRequest,
 }
 
 func (r *DNSRecordResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-	var data DNSRecordResourceModel
+	ctx, span := startOperation(ctx, "lws_dns_record.Update")
+	defer func() { endOperation(span, resp.Diagnostics) }()
 
-	// Read Terraform plan data into the model
+	var data, state DNSRecordResourceModel
+
+	// Read Terraform plan and prior state data into the models
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
 
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	recordID := strings.TrimSpace(data.ID.ValueString())
 	recordName := strings.TrimSpace(data.Name.ValueString())
 	recordType := strings.TrimSpace(data.Type.ValueString())
 	recordValue := strings.TrimSpace(data.Value.ValueString())
 	zoneName := strings.TrimSpace(data.Zone.ValueString())
+	setRecordAttributes(span, zoneName, recordName, recordType)
 
 	// Manual validation for required fields
-	if recordID == "" {
-		resp.Diagnostics.AddError("Validation Error", "DNS record ID cannot be empty for update operation")
-		return
-	}
-
 	if recordName == "" {
 		resp.Diagnostics.AddError("Validation Error", "DNS record name cannot be empty")
 		return
@@ -767,23 +500,32 @@ func (r *DNSRecordResource) Update(ctx context.Context, req resource.UpdateReque
 		return
 	}
 
-	// Convert string ID to int for validation
-	recordIDInt, err := strconv.Atoi(recordID)
-	if err != nil {
-		resp.Diagnostics.AddError("Invalid Record ID",
-			fmt.Sprintf("Record ID '%s' is not a valid integer: %s", recordID, err))
+	ctx, unlock, ok := r.lockZone(ctx, zoneName, &resp.Diagnostics)
+	if !ok {
 		return
 	}
+	defer unlock()
+
+	// Find the current LWS ID of the record in the prior state
+	current, err := r.resolveRecord(ctx, state)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Unable to find DNS record %s in zone '%s' to update it, got error: %s",
+			state.ID.ValueString(), zoneName, err)
+		errorMsg += clientErrorDetails(r.client.Describe(), zoneName)
+
+		tflog.Error(ctx, "Failed to find DNS record to update", map[string]interface{}{
+			"record_id": state.ID.ValueString(),
+			"zone":      zoneName,
+			"error":     err.Error(),
+		})
 
-	if recordIDInt <= 0 {
-		resp.Diagnostics.AddError("Invalid Record ID",
-			fmt.Sprintf("Record ID must be a positive integer, got: %d", recordIDInt))
+		resp.Diagnostics.AddError("Client Error", errorMsg)
 		return
 	}
 
 	// Create record object for API call
 	record := &client.DNSRecord{
-		ID:    recordIDInt,
+		ID:    current.ID,
 		Name:  recordName,
 		Type:  recordType,
 		Value: recordValue,
@@ -795,33 +537,28 @@ func (r *DNSRecordResource) Update(ctx context.Context, req resource.UpdateReque
 	}
 
 	tflog.Info(ctx, "Updating DNS record", map[string]interface{}{
-		"record_id": recordIDInt,
+		"record_id": current.ID,
 		"name":      record.Name,
 		"type":      record.Type,
-		"value":     record.Value,
+		"value":     client.RedactValue(record.Value),
 		"zone":      record.Zone,
 		"ttl":       record.TTL,
-		"base_url":  r.client.BaseURL,
-		"login":     r.client.Login,
+		"base_url":  r.client.Describe().BaseURL,
+		"login":     r.client.Describe().Login,
 	})
 
 	updatedRecord, err := r.client.UpdateDNSRecord(ctx, record)
 	if err != nil {
 		errorMsg := fmt.Sprintf("Unable to update DNS record '%s' (ID: %d) in zone '%s', got error: %s",
-			record.Name, recordIDInt, record.Zone, err)
-		if r.client.TestMode {
-			errorMsg += "\n\nNote: You're in test mode. Make sure your test server is configured correctly."
-		} else {
-			errorMsg += fmt.Sprintf("\n\nAPI Details:\n- Base URL: %s\n- Login: %s\n- Expected endpoint: %s/domain/%s/zdns",
-				r.client.BaseURL, r.client.Login, r.client.BaseURL, record.Zone)
-		}
+			record.Name, current.ID, record.Zone, err)
+		errorMsg += clientErrorDetails(r.client.Describe(), record.Zone)
 
 		tflog.Error(ctx, "Failed to update DNS record", map[string]interface{}{
-			"record_id": recordIDInt,
+			"record_id": current.ID,
 			"name":      record.Name,
 			"zone":      record.Zone,
 			"type":      record.Type,
-			"value":     record.Value,
+			"value":     client.RedactValue(record.Value),
 			"error":     err.Error(),
 		})
 
@@ -830,17 +567,18 @@ func (r *DNSRecordResource) Update(ctx context.Context, req resource.UpdateReque
 	}
 
 	tflog.Info(ctx, "Successfully updated DNS record", map[string]interface{}{
-		"record_id": recordIDInt,
+		"record_id": updatedRecord.ID,
 		"name":      updatedRecord.Name,
 		"type":      updatedRecord.Type,
-		"value":     updatedRecord.Value,
+		"value":     client.RedactValue(updatedRecord.Value),
 		"zone":      updatedRecord.Zone,
 		"ttl":       updatedRecord.TTL,
 		"action":    "updated",
 	})
 
-	// Update the model with the updated data from API response
-	data.ID = types.StringValue(fmt.Sprintf("%d", updatedRecord.ID))
+	// Update the model with the updated data from API response. The ID is
+	// derived from the planned values, as the plan modifier of "id" did.
+	data.ID = types.StringValue(recordStateID(zoneName, *record))
 	data.Name = types.StringValue(updatedRecord.Name)
 	data.Type = types.StringValue(updatedRecord.Type)
 	data.Value = types.StringValue(updatedRecord.Value)
@@ -853,6 +591,9 @@ func (r *DNSRecordResource) Update(ctx context.Context, req resource.UpdateReque
 }
 
 func (r *DNSRecordResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	ctx, span := startOperation(ctx, "lws_dns_record.Delete")
+	defer func() { endOperation(span, resp.Diagnostics) }()
+
 	var data DNSRecordResourceModel
 
 	// Read Terraform prior state data into the model
@@ -866,6 +607,7 @@ func (r *DNSRecordResource) Delete(ctx context.Context, req resource.DeleteReque
 	recordName := strings.TrimSpace(data.Name.ValueString())
 	recordType := strings.TrimSpace(data.Type.ValueString())
 	zoneName := strings.TrimSpace(data.Zone.ValueString())
+	setRecordAttributes(span, zoneName, recordName, recordType)
 
 	// DEBUG: Log the current state being deleted
 	tflog.Debug(ctx, "🗑️ DELETE: Starting delete operation", map[string]interface{}{
@@ -873,15 +615,15 @@ func (r *DNSRecordResource) Delete(ctx context.Context, req resource.DeleteReque
 		"state_zone":      zoneName,
 		"state_name":      recordName,
 		"state_type":      recordType,
-		"state_value":     data.Value.ValueString(),
+		"state_value":     client.RedactValue(data.Value.ValueString()),
 		"state_ttl":       data.TTL.ValueInt64(),
 		"id_is_null":      data.ID.IsNull(),
 		"id_is_unknown":   data.ID.IsUnknown(),
 		"zone_is_null":    data.Zone.IsNull(),
 		"zone_is_unknown": data.Zone.IsUnknown(),
-		"base_url":        r.client.BaseURL,
-		"login":           r.client.Login,
-		"test_mode":       r.client.TestMode,
+		"base_url":        r.client.Describe().BaseURL,
+		"login":           r.client.Describe().Login,
+		"test_mode":       r.client.Describe().TestMode,
 	})
 
 	// Manual validation for required fields
@@ -895,51 +637,43 @@ func (r *DNSRecordResource) Delete(ctx context.Context, req resource.DeleteReque
 		return
 	}
 
-	// Convert string ID to int
-	recordIDInt, err := strconv.Atoi(recordID)
-	if err != nil {
-		resp.Diagnostics.AddError("Invalid Record ID",
-			fmt.Sprintf("Record ID '%s' is not a valid integer: %s", recordID, err))
+	ctx, unlock, ok := r.lockZone(ctx, zoneName, &resp.Diagnostics)
+	if !ok {
 		return
 	}
+	defer unlock()
 
-	if recordIDInt <= 0 {
-		resp.Diagnostics.AddError("Invalid Record ID",
-			fmt.Sprintf("Record ID must be a positive integer, got: %d", recordIDInt))
-		return
-	}
+	// Find the current LWS ID of the record, which may have changed since it
+	// was created, then delete it
+	recordIDInt := 0
+	current, err := r.resolveRecord(ctx, data)
+	if err == nil {
+		recordIDInt = current.ID
 
-	tflog.Info(ctx, "Deleting DNS record", map[string]interface{}{
-		"record_id":   recordIDInt,
-		"record_name": recordName,
-		"record_type": recordType,
-		"zone":        zoneName,
-		"base_url":    r.client.BaseURL,
-		"login":       r.client.Login,
-	})
+		tflog.Info(ctx, "Deleting DNS record", map[string]interface{}{
+			"record_id":   recordIDInt,
+			"record_name": recordName,
+			"record_type": recordType,
+			"zone":        zoneName,
+			"base_url":    r.client.Describe().BaseURL,
+			"login":       r.client.Describe().Login,
+		})
 
-	// Debug: Log the exact parameters being passed to the API
-	tflog.Debug(ctx, "Delete API call parameters", map[string]interface{}{
-		"record_id_int": recordIDInt,
-		"zone_name":     zoneName,
-		"endpoint":      fmt.Sprintf("%s/domain/%s/zdns", r.client.BaseURL, zoneName),
-	})
+		// Debug: Log the exact parameters being passed to the API
+		tflog.Debug(ctx, "Delete API call parameters", map[string]interface{}{
+			"record_id_int": recordIDInt,
+			"zone_name":     zoneName,
+			"endpoint":      fmt.Sprintf("%s/domain/%s/zdns", r.client.Describe().BaseURL, zoneName),
+		})
 
-	// Delete API call logic - using ID from state
-	err = r.client.DeleteDNSRecord(ctx, recordIDInt, zoneName)
+		err = r.client.DeleteDNSRecord(ctx, recordIDInt, zoneName)
+	}
 	if err != nil {
 		// Check if the error indicates the record doesn't exist anymore
-		errorMsg := strings.ToLower(err.Error())
-		if strings.Contains(errorMsg, "not found") ||
-			strings.Contains(errorMsg, "does not exist") ||
-			strings.Contains(errorMsg, "record with id") ||
-			strings.Contains(errorMsg, "no record found") ||
-			strings.Contains(errorMsg, "record not found") ||
-			strings.Contains(errorMsg, "invalid record id") ||
-			strings.Contains(errorMsg, "record id not found") {
+		if client.IsNotFound(err) || (current != nil && r.isAlreadyDeleted(ctx, err, zoneName, strconv.Itoa(recordIDInt))) {
 
 			tflog.Info(ctx, "DNS record already deleted or does not exist, considering deletion successful", map[string]interface{}{
-				"record_id":   recordIDInt,
+				"record_id":   recordID,
 				"record_name": recordName,
 				"record_type": recordType,
 				"zone":        zoneName,
@@ -950,14 +684,14 @@ func (r *DNSRecordResource) Delete(ctx context.Context, req resource.DeleteReque
 			// Add informational warning to let user know the record was already gone
 			resp.Diagnostics.AddWarning(
 				"DNS Record Already Deleted",
-				fmt.Sprintf("DNS record ID %d ('%s' of type '%s') in zone '%s' was already deleted or does not exist. "+
+				fmt.Sprintf("DNS record ID %s ('%s' of type '%s') in zone '%s' was already deleted or does not exist. "+
 					"Deletion operation considered successful since the desired state (record absent) is already achieved.",
-					recordIDInt, recordName, recordType, zoneName),
+					recordID, recordName, recordType, zoneName),
 			)
 
 			// Consider the deletion successful since the record is gone
 			tflog.Info(ctx, "Successfully handled deletion of already-deleted DNS record", map[string]interface{}{
-				"record_id":   recordIDInt,
+				"record_id":   recordID,
 				"record_name": recordName,
 				"record_type": recordType,
 				"zone":        zoneName,
@@ -967,14 +701,9 @@ func (r *DNSRecordResource) Delete(ctx context.Context, req resource.DeleteReque
 		}
 
 		// For other errors (network issues, permissions, etc.), still fail
-		fullErrorMsg := fmt.Sprintf("Unable to delete DNS record ID %d ('%s' of type '%s') in zone '%s', got error: %s",
-			recordIDInt, recordName, recordType, zoneName, err)
-		if r.client.TestMode {
-			fullErrorMsg += "\n\nNote: You're in test mode. Make sure your test server is configured correctly."
-		} else {
-			fullErrorMsg += fmt.Sprintf("\n\nAPI Details:\n- Base URL: %s\n- Login: %s\n- Expected endpoint: %s/domain/%s/zdns",
-				r.client.BaseURL, r.client.Login, r.client.BaseURL, zoneName)
-		}
+		fullErrorMsg := fmt.Sprintf("Unable to delete DNS record ID %s ('%s' of type '%s') in zone '%s', got error: %s",
+			recordID, recordName, recordType, zoneName, err)
+		fullErrorMsg += clientErrorDetails(r.client.Describe(), zoneName)
 
 		tflog.Error(ctx, "Failed to delete DNS record", map[string]interface{}{
 			"record_id":   recordIDInt,
@@ -1009,13 +738,291 @@ func (r *DNSRecordResource) Delete(ctx context.Context, req resource.DeleteReque
 	// No need to manually clear the state
 }
 
+// isAlreadyDeleted reports whether a failed delete can be treated as success
+// because the record is gone. A not-found answer is trusted directly; other
+// rejections from the API (LWS answers some stale IDs with a generic 400) are
+// confirmed by looking the record up in the zone.
+func (r *DNSRecordResource) isAlreadyDeleted(ctx context.Context, err error, zoneName, recordID string) bool {
+	if client.IsNotFound(err) {
+		return true
+	}
+
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || client.IsAuth(err) || client.IsRateLimited(err) || client.IsChallenge(err) {
+		return false
+	}
+
+	_, lookupErr := r.client.GetDNSRecord(ctx, zoneName, recordID)
+	return client.IsNotFound(lookupErr)
+}
+
+// lockZone takes the zone lock of the backend, when it has one, so that
+// looking a record up and changing it don't interleave with other changes to
+// the zone. It reports false once it has added an error to diags.
+func (r *DNSRecordResource) lockZone(ctx context.Context, zoneName string, diags *diag.Diagnostics) (context.Context, func(), bool) {
+	locker, ok := r.client.(client.ZoneLocker)
+	if !ok {
+		return ctx, func() {}, true
+	}
+
+	lockedCtx, unlock, err := locker.LockZone(ctx, zoneName)
+	if err != nil {
+		diags.AddError("Client Error",
+			fmt.Sprintf("Unable to wait for other changes to DNS zone '%s', got error: %s", zoneName, err))
+		return ctx, nil, false
+	}
+	return lockedCtx, unlock, true
+}
+
+// recordStateID returns the ID stored in state for a record of a zone. It is
+// derived from the zone, name, type and value rather than the LWS numeric ID,
+// which LWS may change and which would then point at another record.
+func recordStateID(zoneName string, record client.DNSRecord) string {
+	record.Zone = zoneName
+	return client.IdentityOf(record).String()
+}
+
+// recordIdentity returns the identity of the record tracked by a state. States
+// written before composite IDs hold the LWS numeric ID, their identity is then
+// derived from the other attributes. It reports false when the state does not
+// say which record it tracks, which happens after a "zone:record_id" import.
+func recordIdentity(data DNSRecordResourceModel) (client.RecordIdentity, bool) {
+	if identity, err := client.ParseRecordIdentity(data.ID.ValueString()); err == nil {
+		return identity, true
+	}
+
+	if data.Name.ValueString() == "" || data.Type.ValueString() == "" || data.Value.ValueString() == "" {
+		return client.RecordIdentity{}, false
+	}
+
+	return client.IdentityOf(client.DNSRecord{
+		Zone:  data.Zone.ValueString(),
+		Name:  data.Name.ValueString(),
+		Type:  data.Type.ValueString(),
+		Value: data.Value.ValueString(),
+	}), true
+}
+
+// resolveRecord returns the record a state tracks, with its current LWS ID.
+// The error satisfies client.IsNotFound when the zone no longer holds it.
+func (r *DNSRecordResource) resolveRecord(ctx context.Context, data DNSRecordResourceModel) (*client.DNSRecord, error) {
+	zoneName := data.Zone.ValueString()
+
+	identity, ok := recordIdentity(data)
+	if !ok {
+		return r.client.GetDNSRecord(ctx, zoneName, data.ID.ValueString())
+	}
+
+	zone, err := r.client.GetDNSZone(ctx, zoneName)
+	if err != nil {
+		return nil, err
+	}
+
+	record := client.FindRecord(zone.Records, identity)
+	if record == nil && client.SingleValued(identity.Type) {
+		// The name holds a single record of the type: one with another value
+		// is the tracked record, changed outside Terraform
+		record = recordNamed(zone.Records, identity)
+	}
+	if record == nil {
+		return nil, fmt.Errorf("DNS record %s: %w", identity, client.ErrNotFound)
+	}
+	record.Zone = zoneName
+	return record, nil
+}
+
+// existingRecord returns the record of a zone that a create should take over
+// instead of adding want: the record with the same identity or, for types
+// holding a single record per name such as CNAME, the record of the name.
+// Records of the same name and type with other values are siblings of want
+// and are never returned.
+func existingRecord(records []client.DNSRecord, want client.DNSRecord) *client.DNSRecord {
+	identity := client.IdentityOf(want)
+	if record := client.FindRecord(records, identity); record != nil {
+		return record
+	}
+
+	if !client.SingleValued(want.Type) {
+		return nil
+	}
+	return recordNamed(records, identity)
+}
+
+// recordNamed returns a copy of the first record of records with the name
+// and type of identity, whatever its value, or nil.
+func recordNamed(records []client.DNSRecord, identity client.RecordIdentity) *client.DNSRecord {
+	for _, record := range records {
+		other := client.IdentityOf(record)
+		if other.Name == identity.Name && other.Type == identity.Type {
+			return &record
+		}
+	}
+	return nil
+}
+
+// adoptRecord saves an existing record found by a create into the state,
+// updating it first when its value or TTL differ from the plan.
+func (r *DNSRecordResource) adoptRecord(ctx context.Context, existing, record *client.DNSRecord, data *DNSRecordResourceModel, resp *resource.CreateResponse) {
+	tflog.Info(ctx, "Found existing DNS record, adopting it instead of creating a duplicate", map[string]interface{}{
+		"existing_id":    existing.ID,
+		"existing_value": client.RedactValue(existing.Value),
+		"new_value":      client.RedactValue(record.Value),
+		"name":           record.Name,
+		"type":           record.Type,
+		"zone":           record.Zone,
+	})
+
+	// Validate that the existing record has a valid ID
+	if existing.ID <= 0 {
+		errorMsg := fmt.Sprintf("Found existing DNS record '%s' of type '%s' but it has invalid ID: %d. Cannot update record with invalid ID.",
+			record.Name, record.Type, existing.ID)
+		resp.Diagnostics.AddError("Invalid Record ID", errorMsg)
+		return
+	}
+
+	sameValue := rdata.EqualValues(record.Type, existing.Value, record.Value)
+	adopted := existing
+
+	if !sameValue || (record.TTL != 0 && record.TTL != existing.TTL) {
+		record.ID = existing.ID
+		updatedRecord, err := r.client.UpdateDNSRecord(ctx, record)
+		if err != nil {
+			errorMsg := fmt.Sprintf("Unable to update existing DNS record '%s' (ID: %d) in zone '%s', got error: %s",
+				record.Name, existing.ID, record.Zone, err)
+			errorMsg += clientErrorDetails(r.client.Describe(), record.Zone)
+
+			tflog.Error(ctx, "Failed to update existing DNS record", map[string]interface{}{
+				"name":        record.Name,
+				"zone":        record.Zone,
+				"type":        record.Type,
+				"value":       client.RedactValue(record.Value),
+				"existing_id": existing.ID,
+				"error":       err.Error(),
+			})
+
+			resp.Diagnostics.AddError("Client Error", errorMsg)
+			return
+		}
+
+		// Validate the updated record ID
+		if updatedRecord.ID <= 0 {
+			errorMsg := fmt.Sprintf("Update operation returned invalid ID: %d for record '%s'. This indicates an API problem.",
+				updatedRecord.ID, record.Name)
+			resp.Diagnostics.AddError("Invalid Updated Record ID", errorMsg)
+			return
+		}
+
+		adopted = updatedRecord
+	}
+
+	if sameValue {
+		resp.Diagnostics.AddWarning(
+			"Adopted Existing DNS Record",
+			fmt.Sprintf("Found existing DNS record '%s' of type '%s' in zone '%s' (ID: %d) that matches the desired configuration. Adopted this record instead of creating a duplicate.",
+				record.Name, record.Type, record.Zone, existing.ID),
+		)
+	} else {
+		resp.Diagnostics.AddWarning(
+			"Updated Existing DNS Record",
+			fmt.Sprintf("Found existing DNS record '%s' of type '%s' in zone '%s' (ID: %d). Updated its value from '%s' to '%s' instead of creating a duplicate, since a name holds a single record of this type.",
+				record.Name, record.Type, record.Zone, existing.ID, existing.Value, record.Value),
+		)
+	}
+
+	// Keep the planned name, type and value, they identify the record
+	data.ID = types.StringValue(recordStateID(record.Zone, *record))
+	data.TTL = types.Int64Value(int64(adopted.TTL))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+// recordIDPlanModifier plans the "id" attribute. The ID is derived from the
+// zone, name, type and value, so it is kept while they are unchanged and
+// computed from the plan otherwise.
+type recordIDPlanModifier struct{}
+
+func (m recordIDPlanModifier) Description(ctx context.Context) string {
+	return "Keeps the record ID while zone, name, type and value are unchanged, and derives the new one otherwise."
+}
+
+func (m recordIDPlanModifier) MarkdownDescription(ctx context.Context) string {
+	return m.Description(ctx)
+}
+
+func (m recordIDPlanModifier) PlanModifyString(ctx context.Context, req planmodifier.StringRequest, resp *planmodifier.StringResponse) {
+	// Nothing to do on create, where the ID stays unknown, or on destroy
+	if req.StateValue.IsNull() || req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan DNSRecordResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	for _, value := range []types.String{plan.Zone, plan.Name, plan.Type, plan.Value} {
+		if value.IsUnknown() {
+			return
+		}
+	}
+
+	planned := recordStateID(plan.Zone.ValueString(), client.DNSRecord{
+		Name:  plan.Name.ValueString(),
+		Type:  plan.Type.ValueString(),
+		Value: plan.Value.ValueString(),
+	})
+
+	// A legacy numeric ID is replaced even when the record is unchanged,
+	// since Update stores the composite one
+	if identity, err := client.ParseRecordIdentity(req.StateValue.ValueString()); err == nil && identity.String() == planned {
+		resp.PlanValue = req.StateValue
+		return
+	}
+	resp.PlanValue = types.StringValue(planned)
+}
+
+// withRequestID gives a resource or data source operation a request ID,
+// sent with all its API calls and added to its log lines.
+func withRequestID(ctx context.Context) context.Context {
+	requestID := client.NewRequestID()
+	ctx = tflog.SetField(ctx, "request_id", requestID)
+	return client.WithRequestID(ctx, requestID)
+}
+
+// clientErrorDetails returns the troubleshooting hints appended to API error
+// diagnostics for the given backend and zone.
+func clientErrorDetails(desc client.Description, zoneName string) string {
+	if desc.TestMode {
+		return "\n\nNote: You're in test mode. Make sure your test server is configured correctly."
+	}
+	if desc.BaseURL == "" {
+		return fmt.Sprintf("\n\nBackend: %s", desc.Backend)
+	}
+	return fmt.Sprintf("\n\nAPI Details:\n- Base URL: %s\n- Login: %s\n- Expected endpoint: %s/domain/%s/zdns",
+		desc.BaseURL, desc.Login, desc.BaseURL, zoneName)
+}
+
 func (r *DNSRecordResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	// Support two import formats:
-	// 1. "record_id" (legacy format, for backward compatibility)
-	// 2. "zone:record_id" (new format that includes zone information)
+	// Support three import formats:
+	// 1. "zone/name/type/value_hash" (the ID stored in state, see recordStateID)
+	// 2. "zone:record_id" (LWS numeric ID with zone information)
+	// 3. "record_id" (legacy format, for backward compatibility)
 
 	importID := req.ID
 
+	if identity, err := client.ParseRecordIdentity(importID); err == nil {
+		tflog.Info(ctx, "Importing DNS record by identity", map[string]interface{}{
+			"zone":      identity.Zone,
+			"record_id": identity.String(),
+			"format":    "zone/name/type/value_hash",
+		})
+
+		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), identity.String())...)
+		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("zone"), identity.Zone)...)
+		return
+	}
+
 	// Check if the import ID contains a colon (new format)
 	if strings.Contains(importID, ":") {
 		parts := strings.SplitN(importID, ":", 2)
@@ -1024,6 +1031,7 @@ func (r *DNSRecordResource) ImportState(ctx context.Context, req resource.Import
 				"Invalid Import ID Format",
 				fmt.Sprintf("Expected format 'zone:record_id', got '%s'. Examples:\n"+
 					"- terraform import lws_dns_record.example example.com:12345\n"+
+					"- terraform import lws_dns_record.example example.com/www/A/<value_hash>\n"+
 					"- terraform import lws_dns_record.example 12345 (legacy format)",
 					importID),
 			)
@@ -2,25 +2,30 @@ package provider
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/M4XGO/terraform-provider-lws/internal/client"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
 	"github.com/hashicorp/terraform-plugin-testing/terraform"
 )
 
 func TestAccDNSRecordResource(t *testing.T) {
+	name := testAccRecordName("test")
+	zone := testAccZone()
+
 	resource.Test(t, resource.TestCase{
 		PreCheck:                 func() { testAccPreCheck(t) },
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
 		Steps: []resource.TestStep{
 			// Create and Read testing
 			{
-				Config: testAccDNSRecordResourceConfig("terraform-test", "A", "192.0.2.1", "example.com"),
+				Config: testAccDNSRecordResourceConfig(name, "A", "192.0.2.1", zone),
 				Check: resource.ComposeAggregateTestCheckFunc(
-					resource.TestCheckResourceAttr("lws_dns_record.test", "name", "terraform-test"),
+					resource.TestCheckResourceAttr("lws_dns_record.test", "name", name),
 					resource.TestCheckResourceAttr("lws_dns_record.test", "type", "A"),
 					resource.TestCheckResourceAttr("lws_dns_record.test", "value", "192.0.2.1"),
-					resource.TestCheckResourceAttr("lws_dns_record.test", "zone", "example.com"),
+					resource.TestCheckResourceAttr("lws_dns_record.test", "zone", zone),
 					resource.TestCheckResourceAttr("lws_dns_record.test", "ttl", "3600"),
 					resource.TestCheckResourceAttrSet("lws_dns_record.test", "id"),
 				),
@@ -33,7 +38,7 @@ func TestAccDNSRecordResource(t *testing.T) {
 			},
 			// Update and Read testing
 			{
-				Config: testAccDNSRecordResourceConfig("terraform-test", "A", "192.0.2.2", "example.com"),
+				Config: testAccDNSRecordResourceConfig(name, "A", "192.0.2.2", zone),
 				Check: resource.ComposeAggregateTestCheckFunc(
 					resource.TestCheckResourceAttr("lws_dns_record.test", "value", "192.0.2.2"),
 				),
@@ -44,18 +49,21 @@ func TestAccDNSRecordResource(t *testing.T) {
 }
 
 func TestAccDNSRecordResource_CNAME(t *testing.T) {
+	name := testAccRecordName("cname")
+	zone := testAccZone()
+
 	resource.Test(t, resource.TestCase{
 		PreCheck:                 func() { testAccPreCheck(t) },
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
 		Steps: []resource.TestStep{
 			// Create and Read testing for CNAME
 			{
-				Config: testAccDNSRecordResourceConfig("terraform-cname", "CNAME", "example.com.", "example.com"),
+				Config: testAccDNSRecordResourceConfig(name, "CNAME", "example.com.", zone),
 				Check: resource.ComposeAggregateTestCheckFunc(
-					resource.TestCheckResourceAttr("lws_dns_record.test", "name", "terraform-cname"),
+					resource.TestCheckResourceAttr("lws_dns_record.test", "name", name),
 					resource.TestCheckResourceAttr("lws_dns_record.test", "type", "CNAME"),
 					resource.TestCheckResourceAttr("lws_dns_record.test", "value", "example.com."),
-					resource.TestCheckResourceAttr("lws_dns_record.test", "zone", "example.com"),
+					resource.TestCheckResourceAttr("lws_dns_record.test", "zone", zone),
 					resource.TestCheckResourceAttrSet("lws_dns_record.test", "id"),
 				),
 			},
@@ -64,18 +72,21 @@ func TestAccDNSRecordResource_CNAME(t *testing.T) {
 }
 
 func TestAccDNSRecordResource_TXT(t *testing.T) {
+	name := testAccRecordName("txt")
+	zone := testAccZone()
+
 	resource.Test(t, resource.TestCase{
 		PreCheck:                 func() { testAccPreCheck(t) },
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
 		Steps: []resource.TestStep{
 			// Create and Read testing for TXT
 			{
-				Config: testAccDNSRecordResourceConfig("terraform-txt", "TXT", "v=spf1 include:_spf.google.com ~all", "example.com"),
+				Config: testAccDNSRecordResourceConfig(name, "TXT", "v=spf1 include:_spf.google.com ~all", zone),
 				Check: resource.ComposeAggregateTestCheckFunc(
-					resource.TestCheckResourceAttr("lws_dns_record.test", "name", "terraform-txt"),
+					resource.TestCheckResourceAttr("lws_dns_record.test", "name", name),
 					resource.TestCheckResourceAttr("lws_dns_record.test", "type", "TXT"),
 					resource.TestCheckResourceAttr("lws_dns_record.test", "value", "v=spf1 include:_spf.google.com ~all"),
-					resource.TestCheckResourceAttr("lws_dns_record.test", "zone", "example.com"),
+					resource.TestCheckResourceAttr("lws_dns_record.test", "zone", zone),
 					resource.TestCheckResourceAttrSet("lws_dns_record.test", "id"),
 				),
 			},
@@ -84,18 +95,21 @@ func TestAccDNSRecordResource_TXT(t *testing.T) {
 }
 
 func TestAccDNSRecordResource_AAAA(t *testing.T) {
+	name := testAccRecordName("ipv6")
+	zone := testAccZone()
+
 	resource.Test(t, resource.TestCase{
 		PreCheck:                 func() { testAccPreCheck(t) },
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
 		Steps: []resource.TestStep{
 			// Create and Read testing for AAAA
 			{
-				Config: testAccDNSRecordResourceConfig("terraform-ipv6", "AAAA", "2001:db8::1", "example.com"),
+				Config: testAccDNSRecordResourceConfig(name, "AAAA", "2001:db8::1", zone),
 				Check: resource.ComposeAggregateTestCheckFunc(
-					resource.TestCheckResourceAttr("lws_dns_record.test", "name", "terraform-ipv6"),
+					resource.TestCheckResourceAttr("lws_dns_record.test", "name", name),
 					resource.TestCheckResourceAttr("lws_dns_record.test", "type", "AAAA"),
 					resource.TestCheckResourceAttr("lws_dns_record.test", "value", "2001:db8::1"),
-					resource.TestCheckResourceAttr("lws_dns_record.test", "zone", "example.com"),
+					resource.TestCheckResourceAttr("lws_dns_record.test", "zone", zone),
 					resource.TestCheckResourceAttrSet("lws_dns_record.test", "id"),
 				),
 			},
@@ -104,18 +118,21 @@ func TestAccDNSRecordResource_AAAA(t *testing.T) {
 }
 
 func TestAccDNSRecordResource_MX(t *testing.T) {
+	name := testAccRecordName("mx")
+	zone := testAccZone()
+
 	resource.Test(t, resource.TestCase{
 		PreCheck:                 func() { testAccPreCheck(t) },
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
 		Steps: []resource.TestStep{
 			// Create and Read testing for MX
 			{
-				Config: testAccDNSRecordResourceConfig("@", "MX", "10 mail.example.com.", "example.com"),
+				Config: testAccDNSRecordResourceConfig(name, "MX", "10 mail.example.com.", zone),
 				Check: resource.ComposeAggregateTestCheckFunc(
-					resource.TestCheckResourceAttr("lws_dns_record.test", "name", "@"),
+					resource.TestCheckResourceAttr("lws_dns_record.test", "name", name),
 					resource.TestCheckResourceAttr("lws_dns_record.test", "type", "MX"),
 					resource.TestCheckResourceAttr("lws_dns_record.test", "value", "10 mail.example.com."),
-					resource.TestCheckResourceAttr("lws_dns_record.test", "zone", "example.com"),
+					resource.TestCheckResourceAttr("lws_dns_record.test", "zone", zone),
 					resource.TestCheckResourceAttrSet("lws_dns_record.test", "id"),
 				),
 			},
@@ -124,20 +141,23 @@ func TestAccDNSRecordResource_MX(t *testing.T) {
 }
 
 func TestAccDNSRecordResource_TTL_Values(t *testing.T) {
+	name := testAccRecordName("ttl")
+	zone := testAccZone()
+
 	resource.Test(t, resource.TestCase{
 		PreCheck:                 func() { testAccPreCheck(t) },
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
 		Steps: []resource.TestStep{
 			// Test with minimum TTL
 			{
-				Config: testAccDNSRecordResourceConfigWithTTL("terraform-ttl-test", "A", "192.0.2.10", "example.com", 900),
+				Config: testAccDNSRecordResourceConfigWithTTL(name, "A", "192.0.2.10", zone, 900),
 				Check: resource.ComposeAggregateTestCheckFunc(
 					resource.TestCheckResourceAttr("lws_dns_record.test", "ttl", "900"),
 				),
 			},
 			// Update to maximum TTL
 			{
-				Config: testAccDNSRecordResourceConfigWithTTL("terraform-ttl-test", "A", "192.0.2.10", "example.com", 86400),
+				Config: testAccDNSRecordResourceConfigWithTTL(name, "A", "192.0.2.10", zone, 86400),
 				Check: resource.ComposeAggregateTestCheckFunc(
 					resource.TestCheckResourceAttr("lws_dns_record.test", "ttl", "86400"),
 				),
@@ -176,18 +196,21 @@ func TestAccDNSRecordResource_ExistingRecordAdoption(t *testing.T) {
 		t.Skip("Skipping existing record adoption test in short mode")
 	}
 
+	name := testAccRecordName("adoption")
+	zone := testAccZone()
+
 	resource.Test(t, resource.TestCase{
 		PreCheck:                 func() { testAccPreCheck(t) },
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
 		Steps: []resource.TestStep{
 			// Step 1: Create initial record
 			{
-				Config: testAccDNSRecordResourceConfig("terraform-adoption-test", "A", "192.0.2.100", "example.com"),
+				Config: testAccDNSRecordResourceConfig(name, "A", "192.0.2.100", zone),
 				Check: resource.ComposeAggregateTestCheckFunc(
-					resource.TestCheckResourceAttr("lws_dns_record.test", "name", "terraform-adoption-test"),
+					resource.TestCheckResourceAttr("lws_dns_record.test", "name", name),
 					resource.TestCheckResourceAttr("lws_dns_record.test", "type", "A"),
 					resource.TestCheckResourceAttr("lws_dns_record.test", "value", "192.0.2.100"),
-					resource.TestCheckResourceAttr("lws_dns_record.test", "zone", "example.com"),
+					resource.TestCheckResourceAttr("lws_dns_record.test", "zone", zone),
 					resource.TestCheckResourceAttrSet("lws_dns_record.test", "id"),
 				),
 			},
@@ -197,12 +220,12 @@ func TestAccDNSRecordResource_ExistingRecordAdoption(t *testing.T) {
 			},
 			// Step 3: Recreate with same name/type but different value - should adopt and update
 			{
-				Config: testAccDNSRecordResourceConfig("terraform-adoption-test", "A", "192.0.2.101", "example.com"),
+				Config: testAccDNSRecordResourceConfig(name, "A", "192.0.2.101", zone),
 				Check: resource.ComposeAggregateTestCheckFunc(
-					resource.TestCheckResourceAttr("lws_dns_record.test", "name", "terraform-adoption-test"),
+					resource.TestCheckResourceAttr("lws_dns_record.test", "name", name),
 					resource.TestCheckResourceAttr("lws_dns_record.test", "type", "A"),
 					resource.TestCheckResourceAttr("lws_dns_record.test", "value", "192.0.2.101"),
-					resource.TestCheckResourceAttr("lws_dns_record.test", "zone", "example.com"),
+					resource.TestCheckResourceAttr("lws_dns_record.test", "zone", zone),
 					resource.TestCheckResourceAttrSet("lws_dns_record.test", "id"),
 				),
 			},
@@ -215,29 +238,32 @@ func TestAccDNSRecordResource_CaseInsensitiveAdoption(t *testing.T) {
 		t.Skip("Skipping case insensitive adoption test in short mode")
 	}
 
+	name := testAccRecordName("case")
+	zone := testAccZone()
+
 	resource.Test(t, resource.TestCase{
 		PreCheck:                 func() { testAccPreCheck(t) },
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
 		Steps: []resource.TestStep{
 			// Step 1: Create record with lowercase name and type
 			{
-				Config: testAccDNSRecordResourceConfig("terraform-case-test", "cname", "target.example.com.", "example.com"),
+				Config: testAccDNSRecordResourceConfig(name, "cname", "target.example.com.", zone),
 				Check: resource.ComposeAggregateTestCheckFunc(
-					resource.TestCheckResourceAttr("lws_dns_record.test", "name", "terraform-case-test"),
+					resource.TestCheckResourceAttr("lws_dns_record.test", "name", name),
 					resource.TestCheckResourceAttr("lws_dns_record.test", "type", "cname"),
 					resource.TestCheckResourceAttr("lws_dns_record.test", "value", "target.example.com."),
-					resource.TestCheckResourceAttr("lws_dns_record.test", "zone", "example.com"),
+					resource.TestCheckResourceAttr("lws_dns_record.test", "zone", zone),
 					resource.TestCheckResourceAttrSet("lws_dns_record.test", "id"),
 				),
 			},
 			// Step 2: Update with mixed case - should be detected as same record
 			{
-				Config: testAccDNSRecordResourceConfigCaseMixed("TERRAFORM-CASE-TEST", "CNAME", "target.example.com.", "example.com"),
+				Config: testAccDNSRecordResourceConfigCaseMixed(strings.ToUpper(name), "CNAME", "target.example.com.", zone),
 				Check: resource.ComposeAggregateTestCheckFunc(
-					resource.TestCheckResourceAttr("lws_dns_record.test", "name", "TERRAFORM-CASE-TEST"),
+					resource.TestCheckResourceAttr("lws_dns_record.test", "name", strings.ToUpper(name)),
 					resource.TestCheckResourceAttr("lws_dns_record.test", "type", "CNAME"),
 					resource.TestCheckResourceAttr("lws_dns_record.test", "value", "target.example.com."),
-					resource.TestCheckResourceAttr("lws_dns_record.test", "zone", "example.com"),
+					resource.TestCheckResourceAttr("lws_dns_record.test", "zone", zone),
 					resource.TestCheckResourceAttrSet("lws_dns_record.test", "id"),
 				),
 			},
@@ -250,6 +276,9 @@ func TestAccDNSRecordResource_ACMValidationAdoption(t *testing.T) {
 		t.Skip("Skipping ACM validation adoption test in short mode")
 	}
 
+	name := "_4f63eda418b21d585d04126b53ba4ef1." + testAccRecordName("acm")
+	zone := testAccZone()
+
 	// This test simulates the exact scenario from the user's logs
 	resource.Test(t, resource.TestCase{
 		PreCheck:                 func() { testAccPreCheck(t) },
@@ -258,26 +287,26 @@ func TestAccDNSRecordResource_ACMValidationAdoption(t *testing.T) {
 			// Step 1: Create an ACM validation record
 			{
 				Config: testAccDNSRecordResourceConfig(
-					"_4f63eda418b21d585d04126b53ba4ef1.terraform-test",
+					name,
 					"CNAME",
 					"_ee89810c7b27b5fb90b829b35ea3841a.xlfgrmvvlj.acm-validations.aws.",
-					"example.com",
+					zone,
 				),
 				Check: resource.ComposeAggregateTestCheckFunc(
-					resource.TestCheckResourceAttr("lws_dns_record.test", "name", "_4f63eda418b21d585d04126b53ba4ef1.terraform-test"),
+					resource.TestCheckResourceAttr("lws_dns_record.test", "name", name),
 					resource.TestCheckResourceAttr("lws_dns_record.test", "type", "CNAME"),
 					resource.TestCheckResourceAttr("lws_dns_record.test", "value", "_ee89810c7b27b5fb90b829b35ea3841a.xlfgrmvvlj.acm-validations.aws."),
-					resource.TestCheckResourceAttr("lws_dns_record.test", "zone", "example.com"),
+					resource.TestCheckResourceAttr("lws_dns_record.test", "zone", zone),
 					resource.TestCheckResourceAttrSet("lws_dns_record.test", "id"),
 				),
 			},
 			// Step 2: Update the validation value (simulates ACM renewal)
 			{
 				Config: testAccDNSRecordResourceConfig(
-					"_4f63eda418b21d585d04126b53ba4ef1.terraform-test",
+					name,
 					"CNAME",
 					"_new89810c7b27b5fb90b829b35ea3841a.xlfgrmvvlj.acm-validations.aws.",
-					"example.com",
+					zone,
 				),
 				Check: resource.ComposeAggregateTestCheckFunc(
 					resource.TestCheckResourceAttr("lws_dns_record.test", "value", "_new89810c7b27b5fb90b829b35ea3841a.xlfgrmvvlj.acm-validations.aws."),
@@ -288,22 +317,25 @@ func TestAccDNSRecordResource_ACMValidationAdoption(t *testing.T) {
 }
 
 func TestAccDNSRecordResource_ImportWithZone(t *testing.T) {
+	name := testAccRecordName("import")
+	zone := testAccZone()
+
 	resource.Test(t, resource.TestCase{
 		PreCheck:                 func() { testAccPreCheck(t) },
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
 		Steps: []resource.TestStep{
 			// Create record first
 			{
-				Config: testAccDNSRecordResourceConfig("terraform-import-test", "A", "192.0.2.200", "example.com"),
+				Config: testAccDNSRecordResourceConfig(name, "A", "192.0.2.200", zone),
 				Check: resource.ComposeAggregateTestCheckFunc(
-					resource.TestCheckResourceAttr("lws_dns_record.test", "name", "terraform-import-test"),
+					resource.TestCheckResourceAttr("lws_dns_record.test", "name", name),
 					resource.TestCheckResourceAttr("lws_dns_record.test", "type", "A"),
 					resource.TestCheckResourceAttr("lws_dns_record.test", "value", "192.0.2.200"),
-					resource.TestCheckResourceAttr("lws_dns_record.test", "zone", "example.com"),
+					resource.TestCheckResourceAttr("lws_dns_record.test", "zone", zone),
 					resource.TestCheckResourceAttrSet("lws_dns_record.test", "id"),
 				),
 			},
-			// Test new import format with zone:record_id
+			// Test import with an ID built from the zone, name, type and value
 			{
 				ResourceName:      "lws_dns_record.test",
 				ImportState:       true,
@@ -313,7 +345,11 @@ func TestAccDNSRecordResource_ImportWithZone(t *testing.T) {
 					if !ok {
 						return "", fmt.Errorf("Not found: lws_dns_record.test")
 					}
-					return fmt.Sprintf("example.com:%s", rs.Primary.ID), nil
+					return recordStateID(zone, client.DNSRecord{
+						Name:  rs.Primary.Attributes["name"],
+						Type:  rs.Primary.Attributes["type"],
+						Value: rs.Primary.Attributes["value"],
+					}), nil
 				},
 			},
 		},
@@ -0,0 +1,131 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/M4XGO/terraform-provider-lws/internal/client"
+	"github.com/M4XGO/terraform-provider-lws/internal/lwsfake"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func init() {
+	resource.AddTestSweepers("lws_dns_record", &resource.Sweeper{
+		Name: "lws_dns_record",
+		F:    sweepDNSRecords,
+	})
+}
+
+// sweepDNSRecords deletes the records left behind by acceptance tests. The
+// -sweep flag lists the zones to clean, "all" cleaning those of
+// LWS_ACC_ZONES:
+//
+//	go test ./internal/provider -v -sweep=example.com,example.org
+func sweepDNSRecords(zone string) error {
+	baseURL := os.Getenv("LWS_BASE_URL")
+	if baseURL == "" {
+		baseURL = client.DefaultBaseURL
+	}
+	lwsClient, err := client.New(
+		client.WithCredentials(os.Getenv("LWS_LOGIN"), os.Getenv("LWS_API_KEY")),
+		client.WithBaseURL(baseURL),
+		client.WithTestMode(os.Getenv("LWS_TEST_MODE") == "true"),
+	)
+	if err != nil {
+		return fmt.Errorf("error configuring the LWS client: %w", err)
+	}
+
+	zones := []string{zone}
+	if zone == "" || zone == "all" {
+		zones = testAccZones()
+	}
+
+	var errs []error
+	for _, zone := range zones {
+		errs = append(errs, sweepZone(context.Background(), lwsClient, zone))
+	}
+	return errors.Join(errs...)
+}
+
+// sweepZone deletes the acceptance test records of a zone.
+func sweepZone(ctx context.Context, lwsClient *client.LWSClient, zone string) error {
+	dnsZone, err := lwsClient.GetDNSZone(ctx, zone)
+	if err != nil {
+		return fmt.Errorf("error listing zone %s: %w", zone, err)
+	}
+
+	var errs []error
+	for _, record := range dnsZone.Records {
+		if !isTestAccRecord(record.Name) {
+			continue
+		}
+		log.Printf("[INFO] Deleting %s record %s (%d) from zone %s", record.Type, record.Name, record.ID, zone)
+		if err := lwsClient.DeleteDNSRecord(ctx, record.ID, zone); err != nil && !client.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("error deleting record %s (%d) from zone %s: %w", record.Name, record.ID, zone, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func TestSweepDNSRecords(t *testing.T) {
+	kept := lwsfake.Record{Name: "www", Type: "A", Value: "192.0.2.1", TTL: 3600}
+	leftovers := func() []lwsfake.Record {
+		return []lwsfake.Record{
+			kept,
+			{Name: "tf-acc-test-abcd1234", Type: "A", Value: "192.0.2.2", TTL: 3600},
+			{Name: "_4f63eda418b21d585d04126b53ba4ef1.TF-ACC-acm-abcd1234", Type: "CNAME", Value: "acm-validations.aws.", TTL: 3600},
+			{Name: "tf-accounting", Type: "TXT", Value: "kept", TTL: 3600},
+		}
+	}
+	tests := []struct {
+		name  string
+		sweep string
+		swept []string
+	}{
+		{name: "all", sweep: "all", swept: []string{"example.com", "example.org"}},
+		{name: "listed", sweep: "example.org", swept: []string{"example.org"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := lwsfake.New(
+				lwsfake.WithCredentials("testlogin", "testkey"),
+				lwsfake.WithZone("example.com", leftovers()...),
+				lwsfake.WithZone("example.org", leftovers()...),
+			)
+			server := httptest.NewServer(fake)
+			defer server.Close()
+			t.Setenv("LWS_BASE_URL", server.URL)
+			t.Setenv("LWS_LOGIN", "testlogin")
+			t.Setenv("LWS_API_KEY", "testkey")
+			t.Setenv("LWS_TEST_MODE", "")
+			t.Setenv(testAccZonesEnv, "example.com, example.org")
+
+			if err := sweepDNSRecords(tt.sweep); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			for _, zone := range []string{"example.com", "example.org"} {
+				var names []string
+				for _, record := range fake.Records(zone) {
+					names = append(names, record.Name)
+				}
+				want := []string{"www", "tf-acc-test-abcd1234", "_4f63eda418b21d585d04126b53ba4ef1.TF-ACC-acm-abcd1234", "tf-accounting"}
+				for _, swept := range tt.swept {
+					if swept == zone {
+						want = []string{"www", "tf-accounting"}
+					}
+				}
+				if !reflect.DeepEqual(names, want) {
+					t.Errorf("Expected %v in %s, got %v", want, zone, names)
+				}
+			}
+		})
+	}
+}
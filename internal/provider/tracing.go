@@ -0,0 +1,188 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/M4XGO/terraform-provider-lws/internal/client"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// TracesFileEnv names a file the spans are written to as JSON, one span per
+// line, whatever OTEL_TRACES_EXPORTER says.
+const TracesFileEnv = "LWS_TRACES_FILE"
+
+// tracerName is the instrumentation scope of the operation spans.
+const tracerName = "github.com/M4XGO/terraform-provider-lws/internal/provider"
+
+// tracerProvider and tracer are set by SetupTracing when tracing is
+// configured. While they are nil, operations and API calls are not traced
+// and pay nothing for it.
+var (
+	tracerProvider trace.TracerProvider
+	tracer         trace.Tracer
+)
+
+// SetupTracing configures OpenTelemetry tracing from the standard OTEL_*
+// environment variables and LWS_TRACES_FILE. Tracing is on when
+// OTEL_TRACES_EXPORTER names an exporter, when an OTLP endpoint is set or
+// when LWS_TRACES_FILE is set, unless OTEL_SDK_DISABLED is true. Supported
+// exporters are "otlp" (http/protobuf, configured by the
+// OTEL_EXPORTER_OTLP_* variables), "console" (standard error, which
+// Terraform copies to its log) and "none".
+//
+// The returned function flushes the pending spans and must be called once
+// the provider server has stopped. It does nothing when tracing is off.
+func SetupTracing(ctx context.Context, version string) (func(context.Context) error, error) {
+	shutdown := func(context.Context) error { return nil }
+	if strings.EqualFold(strings.TrimSpace(os.Getenv("OTEL_SDK_DISABLED")), "true") {
+		return shutdown, nil
+	}
+
+	exporterNames := splitList(os.Getenv("OTEL_TRACES_EXPORTER"))
+	if len(exporterNames) == 0 && (os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "") {
+		exporterNames = []string{"otlp"}
+	}
+
+	var (
+		exporters []sdktrace.SpanExporter
+		closers   []func() error
+	)
+	fail := func(err error) (func(context.Context) error, error) {
+		for _, exporter := range exporters {
+			_ = exporter.Shutdown(ctx)
+		}
+		for _, closeFile := range closers {
+			_ = closeFile()
+		}
+		return shutdown, err
+	}
+
+	for _, name := range exporterNames {
+		switch strings.ToLower(name) {
+		case "none":
+		case "otlp":
+			protocol := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL")
+			if protocol == "" {
+				protocol = os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+			}
+			if protocol != "" && protocol != "http/protobuf" {
+				return fail(fmt.Errorf("OTLP protocol %q is not supported, use http/protobuf", protocol))
+			}
+			exporter, err := otlptracehttp.New(ctx)
+			if err != nil {
+				return fail(fmt.Errorf("unable to create the OTLP trace exporter: %w", err))
+			}
+			exporters = append(exporters, exporter)
+		case "console":
+			exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stderr))
+			if err != nil {
+				return fail(fmt.Errorf("unable to create the console trace exporter: %w", err))
+			}
+			exporters = append(exporters, exporter)
+		default:
+			return fail(fmt.Errorf("unsupported trace exporter %q in OTEL_TRACES_EXPORTER, expected otlp, console or none", name))
+		}
+	}
+
+	if path := os.Getenv(TracesFileEnv); path != "" {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			return fail(fmt.Errorf("unable to open the traces file: %w", err))
+		}
+		closers = append(closers, file.Close)
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			return fail(fmt.Errorf("unable to create the file trace exporter: %w", err))
+		}
+		exporters = append(exporters, exporter)
+	}
+
+	if len(exporters) == 0 {
+		return shutdown, nil
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceName("terraform-provider-lws"),
+			semconv.ServiceVersion(version),
+		),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return fail(fmt.Errorf("unable to describe the tracing resource: %w", err))
+	}
+
+	// The sampler and the batching follow OTEL_TRACES_SAMPLER and OTEL_BSP_*
+	opts := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+	for _, exporter := range exporters {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	sdkProvider := sdktrace.NewTracerProvider(opts...)
+	tracerProvider = sdkProvider
+	tracer = sdkProvider.Tracer(tracerName)
+
+	return func(ctx context.Context) error {
+		err := sdkProvider.Shutdown(ctx)
+		for _, closeFile := range closers {
+			err = errors.Join(err, closeFile())
+		}
+		return err
+	}, nil
+}
+
+// startOperation gives a resource or data source operation its request ID
+// and opens its span, the parent of the spans of its API calls.
+func startOperation(ctx context.Context, name string) (context.Context, trace.Span) {
+	ctx = withRequestID(ctx)
+	if tracer == nil {
+		return ctx, noop.Span{}
+	}
+	return tracer.Start(ctx, name, trace.WithAttributes(
+		attribute.String("lws.request_id", client.RequestIDFromContext(ctx)),
+	))
+}
+
+// setRecordAttributes describes the record an operation works on.
+func setRecordAttributes(span trace.Span, zoneName, recordName, recordType string) {
+	if !span.IsRecording() {
+		return
+	}
+	span.SetAttributes(
+		attribute.String("lws.zone", zoneName),
+		attribute.String("lws.record.name", recordName),
+		attribute.String("lws.record.type", recordType),
+	)
+}
+
+// endOperation closes the span of an operation, marking it failed when
+// diags has errors.
+func endOperation(span trace.Span, diags diag.Diagnostics) {
+	if !span.IsRecording() {
+		return
+	}
+	if diags.HasError() {
+		errs := diags.Errors()
+		span.SetStatus(codes.Error, errs[0].Summary())
+		for _, d := range errs {
+			span.AddEvent("diagnostic", trace.WithAttributes(
+				attribute.String("summary", d.Summary()),
+				attribute.String("detail", d.Detail()),
+			))
+		}
+	}
+	span.End()
+}
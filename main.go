@@ -6,6 +6,7 @@ import (
 	"log"
 
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-log/tfsdklog"
 	"github.com/M4XGO/terraform-provider-lws/internal/provider"
 )
 
@@ -31,7 +32,26 @@ func main() {
 		Debug:   debug,
 	}
 
-	err := providerserver.Serve(context.Background(), provider.New(version), opts)
+	// Tracing is optional: the provider serves without it when it is
+	// misconfigured
+	shutdownTracing, err := provider.SetupTracing(context.Background(), version)
+	if err != nil {
+		log.Printf("OpenTelemetry tracing disabled: %s", err)
+	}
+
+	err = providerserver.Serve(context.Background(), provider.New(version), opts)
+
+	if tracingErr := shutdownTracing(context.Background()); tracingErr != nil {
+		log.Print(tracingErr.Error())
+	}
+
+	// Terraform reads the provider output until the process exits
+	if metricsErr := provider.ReportMetrics(tfsdklog.NewRootProviderLogger(context.Background())); metricsErr != nil {
+		log.Print(metricsErr.Error())
+	}
+	if captureErr := provider.WriteHTTPCapture(); captureErr != nil {
+		log.Print(captureErr.Error())
+	}
 
 	if err != nil {
 		log.Fatal(err.Error())